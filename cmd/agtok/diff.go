@@ -0,0 +1,184 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/ops"
+    "tks/internal/providers"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+// diffSide is one operand of `agtok diff`: a preset, or "disk" for the current config.
+type diffSide struct {
+    name   string
+    fields core.Fields
+    preset *core.Preset
+}
+
+// diffCmd compares a preset with another preset or with the agent's current config.
+// Like diff(1) it exits 0 when both sides agree, 1 when they differ and 2 on errors.
+func diffCmd(args []string) {
+    fs := flag.NewFlagSet("diff", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    asJSON := fs.Bool("json", false, "print the field diff as JSON")
+    files := fs.Bool("files", false, "show a unified diff of the config files each side would produce")
+    color := fs.String("color", "auto", "colorize output: auto|always|never")
+    pos := parseInterspersed(fs, args)
+    if *agentFlag == "" || len(pos) < 1 || len(pos) > 2 {
+        fmt.Fprintln(os.Stderr, "usage: agtok diff --agent <id> <aliasA> [<aliasB>|disk]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    if len(pos) == 1 { pos = append(pos, "disk") }
+    prov := providers.NewProvider(agent)
+    if prov == nil { fmt.Fprintln(os.Stderr, "provider not available"); os.Exit(2) }
+    a, err := loadDiffSide(prov, pos[0])
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    b, err := loadDiffSide(prov, pos[1])
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+
+    d := core.DiffFields(a.name, a.fields, b.name, b.fields)
+    useColor := wantColor(*color)
+    switch {
+    case *asJSON:
+        out, err := d.JSON()
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        fmt.Println(string(out))
+    case useColor:
+        fmt.Print(d.Color())
+    default:
+        fmt.Print(d.Text())
+    }
+    differ := !d.Empty()
+    if *files {
+        text, changed, err := fileDiff(prov, a, b)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        if useColor { text = colorUnified(text) }
+        fmt.Print(text)
+        differ = differ || changed
+    }
+    if differ {
+        os.Exit(1)
+    }
+}
+
+func loadDiffSide(prov providers.Provider, name string) (diffSide, error) {
+    if name == "disk" {
+        f, err := prov.Read(context.Background())
+        return diffSide{name: "disk", fields: f}, err
+    }
+    p, err := store.GetPreset(prov.ID(), name)
+    if err != nil {
+        return diffSide{}, err
+    }
+    return diffSide{name: name, fields: p.Fields(), preset: &p}, nil
+}
+
+// sideFiles returns the bytes of each config file as the side leaves it: the
+// current content for disk, the provider's planned write for a preset. For a
+// preset it also returns the resolved secrets the planned files contain.
+func sideFiles(prov providers.Provider, s diffSide) (map[string][]byte, []string, error) {
+    out := map[string][]byte{}
+    if s.preset == nil {
+        for _, p := range prov.Paths() {
+            b, err := fsx.ReadFile(p)
+            if err != nil {
+                if errors.Is(err, os.ErrNotExist) { continue }
+                return nil, nil, err
+            }
+            out[p] = b
+        }
+        return out, nil, nil
+    }
+    plan, err := ops.PlanPreset(context.Background(), prov.ID(), *s.preset)
+    if err != nil {
+        return nil, nil, err
+    }
+    for _, f := range plan.Files {
+        out[f.Path] = f.New
+    }
+    return out, plan.Secrets, nil
+}
+
+// fileDiff renders unified diffs from side a's files to side b's, with tokens
+// and secret header values masked.
+func fileDiff(prov providers.Provider, a, b diffSide) (string, bool, error) {
+    fa, sa, err := sideFiles(prov, a)
+    if err != nil { return "", false, err }
+    fb, sb, err := sideFiles(prov, b)
+    if err != nil { return "", false, err }
+    disk, _ := prov.Read(context.Background())
+    secrets := append(append([]string{a.fields.Token, b.fields.Token, disk.Token}, sa...), sb...)
+    for _, h := range []core.Headers{a.fields.Headers, b.fields.Headers, disk.Headers} {
+        secrets = append(secrets, h.Secrets()...)
+    }
+    var out strings.Builder
+    changed := false
+    for _, p := range prov.Paths() {
+        oldB, inA := fa[p]
+        newB, inB := fb[p]
+        if !inA && !inB { continue }
+        fromLabel, toLabel := a.name+":"+p, b.name+":"+p
+        if !inA { fromLabel = "/dev/null" }
+        d := core.UnifiedDiffLabeled(core.FileChange{Path: p, Old: oldB, New: newB, Existed: inA}, fromLabel, toLabel)
+        if d == "" { continue }
+        changed = true
+        out.WriteString(util.MaskAll(d, secrets))
+    }
+    return out.String(), changed, nil
+}
+
+// wantColor resolves --color; auto colors only a terminal and honors NO_COLOR.
+func wantColor(mode string) bool {
+    switch mode {
+    case "always":
+        return true
+    case "never":
+        return false
+    }
+    if os.Getenv("NO_COLOR") != "" { return false }
+    st, err := os.Stdout.Stat()
+    return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func colorUnified(s string) string {
+    lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+    for i, ln := range lines {
+        switch {
+        case strings.HasPrefix(ln, "+++"), strings.HasPrefix(ln, "---"):
+            lines[i] = "\x1b[1m" + ln + "\x1b[0m"
+        case strings.HasPrefix(ln, "@@"):
+            lines[i] = "\x1b[36m" + ln + "\x1b[0m"
+        case strings.HasPrefix(ln, "+"):
+            lines[i] = "\x1b[32m" + ln + "\x1b[0m"
+        case strings.HasPrefix(ln, "-"):
+            lines[i] = "\x1b[31m" + ln + "\x1b[0m"
+        }
+    }
+    if s == "" { return "" }
+    return strings.Join(lines, "\n") + "\n"
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments and returns the positionals in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+    var pos []string
+    for {
+        _ = fs.Parse(args)
+        rest := fs.Args()
+        if len(rest) == 0 {
+            return pos
+        }
+        pos = append(pos, rest[0])
+        args = rest[1:]
+    }
+}
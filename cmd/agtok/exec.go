@@ -0,0 +1,85 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/secret"
+    "tks/internal/store"
+)
+
+// runFields resolves what env/exec hand to the agent: a preset by alias, or the
+// agent's current config. Token references are resolved here and kept in memory.
+func runFields(agent core.AgentID, alias string) (core.Fields, error) {
+    ctx := context.Background()
+    var f core.Fields
+    if alias != "" {
+        p, err := store.GetPreset(agent, alias)
+        if err != nil { return f, err }
+        warnExpiry(p)
+        f = p.Fields()
+    } else {
+        prov := providers.NewProvider(agent)
+        if prov == nil { return f, errors.New("provider not available") }
+        var err error
+        if f, err = prov.Read(ctx); err != nil { return f, err }
+    }
+    tok, err := secret.Resolve(ctx, f.Token)
+    if err != nil { return f, err }
+    f.Token = tok
+    return f, nil
+}
+
+// envCmd prints the agent's variables as shell exports, e.g. for eval "$(agtok env ...)".
+func envCmd(args []string) {
+    fs := flag.NewFlagSet("env", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    alias := fs.String("alias", "", "preset alias (default: the agent's current config)")
+    _ = fs.Parse(args)
+    if *agentFlag == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok env --agent <id> [--alias <name>]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    f, err := runFields(agent, *alias)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    for _, kv := range providers.EnvVars(agent, f) {
+        k, v, _ := strings.Cut(kv, "=")
+        fmt.Printf("export %s='%s'\n", k, strings.ReplaceAll(v, "'", `'\''`))
+    }
+}
+
+// execCmd runs a command with the agent's variables in its environment; nothing is
+// written to disk. The command's exit code is passed through.
+func execCmd(args []string) {
+    fs := flag.NewFlagSet("exec", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    alias := fs.String("alias", "", "preset alias (default: the agent's current config)")
+    _ = fs.Parse(args)
+    argv := fs.Args()
+    if *agentFlag == "" || len(argv) == 0 {
+        fmt.Fprintln(os.Stderr, "usage: agtok exec --agent <id> [--alias <name>] -- <command> [args...]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    f, err := runFields(agent, *alias)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    c := exec.Command(argv[0], argv[1:]...)
+    c.Env = append(os.Environ(), providers.EnvVars(agent, f)...)
+    c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+    if err := c.Run(); err != nil {
+        var ee *exec.ExitError
+        if errors.As(err, &ee) { os.Exit(ee.ExitCode()) }
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(127)
+    }
+}
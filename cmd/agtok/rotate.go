@@ -0,0 +1,69 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    "tks/internal/ops"
+    "tks/internal/util"
+)
+
+// rotateCmd replaces one key everywhere it is stored: every preset on every agent
+// and every gateway, re-applying the presets that are active.
+func rotateCmd(args []string) {
+    fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+    fp := fs.String("old-fingerprint", "", "old key: its last 4 chars or a sha prefix (6+ hex chars) as shown by history")
+    fromStdin := fs.Bool("token-stdin", false, "read the new key from stdin")
+    dryRun := fs.Bool("dry-run", false, "only list the presets that would change")
+    _ = fs.Parse(args)
+    if *fp == "" || (!*fromStdin && !*dryRun) {
+        fmt.Fprintln(os.Stderr, "usage: agtok rotate --old-fingerprint <last4|sha> --token-stdin [--dry-run]")
+        os.Exit(2)
+    }
+    ctx := context.Background()
+    if *dryRun {
+        matches, err := ops.FindByFingerprint(ctx, *fp)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+        if len(matches) == 0 {
+            fmt.Println("(no matching presets)")
+            return
+        }
+        for _, r := range matches {
+            state := ""
+            if r.Active { state = "\tactive" }
+            fmt.Printf("[%s] %s%s\n", r.Agent, r.Alias, state)
+        }
+        return
+    }
+    b, err := io.ReadAll(os.Stdin)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    tok := strings.TrimSpace(string(b))
+    if tok == "" {
+        fmt.Fprintln(os.Stderr, "empty token on stdin")
+        os.Exit(2)
+    }
+    rep, err := ops.Rotate(ctx, *fp, tok, ops.SourceCLI)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    for _, g := range rep.Gateways {
+        fmt.Printf("[gateway] %s\tupdated\n", g)
+    }
+    for _, r := range rep.Presets {
+        switch {
+        case r.Err != nil:
+            fmt.Fprintf(os.Stderr, "[%s] %s\tfailed: %v\n", r.Agent, r.Alias, r.Err)
+        case r.Applied:
+            fmt.Printf("[%s] %s\tupdated, re-applied\n", r.Agent, r.Alias)
+        default:
+            fmt.Printf("[%s] %s\tupdated\n", r.Agent, r.Alias)
+        }
+    }
+    fmt.Printf("rotated to %s (sha:%s)\n", util.Mask(tok), util.Fingerprint(tok))
+    if rep.Failed() { os.Exit(1) }
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+)
+
+// setCmd patches single fields of the agent's config, keeping everything else.
+func setCmd(args []string) {
+    fs := flag.NewFlagSet("set", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    model := fs.String("model", "", "model to switch to")
+    clearModel := fs.Bool("clear-model", false, "remove the model (the agent's default applies)")
+    fromStdin := fs.Bool("token-stdin", false, "read the new token from stdin")
+    url := fs.String("url", "", "base url")
+    official := fs.Bool("official", false, "drop the base url override (vendor's default endpoint)")
+    dry := fs.Bool("dry-run", false, "do not write, only show diff")
+    _ = fs.Parse(args)
+    var p ops.Patch
+    fs.Visit(func(f *flag.Flag) {
+        switch f.Name {
+        case "model": p.Model = model
+        case "url": p.URL = url
+        }
+    })
+    if *official {
+        empty := ""
+        p.URL = &empty
+    }
+    p.ClearModel = *clearModel
+    if *fromStdin {
+        b, err := io.ReadAll(os.Stdin)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        tok := strings.TrimSpace(string(b))
+        if tok == "" {
+            fmt.Fprintln(os.Stderr, "empty token on stdin")
+            os.Exit(2)
+        }
+        p.Token = &tok
+    }
+    if *agentFlag == "" || p.Empty() {
+        fmt.Fprintln(os.Stderr, "usage: agtok set --agent <id> [--model <m>|--clear-model] [--token-stdin] [--url <u>|--official] [--dry-run]")
+        os.Exit(2)
+    }
+    switch {
+    case p.Model != nil && strings.TrimSpace(*p.Model) == "":
+        fmt.Fprintln(os.Stderr, "empty --model; use --clear-model to remove the model")
+        os.Exit(2)
+    case p.Model != nil && *clearModel:
+        fmt.Fprintln(os.Stderr, "--model and --clear-model are mutually exclusive")
+        os.Exit(2)
+    case *official && *url != "":
+        fmt.Fprintln(os.Stderr, "--url and --official are mutually exclusive")
+        os.Exit(2)
+    case p.URL != nil && *p.URL == "" && !*official:
+        fmt.Fprintln(os.Stderr, "empty --url; use --official to drop the base url override")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+
+    ctx := context.Background()
+    plan, err := ops.PlanPatch(ctx, agent, p)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    d := core.FieldDiff{From: "disk", To: "patched", Changes: plan.Fields}
+    if wantColor("auto") { fmt.Print(d.Color()) } else { fmt.Print(d.Text()) }
+    if *dry {
+        return
+    }
+    if _, err := ops.ApplyPatch(ctx, agent, p, ops.SourceCLI); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    fmt.Println("applied")
+}
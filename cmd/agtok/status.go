@@ -0,0 +1,66 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// statusCmd reports, for each agent, whether the on-disk config matches a preset.
+// It exits 1 when any agent has drifted so scripts can react.
+func statusCmd(args []string) {
+    fs := flag.NewFlagSet("status", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id (optional; if omitted, check all)")
+    _ = fs.Parse(args)
+    agents := providers.Agents()
+    if *agentFlag != "" {
+        a, err := parseAgent(*agentFlag)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+        agents = []core.AgentID{a}
+    }
+    drift, failed := false, false
+    for _, agent := range agents {
+        prov := providers.NewProvider(agent)
+        if prov == nil {
+            fmt.Printf("%s\terror\tprovider not available\n", agent)
+            failed = true
+            continue
+        }
+        cur, err := prov.Read(context.Background())
+        if err != nil {
+            fmt.Printf("%s\terror\t%v\n", agent, err)
+            failed = true
+            continue
+        }
+        presets, _ := store.LoadPresets(agent)
+        switch {
+        case cur.URL == "" && cur.Token == "":
+            fmt.Printf("%s\tunconfigured\t-\n", agent)
+        case len(presets) == 0:
+            fmt.Printf("%s\tunmanaged\tno presets\n", agent)
+        default:
+            nm, ok := core.Nearest(presets, cur)
+            if ok && nm.Exact() {
+                fmt.Printf("%s\tok\t%s\n", agent, nm.Alias)
+                continue
+            }
+            drift = true
+            if ok {
+                fmt.Printf("%s\tdrift\t%s\n", agent, nm.Describe())
+            } else {
+                fmt.Printf("%s\tdrift\tno matching preset\n", agent)
+            }
+        }
+    }
+    if failed {
+        os.Exit(2)
+    }
+    if drift {
+        os.Exit(1)
+    }
+}
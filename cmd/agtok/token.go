@@ -0,0 +1,64 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/secret"
+    "tks/internal/store"
+)
+
+// tokenCmd prints a resolved token on stdout, for credential helpers such as
+// Claude's apiKeyHelper. Nothing else is printed on success.
+func tokenCmd(args []string) {
+    fs := flag.NewFlagSet("token", flag.ExitOnError)
+    agentFlag := fs.String("agent", "", "agent id")
+    alias := fs.String("alias", "", "preset alias")
+    active := fs.Bool("active", false, "the preset the agent's config currently runs (default without --alias)")
+    _ = fs.Parse(args)
+    if *agentFlag == "" || (*alias != "" && *active) {
+        fmt.Fprintln(os.Stderr, "usage: agtok token --agent <id> [--alias <name>|--active]")
+        os.Exit(2)
+    }
+    agent, err := parseAgent(*agentFlag)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(2) }
+    ctx := context.Background()
+    tok := ""
+    if *alias != "" {
+        p, err := store.GetPreset(agent, *alias)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+        tok = p.Token
+    } else {
+        tok, err = activeToken(ctx, agent)
+        if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    }
+    v, err := secret.Resolve(ctx, tok)
+    if err != nil { fmt.Fprintln(os.Stderr, err); os.Exit(1) }
+    if v == "" {
+        fmt.Fprintln(os.Stderr, "no token configured")
+        os.Exit(1)
+    }
+    fmt.Println(v)
+}
+
+// activeToken finds the token behind the agent's current config: the preset its
+// key helper names, else the preset matching disk, else the token on disk.
+func activeToken(ctx context.Context, agent core.AgentID) (string, error) {
+    prov := providers.NewProvider(agent)
+    if prov == nil { return "", fmt.Errorf("provider not available for agent: %s", agent) }
+    cur, err := prov.Read(ctx)
+    if err != nil { return "", err }
+    if a := core.KeyHelperAlias(cur.Helper); a != "" {
+        p, err := store.GetPreset(agent, a)
+        return p.Token, err
+    }
+    ps, _ := store.LoadPresets(agent)
+    for _, p := range ps {
+        if core.Matches(p, cur) { return p.Token, nil }
+    }
+    return cur.Token, nil
+}
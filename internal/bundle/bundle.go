@@ -0,0 +1,215 @@
+// Package bundle seals presets into a file for moving them between machines:
+// a versioned JSON envelope whose payload is encrypted with a passphrase
+// (PBKDF2-SHA256, AES-256-GCM), or left readable when secrets were redacted.
+package bundle
+
+import (
+    "bytes"
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/pbkdf2"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/url"
+    "slices"
+
+    core "tks/internal/core"
+    "tks/internal/util"
+)
+
+// Magic identifies a bundle; Version is the envelope layout written.
+const (
+    Magic   = "agtok-bundle"
+    Version = 1
+)
+
+// Iterations is the PBKDF2 work factor for new bundles; readers use the value recorded in the file.
+var Iterations = 600_000
+
+// maxIterations bounds the work factor Open accepts, so a crafted file cannot
+// keep it deriving keys for minutes.
+const maxIterations = 10_000_000
+
+// ErrPassphrase means the passphrase is wrong or the file was altered.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// Payload is what a bundle carries.
+type Payload struct {
+    CreatedAt string                         `json:"created_at"`
+    Redacted  bool                           `json:"redacted,omitempty"`
+    Agents    map[core.AgentID][]core.Preset `json:"agents"`
+}
+
+// Count returns the number of presets in the payload.
+func (p Payload) Count() int {
+    n := 0
+    for _, ps := range p.Agents { n += len(ps) }
+    return n
+}
+
+// envelope is the file layout. For encrypted bundles every header field is
+// authenticated along with the payload; redacted bundles carry a SHA-256 of it.
+type envelope struct {
+    Format     string          `json:"format"`
+    Version    int             `json:"version"`
+    Cipher     string          `json:"cipher"` // aes-256-gcm or none
+    KDF        string          `json:"kdf,omitempty"`
+    Iterations int             `json:"iterations,omitempty"`
+    Salt       []byte          `json:"salt,omitempty"`
+    Nonce      []byte          `json:"nonce,omitempty"`
+    Data       []byte          `json:"data,omitempty"`    // ciphertext
+    Payload    json.RawMessage `json:"payload,omitempty"` // redacted bundles only
+    SHA256     string          `json:"sha256,omitempty"`
+}
+
+const (
+    cipherAESGCM = "aes-256-gcm"
+    cipherNone   = "none"
+    kdfPBKDF2    = "pbkdf2-sha256"
+)
+
+// Seal encrypts p with passphrase.
+func Seal(p Payload, passphrase string) ([]byte, error) {
+    if passphrase == "" { return nil, errors.New("empty passphrase") }
+    plain, err := json.Marshal(p)
+    if err != nil { return nil, err }
+    env := envelope{Format: Magic, Version: Version, Cipher: cipherAESGCM, KDF: kdfPBKDF2, Iterations: Iterations,
+        Salt: make([]byte, 16), Nonce: make([]byte, 12)}
+    if _, err := rand.Read(env.Salt); err != nil { return nil, err }
+    if _, err := rand.Read(env.Nonce); err != nil { return nil, err }
+    aead, err := newAEAD(passphrase, env.Salt, env.Iterations)
+    if err != nil { return nil, err }
+    env.Data = aead.Seal(nil, env.Nonce, plain, env.header())
+    return json.MarshalIndent(env, "", "  ")
+}
+
+// SealRedacted writes p readable, with every literal secret removed (see Redact).
+func SealRedacted(p Payload) ([]byte, error) {
+    p = Redact(p)
+    plain, err := json.Marshal(p)
+    if err != nil { return nil, err }
+    sum := sha256.Sum256(plain)
+    env := envelope{Format: Magic, Version: Version, Cipher: cipherNone, Payload: plain, SHA256: hex.EncodeToString(sum[:])}
+    return json.MarshalIndent(env, "", "  ")
+}
+
+// IsBundle reports whether data looks like a bundle file.
+func IsBundle(data []byte) bool {
+    var env struct{ Format string `json:"format"` }
+    return json.Unmarshal(data, &env) == nil && env.Format == Magic
+}
+
+// Encrypted reports whether the bundle needs a passphrase.
+func Encrypted(data []byte) (bool, error) {
+    env, err := parse(data)
+    if err != nil { return false, err }
+    return env.Cipher != cipherNone, nil
+}
+
+// Open checks and decrypts a bundle; the passphrase is ignored for redacted bundles.
+func Open(data []byte, passphrase string) (Payload, error) {
+    env, err := parse(data)
+    if err != nil { return Payload{}, err }
+    var plain []byte
+    switch env.Cipher {
+    case cipherNone:
+        // the checksum covers the compact payload; the file holds it indented
+        var buf bytes.Buffer
+        if err := json.Compact(&buf, env.Payload); err != nil { return Payload{}, fmt.Errorf("bundle payload: %w", err) }
+        plain = buf.Bytes()
+        sum := sha256.Sum256(plain)
+        if hex.EncodeToString(sum[:]) != env.SHA256 {
+            return Payload{}, errors.New("bundle integrity check failed")
+        }
+    case cipherAESGCM:
+        if env.KDF != kdfPBKDF2 || env.Iterations <= 0 || len(env.Salt) == 0 {
+            return Payload{}, fmt.Errorf("unsupported key derivation %q", env.KDF)
+        }
+        if env.Iterations > maxIterations {
+            return Payload{}, fmt.Errorf("bundle asks for %d key derivation iterations (at most %d)", env.Iterations, maxIterations)
+        }
+        aead, err := newAEAD(passphrase, env.Salt, env.Iterations)
+        if err != nil { return Payload{}, err }
+        if len(env.Nonce) != aead.NonceSize() { return Payload{}, ErrPassphrase }
+        if plain, err = aead.Open(nil, env.Nonce, env.Data, env.header()); err != nil {
+            return Payload{}, ErrPassphrase
+        }
+    default:
+        return Payload{}, fmt.Errorf("unsupported cipher %q", env.Cipher)
+    }
+    var p Payload
+    if err := json.Unmarshal(plain, &p); err != nil { return Payload{}, fmt.Errorf("bundle payload: %w", err) }
+    // aliases end up in file names and helper commands: refuse the whole bundle
+    for id, ps := range p.Agents {
+        for _, pr := range ps {
+            if err := core.ValidateAlias(pr.Alias); err != nil { return Payload{}, fmt.Errorf("bundle: %s preset: %w", id, err) }
+        }
+    }
+    return p, nil
+}
+
+func parse(data []byte) (envelope, error) {
+    var env envelope
+    if err := json.Unmarshal(data, &env); err != nil || env.Format != Magic {
+        return env, errors.New("not an agtok bundle")
+    }
+    if env.Version > Version {
+        return env, fmt.Errorf("bundle version %d is newer than this agtok supports (%d)", env.Version, Version)
+    }
+    return env, nil
+}
+
+// header is the additional authenticated data: the envelope fields that steer decryption.
+func (e envelope) header() []byte {
+    return fmt.Appendf(nil, "%s/%d/%s/%s/%d/%x", e.Format, e.Version, e.Cipher, e.KDF, e.Iterations, e.Salt)
+}
+
+func newAEAD(passphrase string, salt []byte, iter int) (cipher.AEAD, error) {
+    key, err := pbkdf2.Key(sha256.New, passphrase, salt, iter, 32)
+    if err != nil { return nil, err }
+    block, err := aes.NewCipher(key)
+    if err != nil { return nil, err }
+    return cipher.NewGCM(block)
+}
+
+// Redact removes literal tokens, credential-like header values and proxy
+// credentials. References
+// (env:, file:, cmd:) stay: they name where a secret lives, not the secret.
+func Redact(p Payload) Payload {
+    out := Payload{CreatedAt: p.CreatedAt, Redacted: true, Agents: map[core.AgentID][]core.Preset{}}
+    for id, ps := range p.Agents {
+        rs := make([]core.Preset, 0, len(ps))
+        for _, pr := range ps {
+            if !util.IsSecretRef(pr.Token) { pr.Token = "" }
+            pr.TokenFP = "" // derived from the resolved secret
+            var h core.Headers
+            for _, x := range pr.Headers {
+                if core.SecretHeader(x.Name, x.Value) && !util.IsSecretRef(x.Value) { x.Value = "" }
+                h = append(h, x)
+            }
+            pr.Headers = h
+            pr.Network.Proxy = RedactProxy(pr.Network.Proxy)
+            rs = append(rs, pr)
+        }
+        out.Agents[id] = rs
+    }
+    return out
+}
+
+// RedactProxy drops the user and password from a proxy URL.
+func RedactProxy(proxy string) string {
+    u, err := url.Parse(proxy)
+    if err != nil || u.User == nil { return proxy }
+    u.User = nil
+    return u.String()
+}
+
+// MatchTags reports whether p carries any of tags (all presets when tags is empty).
+func MatchTags(p core.Preset, tags []string) bool {
+    if len(tags) == 0 { return true }
+    return slices.ContainsFunc(tags, p.HasTag)
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Gemini auth modes, named as Gemini CLI records them in settings.json (selectedAuthType).
+const (
+    GeminiAuthAPIKey = "gemini-api-key" // AI Studio key in GEMINI_API_KEY
+    GeminiAuthVertex = "vertex-ai"      // GOOGLE_API_KEY (or ADC) with project and location
+)
+
+// ParseGeminiAuth normalizes a user-supplied auth mode; empty means the API key mode.
+func ParseGeminiAuth(s string) (string, error) {
+    switch strings.ToLower(strings.TrimSpace(s)) {
+    case "", "api-key", "apikey", GeminiAuthAPIKey:
+        return GeminiAuthAPIKey, nil
+    case "vertex", GeminiAuthVertex:
+        return GeminiAuthVertex, nil
+    }
+    return "", fmt.Errorf("unknown gemini auth mode %q (use api-key or vertex-ai)", s)
+}
+
+// GeminiAuth returns the auth mode a preset or config stands for; presets saved
+// before auth modes existed (empty) use the API key.
+func GeminiAuth(mode string) string {
+    if mode == "" { return GeminiAuthAPIKey }
+    return mode
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    "tks/internal/util"
+)
+
+// FieldChange is one managed field whose value would change.
+type FieldChange struct {
+    Field  string // URL, Token, Model, KeyHelper, Headers, Network, Tiers, Auth, Project, Location
+    Old    string
+    New    string
+    Secret bool // values must be masked before display
+}
+
+// ChangedFields lists the fields that differ between old and new, in display order.
+// URLs are compared canonically, so a trailing slash alone is not a change.
+func ChangedFields(old, new Fields) []FieldChange {
+    var out []FieldChange
+    if !SameURL(old.URL, new.URL) {
+        out = append(out, FieldChange{Field: "URL", Old: old.URL, New: new.URL})
+    }
+    if old.Token != new.Token {
+        out = append(out, FieldChange{Field: "Token", Old: old.Token, New: new.Token, Secret: true})
+    }
+    if strings.TrimSpace(old.Model) != strings.TrimSpace(new.Model) {
+        out = append(out, FieldChange{Field: "Model", Old: old.Model, New: new.Model})
+    }
+    if old.Helper != new.Helper {
+        out = append(out, FieldChange{Field: "KeyHelper", Old: old.Helper, New: new.Helper})
+    }
+    if !old.Headers.Equal(new.Headers) || !new.Headers.Equal(old.Headers) {
+        // header values are masked up front, so the change itself is not secret
+        out = append(out, FieldChange{Field: "Headers", Old: old.Headers.Masked(), New: new.Headers.Masked()})
+    }
+    if !old.Network.Equal(new.Network) {
+        out = append(out, FieldChange{Field: "Network", Old: old.Network.String(), New: new.Network.String()})
+    }
+    if old.Tiers != new.Tiers {
+        out = append(out, FieldChange{Field: "Tiers", Old: old.Tiers.String(), New: new.Tiers.String()})
+    }
+    for _, d := range authDiffs(old, new) {
+        c := FieldChange{Field: d}
+        switch d {
+        case "Auth":
+            c.Old, c.New = GeminiAuth(old.Auth), GeminiAuth(new.Auth)
+        case "Project":
+            c.Old, c.New = old.Project, new.Project
+        case "Location":
+            c.Old, c.New = old.Location, new.Location
+        }
+        out = append(out, c)
+    }
+    return out
+}
+
+// FieldDiff compares two named sets of fields, e.g. a preset against disk.
+type FieldDiff struct {
+    From    string
+    To      string
+    Changes []FieldChange
+}
+
+// DiffFields reports where to differs from from; the names label the two sides in output.
+func DiffFields(fromName string, from Fields, toName string, to Fields) FieldDiff {
+    return FieldDiff{From: fromName, To: toName, Changes: ChangedFields(from, to)}
+}
+
+// Empty reports whether both sides agree on every field.
+func (d FieldDiff) Empty() bool { return len(d.Changes) == 0 }
+
+func (c FieldChange) display(v string) string {
+    if c.Secret { v = util.Mask(v) }
+    if v == "" { v = "(unset)" }
+    return v
+}
+
+// Text renders the diff as plain lines; secrets are masked.
+func (d FieldDiff) Text() string { return d.render("", "", "") }
+
+// Color renders the diff like Text with ANSI colors for old (red) and new (green) values.
+func (d FieldDiff) Color() string { return d.render("\x1b[31m", "\x1b[32m", "\x1b[0m") }
+
+func (d FieldDiff) render(red, green, reset string) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "--- %s\n+++ %s\n", d.From, d.To)
+    if d.Empty() {
+        b.WriteString("(no differences)\n")
+    }
+    for _, c := range d.Changes {
+        fmt.Fprintf(&b, "%-6s %s%s%s -> %s%s%s\n", c.Field+":", red, c.display(c.Old), reset, green, c.display(c.New), reset)
+    }
+    return b.String()
+}
+
+// JSON renders the diff for scripts. Secret values are masked and accompanied
+// by fingerprints so equal secrets can still be recognized.
+func (d FieldDiff) JSON() ([]byte, error) {
+    type change struct {
+        Field  string `json:"field"`
+        Old    string `json:"old"`
+        New    string `json:"new"`
+        Secret bool   `json:"secret,omitempty"`
+        OldFP  string `json:"old_fingerprint,omitempty"`
+        NewFP  string `json:"new_fingerprint,omitempty"`
+    }
+    out := struct {
+        From    string   `json:"from"`
+        To      string   `json:"to"`
+        Changes []change `json:"changes"`
+    }{From: d.From, To: d.To, Changes: []change{}}
+    for _, c := range d.Changes {
+        ch := change{Field: c.Field, Old: c.Old, New: c.New, Secret: c.Secret}
+        if c.Secret {
+            ch.Old, ch.New = util.Mask(c.Old), util.Mask(c.New)
+            ch.OldFP, ch.NewFP = util.Fingerprint(c.Old), util.Fingerprint(c.New)
+        }
+        out.Changes = append(out.Changes, ch)
+    }
+    return json.MarshalIndent(out, "", "  ")
+}
+
+// FileChange is the content a write would leave in one file.
+type FileChange struct {
+    Path    string
+    Old     []byte
+    New     []byte
+    Existed bool // Old holds the current content; false when the write creates the file
+}
+
+// Changed reports whether the write alters the file.
+func (c FileChange) Changed() bool { return !c.Existed || string(c.Old) != string(c.New) }
+
+// UnifiedDiff renders a unified diff (3 lines of context) of the change; empty when unchanged.
+func UnifiedDiff(c FileChange) string {
+    from := c.Path
+    if !c.Existed { from = "/dev/null" }
+    return UnifiedDiffLabeled(c, from, c.Path)
+}
+
+// UnifiedDiffLabeled is UnifiedDiff with custom ---/+++ labels.
+func UnifiedDiffLabeled(c FileChange, fromLabel, toLabel string) string {
+    if !c.Changed() {
+        return ""
+    }
+    a, b := splitLines(string(c.Old)), splitLines(string(c.New))
+    ops := diffLines(a, b)
+    var out strings.Builder
+    fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromLabel, toLabel)
+    const ctx = 3
+    for i := 0; i < len(ops); {
+        // find the next changed line
+        if ops[i].kind == ' ' { i++; continue }
+        start := i - ctx
+        if start < 0 { start = 0 }
+        end := i
+        // extend the hunk while changes are within 2*ctx lines of each other
+        for j := i; j < len(ops); j++ {
+            if ops[j].kind != ' ' { end = j }
+            if j-end > 2*ctx { break }
+        }
+        stop := end + ctx + 1
+        if stop > len(ops) { stop = len(ops) }
+        aStart, bStart, aLen, bLen := ops[start].a, ops[start].b, 0, 0
+        for _, o := range ops[start:stop] {
+            if o.kind != '+' { aLen++ }
+            if o.kind != '-' { bLen++ }
+        }
+        fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hunkStart(aStart, aLen), aLen, hunkStart(bStart, bLen), bLen)
+        for _, o := range ops[start:stop] {
+            out.WriteString(string(o.kind) + o.text + "\n")
+        }
+        i = stop
+    }
+    return out.String()
+}
+
+func hunkStart(idx, n int) int {
+    if n == 0 { return idx }
+    return idx + 1
+}
+
+func splitLines(s string) []string {
+    if s == "" { return nil }
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+type lineOp struct {
+    kind byte // ' ', '-', '+'
+    text string
+    a, b int // 0-based line positions in old/new when the op starts
+}
+
+// diffLines computes a line diff from the longest common subsequence.
+// Config files are small, so the quadratic table is fine.
+func diffLines(a, b []string) []lineOp {
+    n, m := len(a), len(b)
+    lcs := make([][]int, n+1)
+    for i := range lcs { lcs[i] = make([]int, m+1) }
+    for i := n - 1; i >= 0; i-- {
+        for j := m - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+    var ops []lineOp
+    i, j := 0, 0
+    for i < n || j < m {
+        switch {
+        case i < n && j < m && a[i] == b[j]:
+            ops = append(ops, lineOp{' ', a[i], i, j}); i++; j++
+        case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+            ops = append(ops, lineOp{'+', b[j], i, j}); j++
+        default:
+            ops = append(ops, lineOp{'-', a[i], i, j}); i++
+        }
+    }
+    return ops
+}
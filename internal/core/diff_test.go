@@ -0,0 +1,16 @@
+package core
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestFieldDiffText(t *testing.T) {
+    f := Fields{URL: "https://api.example.com", Token: "sk-same-1234", Headers: Headers{{Name: "X-Team", Value: "a"}}}
+    if got := DiffFields("a", f, "b", f).Text(); !strings.HasSuffix(got, "(no differences)\n") { t.Fatalf("equal sides:\n%s", got) }
+
+    g := f
+    g.Headers = Headers{{Name: "X-Team", Value: "b"}}
+    got := DiffFields("a", f, "b", g).Text()
+    if strings.Contains(got, "no differences") || !strings.Contains(got, "Headers:") { t.Fatalf("headers change:\n%s", got) }
+}
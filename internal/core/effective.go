@@ -0,0 +1,28 @@
+package core
+
+// Resolved is one managed value as the agent will actually see it.
+type Resolved struct {
+    Value     string `json:"value"`
+    Source    string `json:"source"`     // winning layer, e.g. "env ANTHROPIC_BASE_URL" or a file path; empty when unset
+    FileValue string `json:"file_value"` // value in the file agtok manages
+}
+
+// Shadowed reports whether the agent sees something other than the managed file value.
+func (r Resolved) Shadowed() bool { return r.Value != r.FileValue }
+
+// Effective is the resolved configuration for an agent after its precedence chain.
+type Effective struct {
+    Agent AgentID  `json:"agent"`
+    URL   Resolved `json:"url"`
+    Token Resolved `json:"token"`
+    Model Resolved `json:"model"`
+}
+
+// Shadowed lists the names of fields whose file value is overridden.
+func (e Effective) Shadowed() []string {
+    var out []string
+    if e.URL.Shadowed() { out = append(out, "URL") }
+    if e.Token.Shadowed() { out = append(out, "Token") }
+    if e.Model.Shadowed() { out = append(out, "Model") }
+    return out
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+    "sort"
+    "strings"
+)
+
+// Gateway is one host and key serving several agents' protocols. It expands into
+// a derived preset per agent whose URL is Host plus that agent's path suffix.
+type Gateway struct {
+    Name    string             `json:"name"`
+    Host    string             `json:"host"`
+    Key     string             `json:"key"`
+    Paths   map[AgentID]string `json:"paths"` // agent -> path suffix, e.g. "/openai/v1"; "" uses the bare host
+    AddedAt string             `json:"added_at"`
+}
+
+// URLFor returns the base URL the gateway serves for agent.
+func (g Gateway) URLFor(agent AgentID) string {
+    suffix := g.Paths[agent]
+    if suffix == "" {
+        return g.Host
+    }
+    return strings.TrimRight(g.Host, "/") + "/" + strings.TrimLeft(suffix, "/")
+}
+
+// Agents lists the agents the gateway expands into, sorted.
+func (g Gateway) Agents() []AgentID {
+    out := make([]AgentID, 0, len(g.Paths))
+    for a := range g.Paths {
+        out = append(out, a)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+    return out
+}
+
+// Derive builds the agent's preset from the gateway; base carries the fields the
+// gateway does not own (alias, model, metadata) and is kept as is.
+func (g Gateway) Derive(agent AgentID, base Preset) Preset {
+    if base.Token != g.Key { base.TokenFP = "" }
+    base.URL, base.Token, base.Gateway = g.URLFor(agent), g.Key, g.Name
+    return base
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
+
+    "tks/internal/util"
+)
+
+// Header is one custom HTTP header the agent sends with every request.
+type Header struct {
+    Name  string
+    Value string // may be a secret reference (env:, file:, cmd:)
+}
+
+// Headers is an ordered header map. In JSON it is an object whose keys keep their order.
+type Headers []Header
+
+var headerNameRe = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// ParseHeaders reads "Name: value" entries separated by ";" or newlines. A later
+// entry replaces an earlier one with the same name; an empty string is no headers.
+func ParseHeaders(s string) (Headers, error) {
+    var h Headers
+    for _, e := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' }) {
+        if e = strings.TrimSpace(e); e == "" { continue }
+        i := strings.IndexByte(e, ':')
+        if i < 0 {
+            return nil, fmt.Errorf("invalid header %q (want Name: value)", e)
+        }
+        if err := h.Set(strings.TrimSpace(e[:i]), strings.TrimSpace(e[i+1:])); err != nil {
+            return nil, err
+        }
+    }
+    return h, nil
+}
+
+// Set adds a header or replaces the value of one with the same (case-insensitive) name.
+func (h *Headers) Set(name, value string) error {
+    if !headerNameRe.MatchString(name) {
+        return fmt.Errorf("invalid header name %q", name)
+    }
+    if strings.ContainsAny(value, "\r\n") {
+        return fmt.Errorf("header %s: value must be a single line", name)
+    }
+    for i := range *h {
+        if strings.EqualFold((*h)[i].Name, name) {
+            (*h)[i].Value = value
+            return nil
+        }
+    }
+    *h = append(*h, Header{Name: name, Value: value})
+    return nil
+}
+
+// Get returns the value of the header called name (case-insensitive).
+func (h Headers) Get(name string) (string, bool) {
+    for _, x := range h {
+        if strings.EqualFold(x.Name, name) { return x.Value, true }
+    }
+    return "", false
+}
+
+// Equal reports whether disk sends the headers h describes; order and name case
+// do not matter. A reference in h matches any value, as with preset tokens.
+func (h Headers) Equal(disk Headers) bool {
+    if len(h) != len(disk) { return false }
+    for _, x := range h {
+        v, ok := disk.Get(x.Name)
+        if !ok || (v != x.Value && !util.IsSecretRef(x.Value)) { return false }
+    }
+    return true
+}
+
+// String renders the headers as ParseHeaders reads them.
+func (h Headers) String() string { return h.render(false) }
+
+// Masked is String with values that look like credentials masked.
+func (h Headers) Masked() string { return h.render(true) }
+
+// Secrets returns the values Masked would mask, for masking them elsewhere.
+func (h Headers) Secrets() []string {
+    var out []string
+    for _, x := range h {
+        if x.Value != "" && SecretHeader(x.Name, x.Value) { out = append(out, x.Value) }
+    }
+    return out
+}
+
+func (h Headers) render(mask bool) string {
+    parts := make([]string, 0, len(h))
+    for _, x := range h {
+        v := x.Value
+        if mask && SecretHeader(x.Name, v) { v = util.Mask(v) }
+        parts = append(parts, x.Name+": "+v)
+    }
+    return strings.Join(parts, "; ")
+}
+
+// SecretHeader reports whether a header likely carries a credential, judged by
+// its name (authorization, api keys, tokens, cookies...) or a bearer-style value.
+func SecretHeader(name, value string) bool {
+    n := strings.ToLower(name)
+    for _, s := range []string{"auth", "key", "token", "secret", "cookie", "password", "session", "signature"} {
+        if strings.Contains(n, s) { return true }
+    }
+    v := strings.ToLower(value)
+    return strings.HasPrefix(v, "bearer ") || strings.HasPrefix(v, "basic ") || strings.HasPrefix(v, "sk-")
+}
+
+func (h Headers) MarshalJSON() ([]byte, error) {
+    var b bytes.Buffer
+    b.WriteByte('{')
+    for i, x := range h {
+        if i > 0 { b.WriteByte(',') }
+        k, _ := json.Marshal(x.Name)
+        v, _ := json.Marshal(x.Value)
+        b.Write(k)
+        b.WriteByte(':')
+        b.Write(v)
+    }
+    b.WriteByte('}')
+    return b.Bytes(), nil
+}
+
+func (h *Headers) UnmarshalJSON(b []byte) error {
+    dec := json.NewDecoder(bytes.NewReader(b))
+    t, err := dec.Token()
+    if err != nil { return err }
+    if t == nil {
+        *h = nil
+        return nil
+    }
+    if t != json.Delim('{') {
+        return errors.New("headers: want a JSON object")
+    }
+    var out Headers
+    for dec.More() {
+        kt, err := dec.Token()
+        if err != nil { return err }
+        var v string
+        if err := dec.Decode(&v); err != nil { return fmt.Errorf("headers: %w", err) }
+        out = append(out, Header{Name: kt.(string), Value: v})
+    }
+    *h = out
+    return nil
+}
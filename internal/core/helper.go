@@ -0,0 +1,61 @@
+package core
+
+import "strings"
+
+// KeyHelperCommand is the apiKeyHelper an agent runs to fetch a preset's key from
+// agtok; configDir is passed along when agtok runs with a non-default config dir.
+// The command runs through a shell, so the alias and directory are quoted.
+func KeyHelperCommand(agent AgentID, alias, configDir string) string {
+    cmd := "agtok token --agent " + string(agent) + " --alias " + shellQuote(alias)
+    if configDir != "" {
+        cmd += " --config-dir " + shellQuote(configDir)
+    }
+    return cmd
+}
+
+// KeyHelperAlias returns the preset alias an agtok helper command asks for, or ""
+// when cmd is not one (foreign helpers are left alone).
+func KeyHelperAlias(cmd string) string {
+    f := shellWords(cmd)
+    if len(f) < 2 || f[0] != "agtok" || f[1] != "token" {
+        return ""
+    }
+    for i := 2; i+1 < len(f); i++ {
+        if f[i] == "--alias" { return f[i+1] }
+    }
+    return ""
+}
+
+func shellQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellWords splits cmd as a POSIX shell would for the forms KeyHelperCommand
+// writes: blanks separate words, single quotes group and a backslash escapes.
+func shellWords(cmd string) []string {
+    var words []string
+    var cur strings.Builder
+    inWord, quoted := false, false
+    for i := 0; i < len(cmd); i++ {
+        c := cmd[i]
+        switch {
+        case quoted:
+            if c == '\'' { quoted = false } else { cur.WriteByte(c) }
+        case c == '\'':
+            quoted, inWord = true, true
+        case c == '\\' && i+1 < len(cmd):
+            i++
+            cur.WriteByte(cmd[i])
+            inWord = true
+        case c == ' ' || c == '\t' || c == '\n':
+            if inWord { words = append(words, cur.String()) }
+            cur.Reset()
+            inWord = false
+        default:
+            cur.WriteByte(c)
+            inWord = true
+        }
+    }
+    if inWord { words = append(words, cur.String()) }
+    return words
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+    "os/exec"
+    "runtime"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestKeyHelperAliasRoundTrip(t *testing.T) {
+    for _, alias := range []string{"work", "x; touch /tmp/pwned", "it's", "$(id)", "a b"} {
+        cmd := KeyHelperCommand(AgentClaude, alias, "/tmp/agtok's dir")
+        if got := KeyHelperAlias(cmd); got != alias {
+            t.Errorf("KeyHelperAlias(%q) = %q, want %q", cmd, got, alias)
+        }
+    }
+    // helpers written before aliases were quoted
+    if got := KeyHelperAlias("agtok token --agent claude --alias work"); got != "work" {
+        t.Errorf("unquoted helper: got %q", got)
+    }
+    if got := KeyHelperAlias("my-helper --alias work"); got != "" {
+        t.Errorf("foreign helper: got %q", got)
+    }
+}
+
+// The helper runs through a shell: every alias must reach agtok as one argument.
+func TestKeyHelperCommandIsShellSafe(t *testing.T) {
+    if runtime.GOOS == "windows" { t.Skip("POSIX shell only") }
+    alias, dir := "x; touch pwned`id`$(id)'", "/tmp/a b"
+    cmd := KeyHelperCommand(AgentClaude, alias, dir)
+    script := "set -- " + strings.TrimPrefix(cmd, "agtok ") + `; printf '%s\n' "$@"`
+    out, err := exec.Command("sh", "-c", script).Output()
+    if err != nil { t.Fatal(err) }
+    got := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+    want := []string{"token", "--agent", "claude", "--alias", alias, "--config-dir", dir}
+    if !slices.Equal(got, want) { t.Fatalf("shell saw %q, want %q", got, want) }
+}
+
+func TestValidateAlias(t *testing.T) {
+    for _, a := range []string{"work", "snap-default-20250101-1200", "A_b-9"} {
+        if err := ValidateAlias(a); err != nil { t.Errorf("%q rejected: %v", a, err) }
+    }
+    for _, a := range []string{"", "x; touch /tmp/pwned", "a b", "it's", strings.Repeat("a", MaxAliasLen+1)} {
+        if ValidateAlias(a) == nil { t.Errorf("%q accepted", a) }
+    }
+}
+
+func TestSuffixAlias(t *testing.T) {
+    long := strings.Repeat("a", MaxAliasLen)
+    for _, c := range []struct{ alias, suffix, want string }{
+        {"snap-default", "-20250101-1200", "snap-default-20250101-1200"},
+        {long, "-2", long[:MaxAliasLen-2] + "-2"},
+        {long, "-20250101-1200", long[:MaxAliasLen-14] + "-20250101-1200"},
+    } {
+        got := SuffixAlias(c.alias, c.suffix)
+        if got != c.want { t.Errorf("SuffixAlias(%q, %q) = %q, want %q", c.alias, c.suffix, got, c.want) }
+        if err := ValidateAlias(got); err != nil { t.Error(err) }
+    }
+}
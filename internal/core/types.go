@@ -1,6 +1,9 @@
 package core
 
-import "time"
+import (
+    "strings"
+    "time"
+)
 
 type AgentID string
 
@@ -10,13 +13,46 @@ const (
     AgentCodex  AgentID = "codex"
 )
 
+// BaseAgents lists the built-in agents in display order.
+var BaseAgents = []AgentID{AgentClaude, AgentGemini, AgentCodex}
+
+// Base returns the agent kind of a possibly named instance ("codex@work" -> "codex").
+func (a AgentID) Base() AgentID {
+    if i := strings.IndexByte(string(a), '@'); i >= 0 {
+        return a[:i]
+    }
+    return a
+}
+
+// Instance returns the instance name ("codex@work" -> "work"), or "" for the default instance.
+func (a AgentID) Instance() string {
+    if i := strings.IndexByte(string(a), '@'); i >= 0 {
+        return string(a[i+1:])
+    }
+    return ""
+}
+
 // Fields are the two values we manage per agent.
 type Fields struct {
     URL   string
     Token string
     Model string // optional; used by Claude only
+    // Helper is a command printing the token (Claude's apiKeyHelper); when set,
+    // providers that support it write the command and no token
+    Helper string
+    // Gemini only: auth mode (see GeminiAuth; empty keeps the current mode) and,
+    // for Vertex AI, project and location
+    Auth     string
+    Project  string
+    Location string
+    Headers  Headers // custom HTTP headers; providers mirror them exactly
+    Network  Network // proxy, CA bundle and timeout; providers mirror them exactly
+    Tiers    ModelTiers // Claude only: per-tier model names, mirrored exactly
 }
 
+// Unset reports whether nothing that identifies an endpoint or key is configured.
+func (f Fields) Unset() bool { return f.URL == "" && f.Token == "" && f.Helper == "" }
+
 // DiskState holds actual values read from disk.
 type DiskState struct {
     Agent  AgentID
@@ -31,33 +67,47 @@ type Preset struct {
     URL     string `json:"url"`
     Token   string `json:"token"`
     Model   string `json:"model,omitempty"`
-    AddedAt string `json:"added_at"` // UI does not display this
+    AddedAt string `json:"added_at"` // RFC 3339 (schema v2); v1 files used 20060102-1504
+    // metadata; times are RFC 3339
+    Tags       []string `json:"tags,omitempty"`
+    Notes      string   `json:"notes,omitempty"`
+    ExpiresAt  string   `json:"expires_at,omitempty"`   // when a short-lived key stops working
+    LastUsedAt string   `json:"last_used_at,omitempty"` // maintained on each apply
+    UseCount   int      `json:"use_count,omitempty"`
+    // Gateway names the gateway this preset was expanded from; its URL and token follow the gateway
+    Gateway string `json:"gateway,omitempty"`
+    // KeyHelper (Claude only): apply writes an apiKeyHelper running `agtok token`
+    // instead of copying the token into settings.json
+    KeyHelper bool `json:"key_helper,omitempty"`
+    // Gemini only: auth mode (empty is the API key) and Vertex AI project/location
+    Auth     string `json:"auth,omitempty"`
+    Project  string `json:"project,omitempty"`
+    Location string `json:"location,omitempty"`
+    // Headers are sent with every request (Claude ANTHROPIC_CUSTOM_HEADERS, Codex http_headers)
+    Headers Headers `json:"headers,omitempty"`
+    // Network: proxy, CA bundle, no-proxy list and request timeout
+    Network Network `json:"network,omitzero"`
+    // Tiers (Claude only) maps the opus/sonnet/haiku tiers and the top-level model setting
+    Tiers ModelTiers `json:"tiers,omitzero"`
+    // TokenFP: for a token reference, util.Fingerprint of what it resolved to at the
+    // last apply, so the key on disk can be recognised without resolving again
+    TokenFP string `json:"token_fp,omitempty"`
 }
 
+// Instance is an additional named copy of an agent with its own config home,
+// addressed as "<agent>@<name>" (e.g. codex@work).
+type Instance struct {
+    Agent AgentID `json:"agent"`
+    Name  string  `json:"name"`
+    Home  string  `json:"home"`
+}
+
+// ID returns the instance's agent id, e.g. "codex@work".
+func (i Instance) ID() AgentID { return AgentID(string(i.Agent) + "@" + i.Name) }
+
 // Backup info for write operations.
 type Backup struct {
-    Files map[string]string // oldPath -> backupPath (reserved for future)
+    Files map[string]string // managed path -> backup path; empty when the file did not exist
+    Written map[string]string // managed path -> fsx.Sum of the content written
     Time  time.Time
 }
-
-// Diff renders a simple diff between old and new values.
-func Diff(old, new Fields) string {
-    mask := func(s string) string {
-        if len(s) <= 4 {
-            return "****"
-        }
-        return "****" + s[len(s)-4:]
-    }
-    out := "Diff (URL, Token):\n"
-    if old.URL != new.URL {
-        out += "  URL:  " + old.URL + " -> " + new.URL + "\n"
-    } else {
-        out += "  URL:  (no change)\n"
-    }
-    if old.Token != new.Token {
-        out += "  Token:" + mask(old.Token) + " -> " + mask(new.Token) + "\n"
-    } else {
-        out += "  Token: (no change)\n"
-    }
-    return out
-}
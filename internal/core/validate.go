@@ -2,13 +2,37 @@ package core
 
 import (
     "errors"
+    "fmt"
     "net/url"
+    "regexp"
     "strings"
 )
 
+// aliasRe is the alias syntax. Aliases end up in file names and in the
+// apiKeyHelper command agtok writes for Claude, so they stay shell-safe.
+var aliasRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// MaxAliasLen is the longest alias ValidateAlias accepts.
+const MaxAliasLen = 32
+
+// ValidateAlias rejects aliases outside A-Za-z0-9_- or longer than MaxAliasLen.
+func ValidateAlias(a string) error {
+    if !aliasRe.MatchString(a) {
+        return fmt.Errorf("invalid alias %q (allowed: A-Za-z0-9_-, length 1-%d)", a, MaxAliasLen)
+    }
+    return nil
+}
+
+// SuffixAlias appends suffix to alias, shortening alias so the result still
+// fits in MaxAliasLen.
+func SuffixAlias(alias, suffix string) string {
+    return alias[:min(len(alias), max(MaxAliasLen-len(suffix), 0))] + suffix
+}
+
 func ValidateFields(f Fields) error {
+    // an empty URL means the vendor's official endpoint (no base URL override)
     if strings.TrimSpace(f.URL) == "" {
-        return errors.New("url is required")
+        return nil
     }
     // Basic URL validation, allow http(s) and custom schemes
     u, err := url.Parse(f.URL)
@@ -19,3 +43,11 @@ func ValidateFields(f Fields) error {
     return nil
 }
 
+// OfficialLabel is shown in place of an empty URL.
+const OfficialLabel = "(official)"
+
+// DisplayURL returns u, or OfficialLabel when it is empty.
+func DisplayURL(u string) string {
+    if strings.TrimSpace(u) == "" { return OfficialLabel }
+    return u
+}
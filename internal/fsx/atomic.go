@@ -3,27 +3,67 @@ package fsx
 import (
     "fmt"
     "io/fs"
-    "os"
     "path/filepath"
     "runtime"
     "time"
 )
 
+// WriteOptions controls AtomicWriteWith.
+type WriteOptions struct {
+    Mode      fs.FileMode // mode for new files; existing files keep theirs unless ForceMode
+    ForceMode bool        // apply Mode even when the file already exists
+    NoFollow  bool        // replace a symlink itself instead of writing to its target
+}
+
 // AtomicWrite writes content to a temp file and renames it into place.
+// Symlinks are followed so the real target is replaced (dotfile managers keep
+// their links), and an existing file keeps its mode and owner; mode applies to new files.
 func AtomicWrite(path string, content []byte, mode fs.FileMode) error {
+    return AtomicWriteWith(path, content, WriteOptions{Mode: mode})
+}
+
+// AtomicWriteWith is AtomicWrite with explicit options.
+func AtomicWriteWith(path string, content []byte, opt WriteOptions) error {
+    if !opt.NoFollow {
+        target, err := ResolveLink(path)
+        if err != nil {
+            return err
+        }
+        path = target
+    }
+    if err := replaceFile(path, content, opt); err != nil {
+        return err
+    }
+    noteWritten(path, content)
+    return nil
+}
+
+// replaceFile swaps content into path (already resolved) through a temp file.
+func replaceFile(path string, content []byte, opt WriteOptions) error {
+    mode := opt.Mode
+    uid, gid, keepOwner := -1, -1, false
+    if fi, err := Lstat(path); err == nil && fi.Mode().IsRegular() {
+        if !opt.ForceMode {
+            mode = fi.Mode().Perm()
+        }
+        uid, gid, keepOwner = fileOwner(fi)
+    }
     dir := filepath.Dir(path)
     base := filepath.Base(path)
-    if err := os.MkdirAll(dir, 0o700); err != nil {
+    if err := MkdirAll(dir, 0o700); err != nil {
         return err
     }
     tmp := filepath.Join(dir, "."+base+".tmp")
-    if err := os.WriteFile(tmp, content, mode); err != nil {
+    if err := WriteFile(tmp, content, mode); err != nil {
         return err
     }
-    f, _ := os.Open(tmp)
-    if f != nil { _ = f.Sync(); _ = f.Close() }
+    // WriteFile is subject to umask; set the exact mode and best-effort owner before the swap
+    _ = Chmod(tmp, mode)
+    if keepOwner {
+        _ = Chown(tmp, uid, gid)
+    }
     // Try rename, with Windows-specific retries and replacement fallback.
-    if err := os.Rename(tmp, path); err != nil {
+    if err := Rename(tmp, path); err != nil {
         // On Windows, rename fails if destination exists or is locked. Try limited retries.
         if runtime.GOOS == "windows" {
             // If destination exists, attempt replace by removing existing file then renaming.
@@ -31,10 +71,10 @@ func AtomicWrite(path string, content []byte, mode fs.FileMode) error {
             var last error = err
             for i := 0; i < 5; i++ {
                 // If target exists, try remove and rename
-                if _, statErr := os.Stat(path); statErr == nil {
-                    _ = os.Remove(path)
+                if _, statErr := Stat(path); statErr == nil {
+                    _ = Remove(path)
                 }
-                if rerr := os.Rename(tmp, path); rerr == nil {
+                if rerr := Rename(tmp, path); rerr == nil {
                     return nil
                 } else {
                     last = rerr
@@ -42,26 +82,67 @@ func AtomicWrite(path string, content []byte, mode fs.FileMode) error {
                 time.Sleep(50 * time.Millisecond)
             }
             // Cleanup tmp on failure
-            _ = os.Remove(tmp)
+            _ = Remove(tmp)
             return last
         }
         // Non-Windows: best effort cleanup and return original error
-        _ = os.Remove(tmp)
+        _ = Remove(tmp)
         return err
     }
     return nil
 }
 
-// BackupFile creates a timestamped .bak copy if the file exists.
-func BackupFile(path string) error {
-    if _, err := os.Stat(path); err != nil {
-        return err
+// maxLinkHops bounds symlink resolution to avoid loops.
+const maxLinkHops = 40
+
+// ResolveLink follows path while it is a symlink and returns the final target.
+// A missing final target is returned as-is so it can be created.
+func ResolveLink(path string) (string, error) {
+    for i := 0; i < maxLinkHops; i++ {
+        fi, err := Lstat(path)
+        if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+            return path, nil
+        }
+        target, err := Readlink(path)
+        if err != nil {
+            return "", err
+        }
+        if !filepath.IsAbs(target) {
+            target = filepath.Join(filepath.Dir(path), target)
+        }
+        path = target
+    }
+    return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
+
+// LinkTarget reports the final target when path is a symlink.
+func LinkTarget(path string) (string, bool) {
+    fi, err := Lstat(path)
+    if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+        return "", false
+    }
+    target, err := ResolveLink(path)
+    if err != nil {
+        return "", false
+    }
+    return target, true
+}
+
+// BackupFile creates a timestamped .bak copy if the file exists and returns its path.
+func BackupFile(path string) (string, error) {
+    if _, err := Stat(path); err != nil {
+        return "", err
     }
     dir := filepath.Dir(path)
     base := filepath.Base(path)
     stamp := time.Now().Format("20060102-150405")
     bak := filepath.Join(dir, fmt.Sprintf("%s.%s.bak", base, stamp))
-    b, err := os.ReadFile(path)
-    if err != nil { return err }
-    return os.WriteFile(bak, b, 0o600)
+    // several writes within one second must not overwrite each other's backups
+    for i := 1; ; i++ {
+        if _, err := Lstat(bak); err != nil { break }
+        bak = filepath.Join(dir, fmt.Sprintf("%s.%s-%d.bak", base, stamp, i))
+    }
+    b, err := ReadFile(path)
+    if err != nil { return "", err }
+    return bak, WriteFile(bak, b, 0o600)
 }
@@ -0,0 +1,99 @@
+//go:build !windows
+
+package fsx
+
+import (
+    "io/fs"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+)
+
+func TestAtomicWriteThroughSymlinks(t *testing.T) {
+    dir := t.TempDir()
+    target := filepath.Join(dir, "dotfiles", "settings.json")
+    if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(target, []byte("old"), 0o600); err != nil { t.Fatal(err) }
+    // a relative link to an absolute link, as dotfile managers leave them
+    mid := filepath.Join(dir, "mid.json")
+    link := filepath.Join(dir, "settings.json")
+    if err := os.Symlink(target, mid); err != nil { t.Skip("symlinks unavailable:", err) }
+    if err := os.Symlink("mid.json", link); err != nil { t.Fatal(err) }
+
+    if err := AtomicWrite(link, []byte("new"), 0o600); err != nil { t.Fatal(err) }
+    for _, l := range []string{link, mid} {
+        if fi, err := os.Lstat(l); err != nil || fi.Mode()&fs.ModeSymlink == 0 { t.Fatalf("%s is no longer a symlink", l) }
+    }
+    if b, _ := os.ReadFile(target); string(b) != "new" { t.Fatalf("target = %q", b) }
+    if left, _ := filepath.Glob(filepath.Join(dir, "*", ".*.tmp")); len(left) > 0 { t.Fatalf("temp files left: %v", left) }
+
+    // NoFollow replaces the link itself
+    if err := AtomicWriteWith(link, []byte("own"), WriteOptions{Mode: 0o600, NoFollow: true}); err != nil { t.Fatal(err) }
+    if fi, _ := os.Lstat(link); fi.Mode()&fs.ModeSymlink != 0 { t.Fatal("NoFollow kept the symlink") }
+    if b, _ := os.ReadFile(target); string(b) != "new" { t.Fatalf("NoFollow wrote the target: %q", b) }
+}
+
+func TestResolveLinkLoop(t *testing.T) {
+    dir := t.TempDir()
+    a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+    if err := os.Symlink(b, a); err != nil { t.Skip("symlinks unavailable:", err) }
+    if err := os.Symlink(a, b); err != nil { t.Fatal(err) }
+    if err := AtomicWrite(a, []byte("x"), 0o600); err == nil { t.Fatal("write through a link loop succeeded") }
+}
+
+func TestAtomicWriteMode(t *testing.T) {
+    old := syscall.Umask(0o077)
+    defer syscall.Umask(old)
+    dir := t.TempDir()
+    mode := func(p string) fs.FileMode {
+        t.Helper()
+        fi, err := os.Stat(p)
+        if err != nil { t.Fatal(err) }
+        return fi.Mode().Perm()
+    }
+
+    secret := filepath.Join(dir, "auth.json")
+    if err := AtomicWrite(secret, []byte("1"), 0o600); err != nil { t.Fatal(err) }
+    if m := mode(secret); m != 0o600 { t.Fatalf("new file mode = %v", m) }
+    if err := AtomicWrite(secret, []byte("2"), 0o644); err != nil { t.Fatal(err) }
+    if m := mode(secret); m != 0o600 { t.Fatalf("0600 lost on rewrite: %v", m) }
+
+    // an existing file keeps its mode, even one looser than the default...
+    shared := filepath.Join(dir, "config.toml")
+    if err := os.WriteFile(shared, []byte("1"), 0o600); err != nil { t.Fatal(err) }
+    if err := os.Chmod(shared, 0o640); err != nil { t.Fatal(err) }
+    if err := AtomicWrite(shared, []byte("2"), 0o600); err != nil { t.Fatal(err) }
+    if m := mode(shared); m != 0o640 { t.Fatalf("existing mode = %v, want 0640", m) }
+    // ...unless ForceMode
+    if err := AtomicWriteWith(shared, []byte("3"), WriteOptions{Mode: 0o600, ForceMode: true}); err != nil { t.Fatal(err) }
+    if m := mode(shared); m != 0o600 { t.Fatalf("ForceMode = %v", m) }
+}
+
+// chownFS records the owner AtomicWrite restores on the temp file.
+type chownFS struct {
+    FS
+    uid, gid int
+    called   bool
+}
+
+func (c *chownFS) Chown(name string, uid, gid int) error {
+    c.uid, c.gid, c.called = uid, gid, true
+    return c.FS.Chown(name, uid, gid)
+}
+
+func TestAtomicWriteKeepsOwner(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "settings.json")
+    if err := os.WriteFile(path, []byte("old"), 0o600); err != nil { t.Fatal(err) }
+    fi, err := os.Stat(path)
+    if err != nil { t.Fatal(err) }
+    st := fi.Sys().(*syscall.Stat_t)
+
+    c := &chownFS{}
+    c.FS = SetFS(c)
+    defer SetFS(c.FS)
+    if err := AtomicWrite(path, []byte("new"), 0o600); err != nil { t.Fatal(err) }
+    if !c.called || c.uid != int(st.Uid) || c.gid != int(st.Gid) {
+        t.Fatalf("owner restored as %d:%d (called %v), want %d:%d", c.uid, c.gid, c.called, st.Uid, st.Gid)
+    }
+}
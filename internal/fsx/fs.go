@@ -0,0 +1,147 @@
+package fsx
+
+import (
+    "io"
+    "io/fs"
+    "os"
+    "runtime"
+    "sync"
+)
+
+// FS is the file-system surface used by providers and store. The default is
+// the real OS; tests can install another implementation with SetFS.
+type FS interface {
+    Open(name string) (io.ReadCloser, error)
+    ReadFile(name string) ([]byte, error)
+    // WriteFile writes and syncs data, creating or truncating name.
+    WriteFile(name string, data []byte, perm fs.FileMode) error
+    // AppendFile appends data in a single write, creating name if needed, so
+    // concurrent appenders never overwrite each other.
+    AppendFile(name string, data []byte, perm fs.FileMode) error
+    MkdirAll(path string, perm fs.FileMode) error
+    Rename(oldpath, newpath string) error
+    Remove(name string) error
+    Stat(name string) (fs.FileInfo, error)
+    Lstat(name string) (fs.FileInfo, error)
+    Readlink(name string) (string, error)
+    Chmod(name string, mode fs.FileMode) error
+    Chown(name string, uid, gid int) error
+    ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// Root tells agtok where to find files instead of the real user environment.
+type Root struct {
+    Home      string // used in place of the user's home directory (--root)
+    ConfigDir string // agtok's own config directory (--config-dir / AGTOK_HOME)
+}
+
+var (
+    mu      sync.RWMutex
+    current FS = osFS{}
+    root    Root
+)
+
+// SetFS installs the file system used by all fsx helpers and returns the previous one.
+func SetFS(f FS) FS {
+    mu.Lock()
+    defer mu.Unlock()
+    prev := current
+    current = f
+    return prev
+}
+
+// SetRoot configures the home and config directory overrides.
+func SetRoot(r Root) {
+    mu.Lock()
+    defer mu.Unlock()
+    root = r
+}
+
+// CurrentRoot returns the active overrides; zero fields mean "use the real environment".
+func CurrentRoot() Root {
+    mu.RLock()
+    defer mu.RUnlock()
+    return root
+}
+
+// Rooted reports whether agtok is pointed at another home (so host env vars do not apply).
+func Rooted() bool { return CurrentRoot().Home != "" }
+
+// Home returns the home directory agent config paths are resolved against.
+func Home() string {
+    if h := CurrentRoot().Home; h != "" {
+        return h
+    }
+    h, _ := os.UserHomeDir()
+    if h == "" && runtime.GOOS == "windows" {
+        h = os.Getenv("USERPROFILE")
+    }
+    return h
+}
+
+func fsys() FS {
+    mu.RLock()
+    defer mu.RUnlock()
+    return current
+}
+
+func Open(name string) (io.ReadCloser, error)       { return fsys().Open(name) }
+func ReadFile(name string) ([]byte, error)          { return fsys().ReadFile(name) }
+func MkdirAll(path string, perm fs.FileMode) error  { return fsys().MkdirAll(path, perm) }
+func Rename(oldpath, newpath string) error          { return fsys().Rename(oldpath, newpath) }
+func Remove(name string) error                      { return fsys().Remove(name) }
+func Stat(name string) (fs.FileInfo, error)         { return fsys().Stat(name) }
+func Lstat(name string) (fs.FileInfo, error)        { return fsys().Lstat(name) }
+func Readlink(name string) (string, error)          { return fsys().Readlink(name) }
+func Chmod(name string, mode fs.FileMode) error     { return fsys().Chmod(name, mode) }
+func Chown(name string, uid, gid int) error         { return fsys().Chown(name, uid, gid) }
+func ReadDir(name string) ([]fs.DirEntry, error)    { return fsys().ReadDir(name) }
+
+func WriteFile(name string, data []byte, perm fs.FileMode) error {
+    return fsys().WriteFile(name, data, perm)
+}
+
+func AppendFile(name string, data []byte, perm fs.FileMode) error {
+    return fsys().AppendFile(name, data, perm)
+}
+
+// osFS is the real file system.
+type osFS struct{}
+
+func (osFS) Open(name string) (io.ReadCloser, error)      { return os.Open(name) }
+func (osFS) ReadFile(name string) ([]byte, error)         { return os.ReadFile(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (osFS) Readlink(name string) (string, error)         { return os.Readlink(name) }
+func (osFS) Chmod(name string, mode fs.FileMode) error    { return os.Chmod(name, mode) }
+func (osFS) Chown(name string, uid, gid int) error        { return os.Chown(name, uid, gid) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+    f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        _ = f.Close()
+        return err
+    }
+    _ = f.Sync()
+    return f.Close()
+}
+
+func (osFS) AppendFile(name string, data []byte, perm fs.FileMode) error {
+    f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, perm)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        _ = f.Close()
+        return err
+    }
+    _ = f.Sync()
+    return f.Close()
+}
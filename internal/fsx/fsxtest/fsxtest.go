@@ -0,0 +1,34 @@
+// Package fsxtest has test helpers for code that reaches the file system through fsx.
+package fsxtest
+
+import (
+    "path/filepath"
+    "testing"
+
+    "tks/internal/fsx"
+)
+
+// UseRoot points agtok at a fresh temp home for the test and returns it.
+func UseRoot(t testing.TB) string {
+    t.Helper()
+    home := t.TempDir()
+    prev := fsx.CurrentRoot()
+    fsx.SetRoot(fsx.Root{Home: home})
+    t.Cleanup(func() { fsx.SetRoot(prev) })
+    return home
+}
+
+// WriteFile writes content to path (mode 0600), creating its directory.
+func WriteFile(t testing.TB, path, content string) {
+    t.Helper()
+    if err := fsx.MkdirAll(filepath.Dir(path), 0o700); err != nil { t.Fatal(err) }
+    if err := fsx.WriteFile(path, []byte(content), 0o600); err != nil { t.Fatal(err) }
+}
+
+// ReadFile returns the content of path, failing the test when it cannot be read.
+func ReadFile(t testing.TB, path string) string {
+    t.Helper()
+    b, err := fsx.ReadFile(path)
+    if err != nil { t.Fatal(err) }
+    return string(b)
+}
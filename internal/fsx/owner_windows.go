@@ -0,0 +1,10 @@
+//go:build windows
+
+package fsx
+
+import "io/fs"
+
+// fileOwner is a no-op on Windows; ownership follows the directory ACL.
+func fileOwner(fi fs.FileInfo) (uid, gid int, ok bool) {
+    return 0, 0, false
+}
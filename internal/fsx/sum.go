@@ -0,0 +1,54 @@
+package fsx
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "os"
+    "sync"
+)
+
+// Sum returns the hex SHA-256 of b; agtok records it for every file it writes so
+// it can later tell whether the file still holds that content.
+func Sum(b []byte) string {
+    h := sha256.Sum256(b)
+    return hex.EncodeToString(h[:])
+}
+
+// FileSum returns the Sum of path's content, or "" when the file does not exist.
+func FileSum(path string) (string, error) {
+    b, err := ReadFile(path)
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return "", nil }
+        return "", err
+    }
+    return Sum(b), nil
+}
+
+// written remembers the Sum of the content this process last wrote per path, so a
+// file watcher can tell agtok's own writes from changes made by someone else.
+var (
+    writtenMu sync.Mutex
+    written   = map[string]string{}
+)
+
+func noteWritten(path string, content []byte) {
+    writtenMu.Lock()
+    defer writtenMu.Unlock()
+    written[path] = Sum(content)
+}
+
+// OwnContent reports whether path (or its symlink target) still holds exactly what
+// this process last wrote there. A later external write, even an instant after
+// ours, makes it false.
+func OwnContent(path string) bool {
+    if target, err := ResolveLink(path); err == nil {
+        path = target
+    }
+    writtenMu.Lock()
+    want, ok := written[path]
+    writtenMu.Unlock()
+    if !ok { return false }
+    got, err := FileSum(path)
+    return err == nil && got == want
+}
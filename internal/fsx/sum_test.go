@@ -0,0 +1,25 @@
+package fsx
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestOwnContent(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "settings.json")
+    if OwnContent(path) { t.Fatal("unwritten file reported as own") }
+    if err := AtomicWrite(path, []byte(`{"a":1}`), 0o600); err != nil { t.Fatal(err) }
+    if !OwnContent(path) { t.Fatal("own write not recognised") }
+
+    // an external write right after ours is still an external change
+    if err := os.WriteFile(path, []byte(`{"a":2}`), 0o600); err != nil { t.Fatal(err) }
+    if OwnContent(path) { t.Fatal("external write reported as own") }
+
+    // through a symlink, the target's content is compared
+    link := filepath.Join(dir, "link.json")
+    if err := os.Symlink(path, link); err != nil { t.Skip("symlinks unavailable:", err) }
+    if err := AtomicWrite(link, []byte(`{"a":3}`), 0o600); err != nil { t.Fatal(err) }
+    if !OwnContent(link) || !OwnContent(path) { t.Fatal("write through symlink not recognised") }
+}
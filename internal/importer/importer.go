@@ -0,0 +1,344 @@
+// Package importer reads presets from password-manager exports and other
+// switcher tools' configs. Each format is parsed into flat records; a mapping
+// (or the defaults) picks the preset fields from them.
+package importer
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    core "tks/internal/core"
+)
+
+// Format names an import source.
+type Format string
+
+const (
+    FormatBitwarden Format = "bitwarden-json"
+    Format1Password Format = "1password-csv"
+    FormatDotenv    Format = "dotenv"
+    FormatJSON      Format = "json"
+    FormatYAML      Format = "yaml"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatBitwarden, Format1Password, FormatDotenv, FormatJSON, FormatYAML}
+
+// ParseFormat accepts a format name ("yml" is yaml).
+func ParseFormat(s string) (Format, error) {
+    if s == "yml" { return FormatYAML, nil }
+    for _, f := range Formats {
+        if string(f) == s { return f, nil }
+    }
+    names := make([]string, len(Formats))
+    for i, f := range Formats { names[i] = string(f) }
+    return "", fmt.Errorf("unknown format %q (want %s)", s, strings.Join(names, ", "))
+}
+
+// Record is one entry of an export: its name and its source fields. Nested keys
+// are joined with "." and list items are numbered, e.g. "env.ANTHROPIC_BASE_URL"
+// or "login.uris.0.uri".
+type Record struct {
+    Name   string
+    Fields map[string]string
+}
+
+// Parse reads data in format f. file names the source; a dotenv file is one
+// record named after it.
+func Parse(f Format, data []byte, file string) ([]Record, error) {
+    switch f {
+    case FormatBitwarden:
+        return parseBitwarden(data)
+    case Format1Password:
+        return parse1Password(data)
+    case FormatDotenv:
+        return []Record{{Name: dotenvName(file), Fields: parseDotenv(data)}}, nil
+    case FormatJSON:
+        var v any
+        if err := json.Unmarshal(data, &v); err != nil { return nil, err }
+        return records(v), nil
+    case FormatYAML:
+        var v any
+        if err := yaml.Unmarshal(data, &v); err != nil { return nil, err }
+        return records(v), nil
+    }
+    return nil, fmt.Errorf("unknown format %q", f)
+}
+
+func parseBitwarden(data []byte) ([]Record, error) {
+    var exp struct {
+        Encrypted bool `json:"encrypted"`
+        Items     []struct {
+            Name  string `json:"name"`
+            Notes string `json:"notes"`
+            Login *struct {
+                Username string `json:"username"`
+                Password string `json:"password"`
+                URIs     []struct{ URI string `json:"uri"` } `json:"uris"`
+            } `json:"login"`
+            Fields []struct {
+                Name  string `json:"name"`
+                Value string `json:"value"`
+            } `json:"fields"`
+        } `json:"items"`
+    }
+    if err := json.Unmarshal(data, &exp); err != nil { return nil, err }
+    if exp.Encrypted {
+        return nil, errors.New("encrypted bitwarden export; export as unencrypted JSON")
+    }
+    var out []Record
+    for _, it := range exp.Items {
+        r := Record{Name: it.Name, Fields: map[string]string{"name": it.Name, "notes": it.Notes}}
+        if l := it.Login; l != nil {
+            r.Fields["username"], r.Fields["password"] = l.Username, l.Password
+            if len(l.URIs) > 0 { r.Fields["uri"] = l.URIs[0].URI }
+        }
+        // custom fields ("model", "base_url"...) are addressed by their own names
+        for _, f := range it.Fields {
+            if _, ok := r.Fields[f.Name]; !ok { r.Fields[f.Name] = f.Value }
+        }
+        out = append(out, r)
+    }
+    return out, nil
+}
+
+func parse1Password(data []byte) ([]Record, error) {
+    cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+    cr.FieldsPerRecord = -1
+    rows, err := cr.ReadAll()
+    if err != nil { return nil, err }
+    if len(rows) == 0 { return nil, nil }
+    head := rows[0]
+    var out []Record
+    for _, row := range rows[1:] {
+        r := Record{Fields: map[string]string{}}
+        for i, v := range row {
+            if i < len(head) { r.Fields[head[i]] = v }
+        }
+        r.Name = lookup(r.Fields, []string{"title", "name"})
+        out = append(out, r)
+    }
+    return out, nil
+}
+
+func parseDotenv(data []byte) map[string]string {
+    m := map[string]string{}
+    for _, ln := range strings.Split(string(data), "\n") {
+        ln = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(ln), "export "))
+        if ln == "" || strings.HasPrefix(ln, "#") { continue }
+        k, v, ok := strings.Cut(ln, "=")
+        if !ok { continue }
+        v = strings.TrimSpace(v)
+        if u, err := strconv.Unquote(v); err == nil && strings.HasPrefix(v, `"`) {
+            v = u
+        } else if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+            v = v[1 : len(v)-1]
+        }
+        m[strings.TrimSpace(k)] = v
+    }
+    return m
+}
+
+// dotenvName is the file name without directory and extension ("work.env" -> "work").
+func dotenvName(file string) string {
+    b := filepath.Base(file)
+    if n := strings.TrimSuffix(b, filepath.Ext(b)); n != "" && n != "." { return n }
+    return "dotenv"
+}
+
+// listKeys are the keys under which switcher configs commonly keep their entries.
+var listKeys = []string{"presets", "providers", "profiles", "configs", "items", "entries", "accounts"}
+
+// records finds the entries of a generic JSON/YAML document: a list of objects,
+// an object keyed by name, either of those under a common key, or one object.
+func records(v any) []Record {
+    if m, ok := v.(map[string]any); ok {
+        for _, k := range listKeys {
+            for mk, sub := range m {
+                if strings.EqualFold(mk, k) {
+                    if rs := entries(sub); rs != nil { return rs }
+                }
+            }
+        }
+    }
+    if rs := entries(v); rs != nil { return rs }
+    if m, ok := v.(map[string]any); ok {
+        return []Record{{Fields: flatten(m)}}
+    }
+    return nil
+}
+
+// entries returns the records of a list of objects or of an object whose values
+// are all objects (keyed by name); nil for anything else.
+func entries(v any) []Record {
+    switch t := v.(type) {
+    case []any:
+        var out []Record
+        for _, x := range t {
+            if m, ok := x.(map[string]any); ok { out = append(out, Record{Fields: flatten(m)}) }
+        }
+        return out
+    case map[string]any:
+        keys := make([]string, 0, len(t))
+        for k, x := range t {
+            if _, ok := x.(map[string]any); !ok { return nil }
+            keys = append(keys, k)
+        }
+        if len(keys) == 0 { return nil }
+        sort.Strings(keys)
+        out := make([]Record, 0, len(keys))
+        for _, k := range keys {
+            out = append(out, Record{Name: k, Fields: flatten(t[k].(map[string]any))})
+        }
+        return out
+    }
+    return nil
+}
+
+func flatten(m map[string]any) map[string]string {
+    out := map[string]string{}
+    var walk func(prefix string, v any)
+    walk = func(prefix string, v any) {
+        switch t := v.(type) {
+        case map[string]any:
+            for k, x := range t { walk(join(prefix, k), x) }
+        case []any:
+            for i, x := range t { walk(join(prefix, strconv.Itoa(i)), x) }
+        case nil:
+        case string:
+            out[prefix] = t
+        case time.Time: // YAML timestamps
+            out[prefix] = t.Format(time.RFC3339)
+        default:
+            out[prefix] = fmt.Sprint(t)
+        }
+    }
+    walk("", m)
+    return out
+}
+
+func join(prefix, k string) string {
+    if prefix == "" { return k }
+    return prefix + "." + k
+}
+
+// Targets are the preset fields a mapping can fill.
+var Targets = []string{"alias", "url", "token", "model", "notes", "tags"}
+
+// Mapping names, per target, the source field to read, e.g. {"token": "Password"}.
+type Mapping map[string]string
+
+// ParseMapping reads "target=source" pairs separated by ",", e.g. "url=Website,token=Password".
+func ParseMapping(s string) (Mapping, error) {
+    m := Mapping{}
+    for _, e := range strings.Split(s, ",") {
+        if e = strings.TrimSpace(e); e == "" { continue }
+        k, v, ok := strings.Cut(e, "=")
+        k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+        if !ok || v == "" {
+            return nil, fmt.Errorf("invalid mapping %q (want field=source)", e)
+        }
+        known := false
+        for _, t := range Targets { known = known || t == k }
+        if !known {
+            return nil, fmt.Errorf("unknown field %q in mapping (want %s)", k, strings.Join(Targets, ", "))
+        }
+        m[k] = v
+    }
+    return m, nil
+}
+
+// agentVars are the variables an agent's own config uses, preferred when a
+// record carries several agents' settings.
+var agentVars = map[core.AgentID]map[string][]string{
+    core.AgentClaude: {"url": {"ANTHROPIC_BASE_URL"}, "token": {"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_KEY"}, "model": {"ANTHROPIC_MODEL"}},
+    core.AgentGemini: {"url": {"GOOGLE_GEMINI_BASE_URL"}, "token": {"GEMINI_API_KEY", "GOOGLE_API_KEY"}, "model": {"GEMINI_MODEL"}},
+    core.AgentCodex:  {"url": {"OPENAI_BASE_URL"}, "token": {"OPENAI_API_KEY"}},
+}
+
+// defaults are the source fields tried, in order, when the mapping names none.
+var defaults = map[string][]string{
+    "alias": {"alias", "name", "title"},
+    "url":   {"url", "base_url", "api_base", "endpoint", "website", "uri", "login.uris.0.uri"},
+    "token": {"token", "api_key", "auth_token", "key", "password", "credential"},
+    "model": {"model"},
+    "notes": {"notes", "notesPlain", "description"},
+    "tags":  {"tags"},
+}
+
+// Get returns the value of target in r: the mapped source field, or the first
+// default that is present.
+func (r Record) Get(target string, m Mapping, agent core.AgentID) string {
+    if src, ok := m[target]; ok { return lookup(r.Fields, []string{src}) }
+    cands := append(append([]string(nil), agentVars[agent.Base()][target]...), defaults[target]...)
+    return lookup(r.Fields, cands)
+}
+
+// lookup finds the first candidate among the fields. A candidate matches a field
+// by full key, else by last key segment, ignoring case and punctuation: "base_url"
+// also finds "baseURL", and "env.ANTHROPIC_BASE_URL" is found as ANTHROPIC_BASE_URL.
+func lookup(fields map[string]string, cands []string) string {
+    keys := make([]string, 0, len(fields))
+    for k := range fields { keys = append(keys, k) }
+    sort.Strings(keys) // deterministic among equal matches
+    for _, c := range cands {
+        nc := norm(c)
+        for _, seg := range []func(string) string{func(k string) string { return k }, lastSegment} {
+            for _, k := range keys {
+                if v := strings.TrimSpace(fields[k]); v != "" && norm(seg(k)) == nc { return v }
+            }
+        }
+    }
+    return ""
+}
+
+func lastSegment(k string) string {
+    if i := strings.LastIndexByte(k, '.'); i >= 0 { return k[i+1:] }
+    return k
+}
+
+func norm(s string) string {
+    var b strings.Builder
+    for _, r := range strings.ToLower(s) {
+        if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') { b.WriteRune(r) }
+    }
+    return b.String()
+}
+
+// Preset builds the preset a record describes. Records without a token are
+// rejected: password-manager exports are mostly unrelated logins. An empty URL
+// means the official endpoint, as on init.
+func (r Record) Preset(m Mapping, agent core.AgentID, now time.Time) (core.Preset, error) {
+    name := r.Get("alias", m, agent)
+    if name == "" { name = r.Name }
+    p := core.Preset{
+        Alias: Alias(name), URL: r.Get("url", m, agent), Token: r.Get("token", m, agent),
+        Model: r.Get("model", m, agent), Notes: r.Get("notes", m, agent),
+        Tags: core.ParseTags(r.Get("tags", m, agent)), AddedAt: core.Timestamp(now),
+    }
+    if p.Token == "" { return p, errors.New("no token") }
+    if err := core.ValidateFields(p.Fields()); err != nil { return p, err }
+    return p, nil
+}
+
+var aliasBad = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Alias turns an entry name into a valid alias (A-Za-z0-9_-), leaving room for
+// a conflict suffix within the 32-character limit.
+func Alias(name string) string {
+    a := strings.Trim(aliasBad.ReplaceAllString(strings.TrimSpace(name), "-"), "-")
+    if len(a) > 28 { a = strings.TrimRight(a[:28], "-") }
+    if a == "" { a = "imported" }
+    return a
+}
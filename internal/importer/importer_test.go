@@ -0,0 +1,136 @@
+package importer
+
+import (
+    "os"
+    "path/filepath"
+    "slices"
+    "strings"
+    "testing"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+    "tks/internal/store"
+)
+
+var now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// load parses a testdata fixture and builds a preset from every record,
+// keeping the error text of the rejected ones.
+func load(t *testing.T, f Format, file string, m Mapping, agent core.AgentID) ([]core.Preset, []string) {
+    t.Helper()
+    path := filepath.Join("testdata", file)
+    data, err := os.ReadFile(path)
+    if err != nil { t.Fatal(err) }
+    recs, err := Parse(f, data, path)
+    if err != nil { t.Fatal(err) }
+    var ps []core.Preset
+    var errs []string
+    for _, r := range recs {
+        p, err := r.Preset(m, agent, now)
+        if err != nil { errs = append(errs, err.Error()); continue }
+        ps = append(ps, p)
+    }
+    return ps, errs
+}
+
+func TestParseFormats(t *testing.T) {
+    for _, c := range []struct {
+        format Format
+        file   string
+        agent  core.AgentID
+        want   []core.Preset
+        errs   []string
+    }{
+        {FormatBitwarden, "bitwarden.json", core.AgentClaude, []core.Preset{
+            {Alias: "Relay-work", URL: "https://relay.example.com", Token: "sk-bw-1", Model: "relay-large", Notes: "team gateway"},
+        }, []string{"no token"}},
+        {Format1Password, "1password.csv", core.AgentClaude, []core.Preset{
+            {Alias: "Gateway", URL: "https://gw.example.com", Token: "sk-op-1", Notes: "shared key"},
+            {Alias: "Official", Token: "sk-op-2"}, // no URL: the official endpoint
+        }, nil},
+        {FormatDotenv, "work.env", core.AgentClaude, []core.Preset{
+            {Alias: "work", URL: "https://work.example.com", Token: "sk-env-1", Model: "work-model"},
+        }, nil},
+        {FormatDotenv, "work.env", core.AgentCodex, []core.Preset{
+            {Alias: "work", Token: "sk-openai"}, // the agent's own variables win
+        }, nil},
+        {FormatJSON, "presets.json", core.AgentClaude, []core.Preset{
+            {Alias: "alpha", URL: "https://alpha.example.com", Token: "sk-json-1", Tags: []string{"prod", "team-a"}},
+            {Alias: "beta", URL: "https://beta.example.com", Token: "sk-json-2"},
+        }, nil},
+        {FormatYAML, "presets.yaml", core.AgentClaude, []core.Preset{
+            {Alias: "one", URL: "https://one.example.com", Token: "sk-yaml-1", Model: "m-1"},
+            {Alias: "two", URL: "https://two.example.com", Token: "sk-yaml-2"},
+        }, nil},
+    } {
+        t.Run(string(c.format)+"/"+string(c.agent), func(t *testing.T) {
+            got, errs := load(t, c.format, c.file, nil, c.agent)
+            if !slices.Equal(errs, c.errs) { t.Errorf("errors = %q, want %q", errs, c.errs) }
+            if len(got) != len(c.want) { t.Fatalf("presets = %+v", got) }
+            for i, w := range c.want {
+                g := got[i]
+                if g.Alias != w.Alias || g.URL != w.URL || g.Token != w.Token || g.Model != w.Model || g.Notes != w.Notes || !slices.Equal(g.Tags, w.Tags) {
+                    t.Errorf("preset %d = %+v, want %+v", i, g, w)
+                }
+                if g.AddedAt != core.Timestamp(now) { t.Errorf("added at %q", g.AddedAt) }
+            }
+        })
+    }
+}
+
+func TestParseRejects(t *testing.T) {
+    if _, err := Parse(FormatBitwarden, []byte(`{"encrypted": true, "items": []}`), "x.json"); err == nil || !strings.Contains(err.Error(), "encrypted") {
+        t.Fatalf("encrypted export: %v", err)
+    }
+    if _, err := Parse(FormatJSON, []byte(`{`), "x.json"); err == nil { t.Fatal("broken json parsed") }
+    if _, err := ParseFormat("xml"); err == nil { t.Fatal("unknown format accepted") }
+    if f, err := ParseFormat("yml"); err != nil || f != FormatYAML { t.Fatalf("yml = %q, %v", f, err) }
+}
+
+func TestMapping(t *testing.T) {
+    m, err := ParseMapping("url=Website, token=Username, alias=Notes")
+    if err != nil { t.Fatal(err) }
+    got, _ := load(t, Format1Password, "1password.csv", m, core.AgentClaude)
+    if len(got) != 2 { t.Fatalf("presets = %+v", got) }
+    if p := got[0]; p.Alias != "shared-key" || p.URL != "https://gw.example.com" || p.Token != "me" { t.Fatalf("mapped %+v", p) }
+    // a mapped source that is empty falls back to the entry's name, not to the defaults
+    if p := got[1]; p.Alias != "Official" || p.Token != "me" { t.Fatalf("mapped %+v", p) }
+
+    for _, s := range []string{"url", "url=", "secret=Password"} {
+        if _, err := ParseMapping(s); err == nil { t.Errorf("ParseMapping(%q) accepted", s) }
+    }
+}
+
+func TestAlias(t *testing.T) {
+    for in, want := range map[string]string{
+        "Relay (work)":                 "Relay-work",
+        "  spaced  name ":              "spaced-name",
+        "***":                          "imported",
+        "":                             "imported",
+        strings.Repeat("x", 40):        strings.Repeat("x", 28),
+        strings.Repeat("y", 27) + " z": strings.Repeat("y", 27),
+    } {
+        got := Alias(in)
+        if got != want { t.Errorf("Alias(%q) = %q, want %q", in, got, want) }
+        if err := core.ValidateAlias(got); err != nil { t.Error(err) }
+    }
+}
+
+func TestImportSuffixesAndDedups(t *testing.T) {
+    fsxtest.UseRoot(t)
+    if err := store.AddPreset(core.AgentClaude, core.Preset{Alias: "one", URL: "https://other.example.com", Token: "sk-other"}); err != nil { t.Fatal(err) }
+    if err := store.AddPreset(core.AgentClaude, core.Preset{Alias: "kept", URL: "https://two.example.com", Token: "sk-yaml-2"}); err != nil { t.Fatal(err) }
+    ps, _ := load(t, FormatYAML, "presets.yaml", nil, core.AgentClaude)
+    ps = append(ps, ps[0]) // the same entry twice in one file
+
+    res, err := store.ImportPresets(core.AgentClaude, ps, store.MergeRename, false)
+    if err != nil { t.Fatal(err) }
+    if r := res[0]; r.Requested != "one" || r.Alias != "one-2" { t.Errorf("conflict: %+v", r) }
+    if r := res[1]; r.Duplicate != "kept" { t.Errorf("existing values: %+v", r) }
+    if r := res[2]; r.Duplicate != "one-2" { t.Errorf("repeated entry: %+v", r) }
+    list, _ := store.LoadPresets(core.AgentClaude)
+    var aliases []string
+    for _, p := range list { aliases = append(aliases, p.Alias) }
+    if !slices.Equal(aliases, []string{"one", "kept", "one-2"}) { t.Fatalf("stored %v", aliases) }
+}
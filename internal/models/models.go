@@ -0,0 +1,200 @@
+// Package models lists the model IDs an endpoint serves, with an offline
+// catalog of well-known IDs per agent for when the endpoint cannot be asked.
+package models
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+// Timeout bounds a listing request when the preset sets no timeout of its own.
+var Timeout = 15 * time.Second
+
+// Default endpoints used by presets without a base URL (official endpoint).
+var (
+    ClaudeBaseURL = "https://api.anthropic.com"
+    GeminiBaseURL = "https://generativelanguage.googleapis.com"
+    CodexBaseURL  = "https://api.openai.com/v1"
+)
+
+// Catalog holds well-known model IDs per agent kind, newest first. It is only a
+// starting point: gateways often serve other names.
+var Catalog = map[core.AgentID][]string{
+    core.AgentClaude: {
+        "claude-opus-4-1", "claude-opus-4-0", "claude-sonnet-4-5", "claude-sonnet-4-0",
+        "claude-haiku-4-5", "claude-3-7-sonnet-latest", "claude-3-5-haiku-latest",
+    },
+    core.AgentGemini: {
+        "gemini-2.5-pro", "gemini-2.5-flash", "gemini-2.5-flash-lite", "gemini-2.0-flash",
+    },
+    core.AgentCodex: {
+        "gpt-5-codex", "gpt-5", "gpt-5-mini", "gpt-5-nano", "o3", "o4-mini", "gpt-4.1",
+    },
+}
+
+// Fetch asks the endpoint f points at for its model IDs, sorted. f.Token must be
+// resolved already; f's headers and network settings are honored. client may be
+// nil to build one from f.Network.
+func Fetch(ctx context.Context, agent core.AgentID, f core.Fields, client *http.Client) ([]string, error) {
+    if client == nil {
+        var err error
+        if client, err = newClient(f.Network); err != nil { return nil, err }
+    }
+    var ids []string
+    var err error
+    switch agent.Base() {
+    case core.AgentClaude:
+        ids, err = fetchClaude(ctx, client, f)
+    case core.AgentGemini:
+        if f.Auth == core.GeminiAuthVertex {
+            return nil, errors.New("listing Vertex AI models is not supported; use the catalog")
+        }
+        ids, err = fetchGemini(ctx, client, f)
+    case core.AgentCodex:
+        ids, err = fetchOpenAI(ctx, client, f)
+    default:
+        return nil, fmt.Errorf("unknown agent: %s", agent)
+    }
+    if err != nil { return nil, err }
+    sort.Strings(ids)
+    return ids, nil
+}
+
+// newClient honors the preset's proxy, CA bundle and timeout.
+func newClient(n core.Network) (*http.Client, error) {
+    tr := http.DefaultTransport.(*http.Transport).Clone()
+    if n.Proxy != "" {
+        u, err := url.Parse(n.Proxy)
+        if err != nil { return nil, fmt.Errorf("proxy: %w", err) }
+        tr.Proxy = func(r *http.Request) (*url.URL, error) {
+            if bypassProxy(r.URL.Hostname(), n.NoProxy) { return nil, nil }
+            return u, nil
+        }
+    }
+    if n.CABundle != "" {
+        pem, err := fsx.ReadFile(n.CABundle)
+        if err != nil { return nil, fmt.Errorf("ca bundle: %w", err) }
+        pool, err := x509.SystemCertPool()
+        if err != nil || pool == nil { pool = x509.NewCertPool() }
+        if !pool.AppendCertsFromPEM(pem) { return nil, fmt.Errorf("ca bundle %s: no certificates", n.CABundle) }
+        tr.TLSClientConfig = &tls.Config{RootCAs: pool}
+    }
+    timeout := Timeout
+    if ms := n.TimeoutMillis(); ms > 0 { timeout = time.Duration(ms) * time.Millisecond }
+    return &http.Client{Transport: tr, Timeout: timeout}, nil
+}
+
+// bypassProxy applies a NO_PROXY-style list: exact hosts, ".suffix" domains or "*".
+func bypassProxy(host, noProxy string) bool {
+    for _, e := range strings.Split(noProxy, ",") {
+        e = strings.TrimSpace(e)
+        switch {
+        case e == "":
+        case e == "*", strings.EqualFold(e, host):
+            return true
+        case strings.HasPrefix(e, ".") && strings.HasSuffix(strings.ToLower(host), strings.ToLower(e)):
+            return true
+        }
+    }
+    return false
+}
+
+func fetchClaude(ctx context.Context, c *http.Client, f core.Fields) ([]string, error) {
+    base := baseURL(f.URL, ClaudeBaseURL)
+    var ids []string
+    after := ""
+    for {
+        u := base + "/v1/models?limit=1000"
+        if after != "" { u += "&after_id=" + url.QueryEscape(after) }
+        var page struct {
+            Data    []struct{ ID string `json:"id"` } `json:"data"`
+            HasMore bool   `json:"has_more"`
+            LastID  string `json:"last_id"`
+        }
+        h := map[string]string{"anthropic-version": "2023-06-01"}
+        if f.Token != "" {
+            // gateways differ on the auth header, so both are sent
+            h["x-api-key"], h["Authorization"] = f.Token, "Bearer "+f.Token
+        }
+        if err := getJSON(ctx, c, u, h, f.Headers, &page); err != nil { return nil, err }
+        for _, m := range page.Data { ids = append(ids, m.ID) }
+        if !page.HasMore || page.LastID == "" || page.LastID == after { return ids, nil }
+        after = page.LastID
+    }
+}
+
+func fetchGemini(ctx context.Context, c *http.Client, f core.Fields) ([]string, error) {
+    base := baseURL(f.URL, GeminiBaseURL)
+    var ids []string
+    token := ""
+    for {
+        u := base + "/v1beta/models?pageSize=1000"
+        if token != "" { u += "&pageToken=" + url.QueryEscape(token) }
+        var page struct {
+            Models []struct{ Name string `json:"name"` } `json:"models"`
+            Next   string `json:"nextPageToken"`
+        }
+        if err := getJSON(ctx, c, u, map[string]string{"x-goog-api-key": f.Token}, f.Headers, &page); err != nil { return nil, err }
+        for _, m := range page.Models { ids = append(ids, strings.TrimPrefix(m.Name, "models/")) }
+        if page.Next == "" || page.Next == token { return ids, nil }
+        token = page.Next
+    }
+}
+
+func fetchOpenAI(ctx context.Context, c *http.Client, f core.Fields) ([]string, error) {
+    var page struct {
+        Data []struct{ ID string `json:"id"` } `json:"data"`
+    }
+    u := baseURL(f.URL, CodexBaseURL) + "/models"
+    h := map[string]string{}
+    if f.Token != "" { h["Authorization"] = "Bearer " + f.Token }
+    if err := getJSON(ctx, c, u, h, f.Headers, &page); err != nil { return nil, err }
+    ids := make([]string, 0, len(page.Data))
+    for _, m := range page.Data { ids = append(ids, m.ID) }
+    return ids, nil
+}
+
+func baseURL(u, def string) string {
+    if strings.TrimSpace(u) == "" { u = def }
+    return strings.TrimRight(u, "/")
+}
+
+// getJSON sends a GET with the auth headers plus the preset's own headers and decodes the reply.
+func getJSON(ctx context.Context, c *http.Client, u string, auth map[string]string, extra core.Headers, v any) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+    if err != nil { return err }
+    for k, val := range auth {
+        if val != "" { req.Header.Set(k, val) }
+    }
+    for _, h := range extra { req.Header.Set(h.Name, h.Value) }
+    resp, err := c.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("%s: %s: %s", redact(u), resp.Status, strings.TrimSpace(string(b)))
+    }
+    if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+        return fmt.Errorf("%s: unexpected reply: %w", redact(u), err)
+    }
+    return nil
+}
+
+// redact drops the query, which may carry page tokens, from URLs shown in errors.
+func redact(u string) string {
+    if i := strings.IndexByte(u, '?'); i >= 0 { return u[:i] }
+    return u
+}
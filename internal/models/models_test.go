@@ -0,0 +1,114 @@
+package models
+
+import (
+    "context"
+    "encoding/json"
+    "encoding/pem"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "slices"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+func serveJSON(t *testing.T, h func(r *http.Request) any) *httptest.Server {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        _ = json.NewEncoder(w).Encode(h(r))
+    }))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+func TestFetchClaudePages(t *testing.T) {
+    srv := serveJSON(t, func(r *http.Request) any {
+        if r.Header.Get("x-api-key") != "sk-1" || r.Header.Get("X-Team") != "infra" {
+            t.Errorf("headers = %v", r.Header)
+        }
+        if r.URL.Query().Get("after_id") == "" {
+            return map[string]any{"data": []map[string]string{{"id": "m-b"}}, "has_more": true, "last_id": "m-b"}
+        }
+        return map[string]any{"data": []map[string]string{{"id": "m-a"}}, "has_more": false}
+    })
+    f := core.Fields{URL: srv.URL + "/", Token: "sk-1", Headers: core.Headers{{Name: "X-Team", Value: "infra"}}}
+    ids, err := Fetch(context.Background(), core.AgentClaude, f, nil)
+    if err != nil { t.Fatal(err) }
+    if !slices.Equal(ids, []string{"m-a", "m-b"}) { t.Fatalf("ids = %v", ids) }
+}
+
+func TestFetchGeminiAndOpenAI(t *testing.T) {
+    gem := serveJSON(t, func(r *http.Request) any {
+        if r.URL.Path != "/v1beta/models" || r.Header.Get("x-goog-api-key") != "g-1" { t.Errorf("gemini request %s %v", r.URL, r.Header) }
+        return map[string]any{"models": []map[string]string{{"name": "models/gemini-x"}}}
+    })
+    ids, err := Fetch(context.Background(), core.AgentGemini, core.Fields{URL: gem.URL, Token: "g-1"}, nil)
+    if err != nil || !slices.Equal(ids, []string{"gemini-x"}) { t.Fatalf("gemini: %v %v", ids, err) }
+
+    oai := serveJSON(t, func(r *http.Request) any {
+        if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-2" { t.Errorf("openai request %s %v", r.URL, r.Header) }
+        return map[string]any{"data": []map[string]string{{"id": "gpt-y"}, {"id": "gpt-x"}}}
+    })
+    ids, err = Fetch(context.Background(), core.AgentCodex, core.Fields{URL: oai.URL + "/v1", Token: "sk-2"}, nil)
+    if err != nil || !slices.Equal(ids, []string{"gpt-x", "gpt-y"}) { t.Fatalf("codex: %v %v", ids, err) }
+}
+
+func TestFetchReportsHTTPErrors(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "bad key", http.StatusUnauthorized)
+    }))
+    defer srv.Close()
+    _, err := Fetch(context.Background(), core.AgentCodex, core.Fields{URL: srv.URL, Token: "sk-bad"}, nil)
+    if err == nil || !strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "sk-bad") {
+        t.Fatalf("err = %v", err)
+    }
+}
+
+// memFS serves some files from memory and everything else from the real FS.
+type memFS struct {
+    fsx.FS
+    files map[string][]byte
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+    if b, ok := m.files[name]; ok { return b, nil }
+    return m.FS.ReadFile(name)
+}
+
+func TestCABundleReadThroughFsx(t *testing.T) {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        _, _ = w.Write([]byte(`{"data":[{"id":"tls-model"}]}`))
+    }))
+    defer srv.Close()
+    ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+    m := &memFS{files: map[string][]byte{"/virtual/ca.pem": ca}}
+    m.FS = fsx.SetFS(m)
+    t.Cleanup(func() { fsx.SetFS(m.FS) })
+    if _, err := os.Stat("/virtual/ca.pem"); err == nil { t.Skip("path exists on the host") }
+
+    f := core.Fields{URL: srv.URL, Network: core.Network{CABundle: "/virtual/ca.pem"}}
+    ids, err := Fetch(context.Background(), core.AgentCodex, f, nil)
+    if err != nil || !slices.Equal(ids, []string{"tls-model"}) { t.Fatalf("ids = %v, err = %v", ids, err) }
+
+    // without the bundle the test server's certificate is not trusted
+    if _, err := Fetch(context.Background(), core.AgentCodex, core.Fields{URL: srv.URL}, nil); err == nil {
+        t.Fatal("untrusted certificate accepted")
+    }
+}
+
+func TestBypassProxy(t *testing.T) {
+    for _, c := range []struct {
+        host, list string
+        want       bool
+    }{
+        {"api.corp", "localhost,.corp", true},
+        {"api.example.com", "localhost,.corp", false},
+        {"localhost", "localhost", true},
+        {"anything", "*", true},
+    } {
+        if got := bypassProxy(c.host, c.list); got != c.want { t.Errorf("bypassProxy(%q, %q) = %v", c.host, c.list, got) }
+    }
+}
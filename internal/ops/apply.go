@@ -0,0 +1,212 @@
+package ops
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "io/fs"
+    "os"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/providers"
+    "tks/internal/secret"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+// Sources recorded in history entries.
+const (
+    SourceCLI = "cli"
+    SourceTUI = "tui"
+)
+
+// ApplyPreset writes a preset to the agent's config, mirroring its model, headers,
+// network settings and model tiers strictly (what the preset leaves unset is
+// removed), and records the apply in history.
+func ApplyPreset(ctx context.Context, agent core.AgentID, p core.Preset, source string) (core.Backup, error) {
+    f, err := presetFields(agent, p)
+    if err != nil {
+        return core.Backup{}, err
+    }
+    return Apply(providers.WithMirror(ctx), agent, p.Alias, f, p.Model == "", source)
+}
+
+// presetFields is what applying p writes. A key-helper preset hands the agent a
+// command that asks agtok for the token instead of the token itself; a Gemini
+// preset always names its auth mode, so applying it switches modes.
+func presetFields(agent core.AgentID, p core.Preset) (core.Fields, error) {
+    f := p.Fields()
+    switch {
+    case agent.Base() == core.AgentGemini:
+        f.Auth = core.GeminiAuth(p.Auth)
+    case p.Auth != "":
+        return f, fmt.Errorf("preset %s: auth modes are only supported for gemini", p.Alias)
+    }
+    if !p.Tiers.IsZero() && agent.Base() != core.AgentClaude {
+        return f, fmt.Errorf("preset %s: model tiers are only supported for claude", p.Alias)
+    }
+    if len(p.Headers) > 0 && agent.Base() == core.AgentGemini {
+        return f, fmt.Errorf("preset %s: custom headers are not supported for gemini", p.Alias)
+    }
+    // presets saved before the store checked their network settings
+    if err := core.CheckNetwork(agent, p.Network); err != nil {
+        return f, fmt.Errorf("preset %s: %w", p.Alias, err)
+    }
+    if p.KeyHelper {
+        if agent.Base() != core.AgentClaude {
+            return f, fmt.Errorf("preset %s: key helper mode is only supported for claude", p.Alias)
+        }
+        f.Token, f.Helper = "", core.KeyHelperCommand(agent, p.Alias, fsx.CurrentRoot().ConfigDir)
+    }
+    return f, nil
+}
+
+// Apply writes fields through the agent's provider and appends a history entry.
+// alias names the preset being applied; empty for ad-hoc values. Headers, network
+// settings and model tiers the fields leave unset are kept unless ctx is marked
+// with providers.WithMirror.
+func Apply(ctx context.Context, agent core.AgentID, alias string, f core.Fields, clearModel bool, source string) (core.Backup, error) {
+    prov := providers.NewProvider(agent)
+    if prov == nil {
+        return core.Backup{}, fmt.Errorf("provider not available for agent: %s", agent)
+    }
+    if clearModel {
+        ctx = providers.WithClearModel(ctx, agent)
+    }
+    // token references are resolved here, at the last moment, and only the
+    // agent's own config receives the value
+    ref := f.Token
+    tok, err := secret.Resolve(ctx, ref)
+    if err != nil {
+        return core.Backup{}, err
+    }
+    f.Token = tok
+    if f.Headers, err = resolveHeaders(ctx, agent, f.Headers); err != nil {
+        return core.Backup{}, err
+    }
+    if f.Helper != "" {
+        ref = "(apiKeyHelper)"
+    }
+    bk, err := prov.Write(ctx, f)
+    if err != nil {
+        return bk, err
+    }
+    e := core.HistoryEntry{
+        Time: bk.Time, Agent: agent, Action: core.ActionApply, Alias: alias,
+        Token: util.Mask(ref), TokenFP: util.Fingerprint(tok), Backups: bk.Files, Written: bk.Written, Source: source,
+    }
+    if err := store.AppendHistory(e); err != nil {
+        return bk, fmt.Errorf("applied, but recording history failed: %w", err)
+    }
+    if alias != "" {
+        if err := store.RecordUse(agent, alias, time.Now(), util.Fingerprint(tok)); err != nil {
+            return bk, fmt.Errorf("applied, but recording usage failed: %w", err)
+        }
+    }
+    return bk, nil
+}
+
+// ErrModified is returned by Undo when a file no longer holds what the apply wrote.
+var ErrModified = errors.New("changed since that apply")
+
+// Undo restores the files backed up by the agent's most recent apply that has not
+// been undone yet. The undo itself is recorded (with fresh backups), so repeated
+// undos walk further back. Unless force is set, it refuses when a file was edited
+// after the apply, since restoring would silently discard those edits.
+func Undo(agent core.AgentID, force bool, source string) (core.HistoryEntry, error) {
+    hist, err := store.LoadHistory(agent)
+    if err != nil {
+        return core.HistoryEntry{}, err
+    }
+    applies := liveApplies(hist)
+    if len(applies) == 0 {
+        return core.HistoryEntry{}, fmt.Errorf("nothing to undo for %s", agent)
+    }
+    target := applies[len(applies)-1]
+    if !force {
+        // entries written before hashes were recorded cannot be checked
+        for path, want := range target.Written {
+            got, err := fsx.FileSum(path)
+            if err != nil { return core.HistoryEntry{}, err }
+            if got != want { return core.HistoryEntry{}, fmt.Errorf("%s %w", path, ErrModified) }
+        }
+    }
+    e := core.HistoryEntry{Agent: agent, Action: core.ActionUndo, Undoes: target.ID, Backups: map[string]string{}, Written: map[string]string{}, Source: source}
+    if len(applies) > 1 {
+        e.Alias = applies[len(applies)-2].Alias
+    }
+    for path, bak := range target.Backups {
+        cur, err := fsx.BackupFile(path)
+        if err != nil && !errors.Is(err, os.ErrNotExist) {
+            return core.HistoryEntry{}, err
+        }
+        e.Backups[path] = cur
+        if bak == "" {
+            // the apply created this file; undo removes it
+            if err := fsx.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+                return core.HistoryEntry{}, err
+            }
+            continue
+        }
+        b, err := fsx.ReadFile(bak)
+        if err != nil {
+            return core.HistoryEntry{}, fmt.Errorf("backup unavailable: %w", err)
+        }
+        if err := fsx.AtomicWrite(path, b, fs.FileMode(0o600)); err != nil {
+            return core.HistoryEntry{}, err
+        }
+        e.Written[path] = fsx.Sum(b)
+    }
+    if err := store.AppendHistory(e); err != nil {
+        return e, fmt.Errorf("restored, but recording history failed: %w", err)
+    }
+    return e, nil
+}
+
+// PreviousAlias returns the preset that was active before the current one, like "cd -".
+func PreviousAlias(agent core.AgentID) (string, error) {
+    hist, err := store.LoadHistory(agent)
+    if err != nil {
+        return "", err
+    }
+    applies := liveApplies(hist)
+    cur := ""
+    for i := len(applies) - 1; i >= 0; i-- {
+        a := applies[i].Alias
+        if a == "" { continue }
+        if cur == "" { cur = a; continue }
+        if a != cur { return a, nil }
+    }
+    return "", fmt.Errorf("no previous preset recorded for %s", agent)
+}
+
+// liveApplies returns apply entries (oldest first) that have not been reverted by an undo.
+func liveApplies(hist []core.HistoryEntry) []core.HistoryEntry {
+    undone := map[string]bool{}
+    for _, e := range hist {
+        if e.Action == core.ActionUndo { undone[e.Undoes] = true }
+    }
+    var out []core.HistoryEntry
+    for _, e := range hist {
+        if e.Action == core.ActionApply && !undone[e.ID] { out = append(out, e) }
+    }
+    return out
+}
+
+// resolveHeaders resolves header values that are secret references. Codex reads
+// env: references itself (env_http_headers), so those are passed through.
+func resolveHeaders(ctx context.Context, agent core.AgentID, h core.Headers) (core.Headers, error) {
+    out := make(core.Headers, 0, len(h))
+    for _, x := range h {
+        if !(agent.Base() == core.AgentCodex && strings.HasPrefix(x.Value, "env:")) {
+            v, err := secret.Resolve(ctx, x.Value)
+            if err != nil { return nil, fmt.Errorf("header %s: %w", x.Name, err) }
+            x.Value = v
+        }
+        out = append(out, x)
+    }
+    return out, nil
+}
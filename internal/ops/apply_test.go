@@ -0,0 +1,45 @@
+package ops
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/fsx/fsxtest"
+)
+
+func TestUndoRefusesLaterEdits(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    path := filepath.Join(home, ".claude", "settings.json")
+    ctx := context.Background()
+    for _, tok := range []string{"sk-first", "sk-second"} {
+        f := core.Fields{URL: "https://api.example.com", Token: tok}
+        if _, err := Apply(ctx, core.AgentClaude, "", f, false, SourceCLI); err != nil { t.Fatal(err) }
+    }
+    edited := strings.Replace(fsxtest.ReadFile(t, path), "sk-second", "sk-edited", 1)
+    if err := fsx.WriteFile(path, []byte(edited), 0o600); err != nil { t.Fatal(err) }
+
+    if _, err := Undo(core.AgentClaude, false, SourceCLI); !errors.Is(err, ErrModified) {
+        t.Fatalf("undo over an edited file: err = %v, want ErrModified", err)
+    }
+    if got := fsxtest.ReadFile(t, path); got != edited { t.Fatal("refused undo changed the file") }
+
+    if _, err := Undo(core.AgentClaude, true, SourceCLI); err != nil { t.Fatal(err) }
+    if got := fsxtest.ReadFile(t, path); !strings.Contains(got, "sk-first") { t.Fatalf("forced undo did not restore:\n%s", got) }
+}
+
+func TestUndoRestoresUnchangedFile(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    path := filepath.Join(home, ".claude", "settings.json")
+    ctx := context.Background()
+    for _, tok := range []string{"sk-first", "sk-second"} {
+        f := core.Fields{URL: "https://api.example.com", Token: tok}
+        if _, err := Apply(ctx, core.AgentClaude, "", f, false, SourceCLI); err != nil { t.Fatal(err) }
+    }
+    if _, err := Undo(core.AgentClaude, false, SourceCLI); err != nil { t.Fatal(err) }
+    if got := fsxtest.ReadFile(t, path); !strings.Contains(got, "sk-first") { t.Fatalf("undo did not restore:\n%s", got) }
+}
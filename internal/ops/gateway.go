@@ -0,0 +1,47 @@
+package ops
+
+import (
+    "context"
+    "fmt"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// UpdateGatewayKey replaces a gateway's key in every derived preset. Agents whose
+// config was running a derived preset get the new key applied as well, so a key
+// rotation does not leave them on the old one. It returns the re-applied agents.
+func UpdateGatewayKey(ctx context.Context, name, key, source string) ([]core.AgentID, error) {
+    g, err := store.GetGateway(name)
+    if err != nil {
+        return nil, err
+    }
+    // note which agents are on a derived preset before the presets change
+    var active []core.AgentID
+    for _, agent := range g.Agents() {
+        prov := providers.NewProvider(agent)
+        if prov == nil { continue }
+        cur, err := prov.Read(ctx)
+        if err != nil { continue }
+        ps, _ := store.LoadPresets(agent)
+        for _, p := range ps {
+            if p.Gateway == name && core.Matches(p, cur) { active = append(active, agent) }
+        }
+    }
+    if _, err := store.SetGatewayKey(name, key); err != nil {
+        return nil, err
+    }
+    var applied []core.AgentID
+    for _, agent := range active {
+        ps, _ := store.LoadPresets(agent)
+        for _, p := range ps {
+            if p.Gateway != name { continue }
+            if _, err := ApplyPreset(ctx, agent, p, source); err != nil {
+                return applied, fmt.Errorf("%s: presets updated, but applying the new key failed: %w", agent, err)
+            }
+            applied = append(applied, agent)
+        }
+    }
+    return applied, nil
+}
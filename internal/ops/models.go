@@ -0,0 +1,61 @@
+package ops
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    core "tks/internal/core"
+    "tks/internal/models"
+    "tks/internal/providers"
+    "tks/internal/secret"
+    "tks/internal/store"
+)
+
+// FetchModels asks the endpoint of preset alias for its models and caches the
+// result for the preset. With alias "" the agent's current config is asked; its
+// result is cached only when the config matches a preset exactly. It returns the
+// alias the result was cached under ("" when not cached).
+func FetchModels(ctx context.Context, agent core.AgentID, alias string) ([]string, string, error) {
+    var f core.Fields
+    if alias != "" {
+        p, err := store.GetPreset(agent, alias)
+        if err != nil { return nil, "", err }
+        f = p.Fields()
+        if agent.Base() == core.AgentGemini { f.Auth = core.GeminiAuth(p.Auth) }
+    } else {
+        prov := providers.NewProvider(agent)
+        if prov == nil { return nil, "", fmt.Errorf("provider not available for agent: %s", agent) }
+        var err error
+        if f, err = prov.Read(ctx); err != nil { return nil, "", err }
+        ps, _ := store.LoadPresets(agent)
+        if m, ok := core.Nearest(ps, f); ok && m.Exact() { alias = m.Alias }
+    }
+    tok, err := secret.Resolve(ctx, f.Token)
+    if err != nil { return nil, "", err }
+    f.Token = tok
+    // the request is made here, so even codex env: headers are resolved
+    if f.Headers, err = resolveHeaders(ctx, "", f.Headers); err != nil { return nil, "", err }
+    ids, err := models.Fetch(ctx, agent, f, nil)
+    if err != nil { return nil, "", err }
+    if alias != "" {
+        if err := store.SaveModelCache(agent, alias, ids, time.Now()); err != nil {
+            return ids, "", fmt.Errorf("fetched, but caching failed: %w", err)
+        }
+    }
+    return ids, alias, nil
+}
+
+// ModelChoices lists the models to offer for a preset without asking the network:
+// its cached list when there is one, else the offline catalog for the agent kind.
+// source describes where the list came from, e.g. "cached 2026-01-02 15:04" or "catalog".
+func ModelChoices(agent core.AgentID, alias string) (ids []string, source string) {
+    if alias != "" {
+        if c, ok, err := store.LoadModelCache(agent, alias); err == nil && ok && len(c.Models) > 0 {
+            source = "cached"
+            if t, ok := core.ParseTime(c.FetchedAt); ok { source += " " + t.Local().Format("2006-01-02 15:04") }
+            return c.Models, source
+        }
+    }
+    return models.Catalog[agent.Base()], "catalog"
+}
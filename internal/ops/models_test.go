@@ -0,0 +1,41 @@
+package ops
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "slices"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+    "tks/internal/models"
+    "tks/internal/store"
+)
+
+func TestFetchModelsCachesAndFallsBack(t *testing.T) {
+    fsxtest.UseRoot(t)
+    ctx := context.Background()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        _, _ = w.Write([]byte(`{"data":[{"id":"gw-model-b"},{"id":"gw-model-a"}]}`))
+    }))
+    p := core.Preset{Alias: "gw", URL: srv.URL, Token: "sk-1"}
+    if err := store.AddPreset(core.AgentCodex, p); err != nil { t.Fatal(err) }
+
+    // nothing fetched yet: the offline catalog
+    ids, source := ModelChoices(core.AgentCodex, "gw")
+    if source != "catalog" || !slices.Equal(ids, models.Catalog[core.AgentCodex]) { t.Fatalf("before fetch: %s %v", source, ids) }
+
+    ids, cachedAs, err := FetchModels(ctx, core.AgentCodex, "gw")
+    if err != nil { t.Fatal(err) }
+    if cachedAs != "gw" || !slices.Equal(ids, []string{"gw-model-a", "gw-model-b"}) { t.Fatalf("fetched %v as %q", ids, cachedAs) }
+
+    // the endpoint goes away: fetching fails, the cached list is still offered
+    srv.Close()
+    if _, _, err := FetchModels(ctx, core.AgentCodex, "gw"); err == nil { t.Fatal("fetch from a closed server succeeded") }
+    ids, source = ModelChoices(core.AgentCodex, "gw")
+    if !strings.HasPrefix(source, "cached") || !slices.Equal(ids, []string{"gw-model-a", "gw-model-b"}) {
+        t.Fatalf("offline: %s %v", source, ids)
+    }
+}
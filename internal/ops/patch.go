@@ -0,0 +1,60 @@
+package ops
+
+import (
+    "context"
+    "fmt"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+)
+
+// Patch names the fields a partial apply changes; nil fields keep their value on disk.
+type Patch struct {
+    URL, Token, Model *string
+    ClearModel        bool
+}
+
+// Empty reports whether the patch changes nothing.
+func (p Patch) Empty() bool { return p.URL == nil && p.Token == nil && p.Model == nil && !p.ClearModel }
+
+// patchFields returns the fields p writes and ctx marked for them. Only the keys
+// the patch names are written: providers keep a token or model left empty, and
+// without mirroring they keep headers, network settings and tiers; the URL is kept
+// through providers.WithKeepURL.
+func patchFields(ctx context.Context, p Patch) (context.Context, core.Fields, error) {
+    var f core.Fields
+    if p.Model != nil && p.ClearModel {
+        return ctx, f, fmt.Errorf("a model and clearing the model are mutually exclusive")
+    }
+    if p.URL != nil {
+        f.URL = *p.URL
+    } else {
+        ctx = providers.WithKeepURL(ctx)
+    }
+    // a token replaces an agtok key helper (providers drop it)
+    if p.Token != nil { f.Token = *p.Token }
+    if p.Model != nil { f.Model = *p.Model }
+    if err := core.ValidateFields(f); err != nil {
+        return ctx, f, err
+    }
+    return ctx, f, nil
+}
+
+// PlanPatch previews ApplyPatch without writing anything.
+func PlanPatch(ctx context.Context, agent core.AgentID, p Patch) (Plan, error) {
+    ctx, f, err := patchFields(ctx, p)
+    if err != nil {
+        return Plan{}, err
+    }
+    return PlanApply(ctx, agent, "", f, p.ClearModel)
+}
+
+// ApplyPatch writes only the fields p names, with backups and a history entry
+// like any apply. The entry names no preset: a patch is an ad-hoc change.
+func ApplyPatch(ctx context.Context, agent core.AgentID, p Patch, source string) (core.Backup, error) {
+    ctx, f, err := patchFields(ctx, p)
+    if err != nil {
+        return core.Backup{}, err
+    }
+    return Apply(ctx, agent, "", f, p.ClearModel, source)
+}
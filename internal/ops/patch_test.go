@@ -0,0 +1,116 @@
+package ops
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+)
+
+// claudeSettings splits a settings file into its env block and its other keys,
+// compacted so formatting does not matter.
+func claudeSettings(t *testing.T, path string) (map[string]string, map[string]string) {
+    t.Helper()
+    var top map[string]json.RawMessage
+    if err := json.Unmarshal([]byte(fsxtest.ReadFile(t, path)), &top); err != nil { t.Fatal(err) }
+    env := map[string]string{}
+    if err := json.Unmarshal(top["env"], &env); err != nil { t.Fatal(err) }
+    rest := map[string]string{}
+    for k, v := range top {
+        if k == "env" { continue }
+        var b bytes.Buffer
+        if err := json.Compact(&b, v); err != nil { t.Fatal(err) }
+        rest[k] = b.String()
+    }
+    return env, rest
+}
+
+func TestPatchModelLeavesClaudeKeysAlone(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    path := filepath.Join(home, ".claude", "settings.json")
+    fsxtest.WriteFile(t, path, `{
+  "theme": "dark",
+  "model": "opus",
+  "permissions": {"allow": ["Bash(ls)"]},
+  "env": {
+    "ANTHROPIC_BASE_URL": "https://gw.example.com",
+    "ANTHROPIC_API_KEY": "sk-hand-set",
+    "ANTHROPIC_CUSTOM_HEADERS": "X-Team: infra",
+    "HTTPS_PROXY": "http://proxy:3128",
+    "NODE_EXTRA_CA_CERTS": "/etc/ssl/corp.pem",
+    "API_TIMEOUT_MS": "600000",
+    "ANTHROPIC_DEFAULT_OPUS_MODEL": "gw-large",
+    "ANTHROPIC_SMALL_FAST_MODEL": "gw-small",
+    "ANTHROPIC_MODEL": "old-model"
+  }
+}`)
+    beforeEnv, beforeRest := claudeSettings(t, path)
+    model := "new-model"
+    ctx := context.Background()
+    plan, err := PlanPatch(ctx, core.AgentClaude, Patch{Model: &model})
+    if err != nil { t.Fatal(err) }
+    if len(plan.Fields) != 1 || plan.Fields[0].Field != "Model" { t.Fatalf("plan fields = %+v", plan.Fields) }
+    if _, err := ApplyPatch(ctx, core.AgentClaude, Patch{Model: &model}, SourceCLI); err != nil { t.Fatal(err) }
+
+    env, rest := claudeSettings(t, path)
+    if env["ANTHROPIC_MODEL"] != model { t.Fatalf("model = %q", env["ANTHROPIC_MODEL"]) }
+    if _, ok := env["ANTHROPIC_AUTH_TOKEN"]; ok { t.Fatal("patch copied the API key into ANTHROPIC_AUTH_TOKEN") }
+    delete(env, "ANTHROPIC_MODEL")
+    delete(beforeEnv, "ANTHROPIC_MODEL")
+    if len(env) != len(beforeEnv) { t.Fatalf("env keys changed: %v -> %v", beforeEnv, env) }
+    for k, v := range beforeEnv {
+        if env[k] != v { t.Errorf("env %s: %q -> %q", k, v, env[k]) }
+    }
+    for k, v := range beforeRest {
+        if rest[k] != v { t.Errorf("%s: %s -> %s", k, v, rest[k]) }
+    }
+}
+
+func TestPatchModelLeavesCodexLinesAlone(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    dir := filepath.Join(home, ".codex")
+    before := strings.Join([]string{
+        `model = "old-model"`,
+        `model_provider = "gw"`,
+        ``,
+        `[model_providers.gw]`,
+        `name = "gateway"`,
+        `base_url = 'https://gw.example.com/v1'`,
+        `http_headers = { "X-Team" = "infra" }`,
+        `stream_idle_timeout_ms = 600000`,
+        ``,
+    }, "\n")
+    fsxtest.WriteFile(t, filepath.Join(dir, "config.toml"), before)
+    fsxtest.WriteFile(t, filepath.Join(dir, "auth.json"), `{"OPENAI_API_KEY": "sk-hand-set"}`)
+    model := "new-model"
+    if _, err := ApplyPatch(context.Background(), core.AgentCodex, Patch{Model: &model}, SourceCLI); err != nil { t.Fatal(err) }
+    want := strings.Replace(before, `model = "old-model"`, `model = "new-model"`, 1)
+    if got := fsxtest.ReadFile(t, filepath.Join(dir, "config.toml")); got != want {
+        t.Fatalf("config.toml:\n%s\nwant:\n%s", got, want)
+    }
+    if auth := fsxtest.ReadFile(t, filepath.Join(dir, "auth.json")); auth != `{"OPENAI_API_KEY": "sk-hand-set"}` { t.Fatalf("auth.json = %s", auth) }
+}
+
+func TestPatchModelLeavesGeminiSettingsAlone(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    dir := filepath.Join(home, ".gemini")
+    env := "GOOGLE_GEMINI_BASE_URL=https://gw.example.com\nGEMINI_API_KEY=g-hand-set\nGEMINI_MODEL=old-model\n"
+    settings := `{"selectedAuthType": "oauth-personal", "theme": "dark"}`
+    fsxtest.WriteFile(t, filepath.Join(dir, ".env"), env)
+    fsxtest.WriteFile(t, filepath.Join(dir, "settings.json"), settings)
+    model := "new-model"
+    ctx := context.Background()
+    plan, err := PlanPatch(ctx, core.AgentGemini, Patch{Model: &model})
+    if err != nil { t.Fatal(err) }
+    for _, fc := range plan.Files {
+        if filepath.Base(fc.Path) == "settings.json" { t.Fatalf("plan rewrites settings.json:\n%s", fc.New) }
+    }
+    if _, err := ApplyPatch(ctx, core.AgentGemini, Patch{Model: &model}, SourceCLI); err != nil { t.Fatal(err) }
+    if got := fsxtest.ReadFile(t, filepath.Join(dir, ".env")); got != strings.Replace(env, "old-model", model, 1) { t.Fatalf(".env:\n%s", got) }
+    if got := fsxtest.ReadFile(t, filepath.Join(dir, "settings.json")); got != settings { t.Fatalf("settings.json = %s", got) }
+}
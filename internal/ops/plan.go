@@ -0,0 +1,119 @@
+package ops
+
+import (
+    "context"
+    "fmt"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/secret"
+)
+
+// Plan is a preview of an apply: the managed fields that change and the bytes
+// each config file would end up with.
+type Plan struct {
+    Agent   core.AgentID
+    Alias   string
+    Fields  []core.FieldChange
+    Files   []core.FileChange
+    // Secrets are resolved token and secret header values present in Files;
+    // mask them before display.
+    Secrets []string
+}
+
+// PlanPreset previews ApplyPreset without writing anything.
+func PlanPreset(ctx context.Context, agent core.AgentID, p core.Preset) (Plan, error) {
+    f, err := presetFields(agent, p)
+    if err != nil {
+        return Plan{}, err
+    }
+    return PlanApply(providers.WithMirror(ctx), agent, p.Alias, f, p.Model == "")
+}
+
+// PlanApply previews Apply without writing anything. Token references are
+// resolved as Apply resolves them, so the preview shows what will really be
+// written; the resolved values are listed in Secrets.
+func PlanApply(ctx context.Context, agent core.AgentID, alias string, f core.Fields, clearModel bool) (Plan, error) {
+    prov := providers.NewProvider(agent)
+    if prov == nil {
+        return Plan{}, fmt.Errorf("provider not available for agent: %s", agent)
+    }
+    tok, err := secret.Resolve(ctx, f.Token)
+    if err != nil {
+        return Plan{}, err
+    }
+    f.Token = tok
+    if f.Headers, err = resolveHeaders(ctx, agent, f.Headers); err != nil {
+        return Plan{}, err
+    }
+    old, err := prov.Read(ctx)
+    if err != nil {
+        return Plan{}, err
+    }
+    if clearModel {
+        ctx = providers.WithClearModel(ctx, agent)
+    }
+    files, err := providers.Plan(ctx, prov, f)
+    if err != nil {
+        return Plan{}, err
+    }
+    // providers keep the current token/model when the new value is empty
+    after := core.Fields{URL: f.URL, Token: old.Token, Model: old.Model, Helper: old.Helper}
+    if providers.KeepingURL(ctx) { after.URL = old.URL }
+    switch {
+    case f.Helper != "":
+        after.Token, after.Helper = "", f.Helper
+    case f.Token != "":
+        after.Token = f.Token
+        if core.KeyHelperAlias(old.Helper) != "" { after.Helper = "" }
+    }
+    after.Headers, after.Network, after.Tiers = f.Headers, f.Network, f.Tiers
+    if !providers.Mirroring(ctx) {
+        if len(f.Headers) == 0 { after.Headers = old.Headers }
+        after.Network = keepNetwork(old.Network, f.Network)
+        after.Tiers = keepTiers(old.Tiers, f.Tiers)
+    }
+    after.Auth, after.Project, after.Location = old.Auth, old.Project, old.Location
+    if f.Auth != "" {
+        after.Auth, after.Project, after.Location = f.Auth, "", ""
+        if f.Auth == core.GeminiAuthVertex { after.Project, after.Location = f.Project, f.Location }
+    }
+    if clearModel {
+        after.Model = ""
+    } else if f.Model != "" {
+        after.Model = f.Model
+    }
+    var secrets []string
+    for _, t := range []string{old.Token, after.Token} {
+        if t != "" { secrets = append(secrets, t) }
+    }
+    secrets = append(append(secrets, old.Headers.Secrets()...), after.Headers.Secrets()...)
+    return Plan{Agent: agent, Alias: alias, Fields: core.ChangedFields(old, after), Files: files, Secrets: secrets}, nil
+}
+
+// keepNetwork is n with the settings it leaves unset taken from old, as providers
+// write them without mirroring.
+func keepNetwork(old, n core.Network) core.Network {
+    if n.Proxy == "" { n.Proxy = old.Proxy }
+    if n.NoProxy == "" { n.NoProxy = old.NoProxy }
+    if n.CABundle == "" { n.CABundle = old.CABundle }
+    if n.Timeout == "" { n.Timeout = old.Timeout }
+    return n
+}
+
+// keepTiers is t with the tiers it leaves unset taken from old.
+func keepTiers(old, t core.ModelTiers) core.ModelTiers {
+    if t.Main == "" { t.Main = old.Main }
+    if t.Opus == "" { t.Opus = old.Opus }
+    if t.Sonnet == "" { t.Sonnet = old.Sonnet }
+    if t.Haiku == "" { t.Haiku = old.Haiku }
+    return t
+}
+
+// Changed reports whether applying would alter any file.
+func (p Plan) Changed() bool {
+    for _, f := range p.Files {
+        if f.Changed() { return true }
+    }
+    return false
+}
@@ -0,0 +1,95 @@
+package ops
+
+import (
+    "context"
+    "path/filepath"
+    "slices"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/fsx/fsxtest"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+func TestPlanResolvesTokenReference(t *testing.T) {
+    fsxtest.UseRoot(t)
+    t.Setenv("AGTOK_TEST_KEY", "sk-resolved-1234")
+    ctx := context.Background()
+    p := core.Preset{Alias: "ref", URL: "https://api.example.com", Token: "env:AGTOK_TEST_KEY"}
+    if err := store.AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+
+    plan, err := PlanPreset(ctx, core.AgentClaude, p)
+    if err != nil { t.Fatal(err) }
+    if !slices.Contains(plan.Secrets, "sk-resolved-1234") { t.Fatalf("resolved token not in Secrets: %v", plan.Secrets) }
+    for _, f := range plan.Files {
+        if strings.Contains(string(f.New), "env:AGTOK_TEST_KEY") { t.Fatal("plan writes the reference instead of the secret") }
+    }
+    var tokenChange *core.FieldChange
+    for i, c := range plan.Fields {
+        if c.Field == "Token" { tokenChange = &plan.Fields[i] }
+    }
+    if tokenChange == nil || tokenChange.New != "sk-resolved-1234" || !tokenChange.Secret {
+        t.Fatalf("token change = %+v", tokenChange)
+    }
+
+    if _, err := ApplyPreset(ctx, core.AgentClaude, p, SourceCLI); err != nil { t.Fatal(err) }
+    plan, err = PlanPreset(ctx, core.AgentClaude, p)
+    if err != nil { t.Fatal(err) }
+    if len(plan.Fields) != 0 { t.Fatalf("re-applying the same reference reports changes: %+v", plan.Fields) }
+}
+
+func TestReferenceMatchesOnlyItsResolvedKey(t *testing.T) {
+    fsxtest.UseRoot(t)
+    t.Setenv("AGTOK_TEST_KEY", "sk-resolved-1234")
+    ctx := context.Background()
+    p := core.Preset{Alias: "ref", URL: "https://api.example.com", Token: "env:AGTOK_TEST_KEY"}
+    if err := store.AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+    disk := core.Fields{URL: p.URL, Token: "sk-resolved-1234"}
+    if core.Matches(p, disk) { t.Fatal("never-applied reference reported as matching") }
+
+    if _, err := ApplyPreset(ctx, core.AgentClaude, p, SourceCLI); err != nil { t.Fatal(err) }
+    p, err := store.GetPreset(core.AgentClaude, "ref")
+    if err != nil { t.Fatal(err) }
+    if !core.Matches(p, disk) { t.Fatal("applied reference does not match the key it wrote") }
+    if core.Matches(p, core.Fields{URL: p.URL, Token: "sk-someone-else"}) { t.Fatal("reference matches a different key") }
+}
+
+func TestPlanMasksSecretHeaders(t *testing.T) {
+    fsxtest.UseRoot(t)
+    t.Setenv("AGTOK_TEST_HEADER", "hdr-resolved-5678")
+    ctx := context.Background()
+    old := core.Headers{{Name: "X-Api-Key", Value: "hdr-old-1111"}}
+    if _, err := Apply(ctx, core.AgentClaude, "", core.Fields{URL: "https://api.example.com", Token: "sk-1", Headers: old}, false, SourceCLI); err != nil {
+        t.Fatal(err)
+    }
+    p := core.Preset{Alias: "hdr", URL: "https://api.example.com", Token: "sk-1", Headers: core.Headers{
+        {Name: "Authorization", Value: "Bearer hdr-plain-1234"},
+        {Name: "X-Api-Key", Value: "env:AGTOK_TEST_HEADER"},
+        {Name: "X-Team", Value: "infra"},
+    }}
+    plan, err := PlanPreset(ctx, core.AgentClaude, p)
+    if err != nil { t.Fatal(err) }
+    var diff strings.Builder
+    for _, f := range plan.Files { diff.WriteString(util.MaskAll(core.UnifiedDiff(f), plan.Secrets)) }
+    out := diff.String()
+    for _, v := range []string{"hdr-old-1111", "hdr-plain-1234", "hdr-resolved-5678"} {
+        if strings.Contains(out, v) { t.Errorf("file diff shows %s:\n%s", v, out) }
+    }
+    if !strings.Contains(out, "infra") { t.Errorf("non-secret header masked:\n%s", out) }
+}
+
+func TestAdHocPlanKeepsHandSetKeys(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    path := filepath.Join(home, ".claude", "settings.json")
+    if err := fsx.MkdirAll(filepath.Dir(path), 0o700); err != nil { t.Fatal(err) }
+    hand := `{"model": "opus", "env": {"ANTHROPIC_BASE_URL": "https://old.example.com", "HTTPS_PROXY": "http://proxy:3128", "ANTHROPIC_CUSTOM_HEADERS": "X-Team: infra", "ANTHROPIC_DEFAULT_OPUS_MODEL": "gw-large"}}`
+    if err := fsx.WriteFile(path, []byte(hand), 0o600); err != nil { t.Fatal(err) }
+    plan, err := PlanApply(context.Background(), core.AgentClaude, "", core.Fields{URL: "https://api.example.com", Token: "sk-1"}, false)
+    if err != nil { t.Fatal(err) }
+    for _, c := range plan.Fields {
+        if c.Field == "Network" || c.Field == "Headers" || c.Field == "Tiers" { t.Fatalf("ad-hoc plan reports %s change: %+v", c.Field, c) }
+    }
+}
@@ -0,0 +1,99 @@
+package ops
+
+import (
+    "context"
+    "fmt"
+
+    core "tks/internal/core"
+    "tks/internal/providers"
+    "tks/internal/store"
+    "tks/internal/util"
+)
+
+// RotateResult is the outcome of rotating one preset.
+type RotateResult struct {
+    Agent   core.AgentID
+    Alias   string
+    Active  bool  // the agent's config was running this preset
+    Applied bool  // the new key was written to the agent's config
+    Err     error
+}
+
+// RotateReport lists every preset and gateway whose key was rotated.
+type RotateReport struct {
+    Presets  []RotateResult
+    Gateways []string
+}
+
+// Failed reports whether any preset could not be updated or re-applied.
+func (r RotateReport) Failed() bool {
+    for _, x := range r.Presets {
+        if x.Err != nil { return true }
+    }
+    return false
+}
+
+// FindByFingerprint lists presets on every agent whose token matches fp (see
+// util.MatchesFingerprint), marking those the agent's config currently runs.
+// A fingerprint that matches more than one distinct key is rejected.
+func FindByFingerprint(ctx context.Context, fp string) ([]RotateResult, error) {
+    var out []RotateResult
+    keys := map[string]bool{}
+    for _, agent := range providers.Agents() {
+        ps, err := store.LoadPresets(agent)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", agent, err)
+        }
+        var cur core.Fields
+        if prov := providers.NewProvider(agent); prov != nil {
+            cur, _ = prov.Read(ctx)
+        }
+        for _, p := range ps {
+            if !util.MatchesFingerprint(p.Token, fp) { continue }
+            keys[p.Token] = true
+            out = append(out, RotateResult{Agent: agent, Alias: p.Alias, Active: core.Matches(p, cur)})
+        }
+    }
+    if len(keys) > 1 {
+        return nil, fmt.Errorf("fingerprint %s matches %d different keys; use the sha fingerprint", fp, len(keys))
+    }
+    return out, nil
+}
+
+// Rotate replaces the key identified by fp with newToken in every matching preset
+// and gateway, then re-applies the presets that were active. Per-preset failures
+// are recorded in the report; the error is for failures before anything changed.
+func Rotate(ctx context.Context, fp, newToken, source string) (RotateReport, error) {
+    var rep RotateReport
+    matches, err := FindByFingerprint(ctx, fp)
+    if err != nil {
+        return rep, err
+    }
+    gws, err := store.LoadGateways()
+    if err != nil {
+        return rep, err
+    }
+    if len(matches) == 0 {
+        return rep, fmt.Errorf("no preset uses a key matching %s", fp)
+    }
+    // gateways first: syncing rewrites their derived presets, which also matched
+    for _, g := range gws {
+        if !util.MatchesFingerprint(g.Key, fp) { continue }
+        if _, err := store.SetGatewayKey(g.Name, newToken); err != nil {
+            return rep, fmt.Errorf("gateway %s: %w", g.Name, err)
+        }
+        rep.Gateways = append(rep.Gateways, g.Name)
+    }
+    for _, r := range matches {
+        tok := newToken
+        if r.Err = store.UpdatePreset(r.Agent, r.Alias, r.Alias, nil, &tok, nil, false, false); r.Err == nil && r.Active {
+            var p core.Preset
+            if p, r.Err = store.GetPreset(r.Agent, r.Alias); r.Err == nil {
+                _, r.Err = ApplyPreset(ctx, r.Agent, p, source)
+                r.Applied = r.Err == nil
+            }
+        }
+        rep.Presets = append(rep.Presets, r)
+    }
+    return rep, nil
+}
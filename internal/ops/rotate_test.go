@@ -0,0 +1,62 @@
+package ops
+
+import (
+    "context"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+    "tks/internal/store"
+)
+
+func TestRotateSharedKey(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    ctx := context.Background()
+    shared := "sk-shared-old-1234"
+    work := core.Preset{Alias: "work", URL: "https://gw.example.com", Token: shared}
+    mirror := core.Preset{Alias: "mirror", URL: "https://gw.example.com/v1", Token: shared}
+    other := core.Preset{Alias: "other", URL: "https://other.example.com", Token: "sk-other-9999"}
+    if err := store.AddPreset(core.AgentClaude, work); err != nil { t.Fatal(err) }
+    if err := store.AddPreset(core.AgentClaude, other); err != nil { t.Fatal(err) }
+    if err := store.AddPreset(core.AgentCodex, mirror); err != nil { t.Fatal(err) }
+    if _, err := ApplyPreset(ctx, core.AgentClaude, work, SourceCLI); err != nil { t.Fatal(err) }
+
+    found, err := FindByFingerprint(ctx, "1234")
+    if err != nil { t.Fatal(err) }
+    if len(found) != 2 { t.Fatalf("found %+v", found) }
+    for _, r := range found {
+        if r.Active != (r.Agent == core.AgentClaude) { t.Errorf("%s/%s active = %v", r.Agent, r.Alias, r.Active) }
+    }
+
+    rep, err := Rotate(ctx, "****1234", "sk-shared-new-5678", SourceCLI)
+    if err != nil { t.Fatal(err) }
+    if rep.Failed() || len(rep.Presets) != 2 { t.Fatalf("report = %+v", rep) }
+    for agent, alias := range map[core.AgentID]string{core.AgentClaude: "work", core.AgentCodex: "mirror"} {
+        if p, _ := store.GetPreset(agent, alias); p.Token != "sk-shared-new-5678" { t.Errorf("%s/%s token = %q", agent, alias, p.Token) }
+    }
+    if p, _ := store.GetPreset(core.AgentClaude, "other"); p.Token != "sk-other-9999" { t.Errorf("unrelated preset rotated: %q", p.Token) }
+
+    // the active claude config is rewritten; codex, which was not running it, is not written
+    settings := fsxtest.ReadFile(t, filepath.Join(home, ".claude", "settings.json"))
+    if !strings.Contains(settings, "sk-shared-new-5678") || strings.Contains(settings, shared) { t.Fatalf("settings.json:\n%s", settings) }
+    for _, r := range rep.Presets {
+        if r.Applied != (r.Agent == core.AgentClaude) { t.Errorf("%s applied = %v", r.Agent, r.Applied) }
+    }
+}
+
+func TestRotateRejectsAmbiguousFingerprint(t *testing.T) {
+    fsxtest.UseRoot(t)
+    for _, p := range []core.Preset{
+        {Alias: "a", URL: "https://a.example.com", Token: "sk-first-1234"},
+        {Alias: "b", URL: "https://b.example.com", Token: "sk-second-1234"},
+    } {
+        if err := store.AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+    }
+    if _, err := Rotate(context.Background(), "1234", "sk-new", SourceCLI); err == nil || !strings.Contains(err.Error(), "different keys") {
+        t.Fatalf("err = %v", err)
+    }
+    if p, _ := store.GetPreset(core.AgentClaude, "a"); p.Token != "sk-first-1234" { t.Fatalf("rotated anyway: %q", p.Token) }
+    if _, err := Rotate(context.Background(), "0000", "sk-new", SourceCLI); err == nil { t.Fatal("unknown fingerprint accepted") }
+}
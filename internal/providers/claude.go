@@ -2,86 +2,190 @@ package providers
 
 import (
     "context"
-    "encoding/json"
     "errors"
-    "io/fs"
+    "fmt"
     "os"
     "path/filepath"
+    "strings"
     core "tks/internal/core"
     "tks/internal/fsx"
 )
 
-type claude struct{}
+type claude struct {
+    id   core.AgentID
+    home string // config directory, e.g. ~/.claude
+}
 
-func (c *claude) ID() core.AgentID { return core.AgentClaude }
+func (c *claude) ID() core.AgentID { return c.id }
 
 func (c *claude) Paths() []string {
-    return []string{joinHome(".claude", "settings.json")}
+    return []string{filepath.Join(c.home, "settings.json")}
 }
 
-type claudeSettings struct {
-    Env map[string]string `json:"env"`
-}
+// claudeHelperKey is the settings.json key naming a command that prints the API key.
+const claudeHelperKey = "apiKeyHelper"
 
 func (c *claude) Read(ctx context.Context) (core.Fields, error) {
-    p := c.Paths()[0]
-    b, err := os.ReadFile(p)
+    o, err := readClaudeSettings(c.Paths()[0])
     if err != nil {
         if errors.Is(err, os.ErrNotExist) {
             return core.Fields{}, nil
         }
         return core.Fields{}, err
     }
-    var s claudeSettings
-    if err := json.Unmarshal(b, &s); err != nil {
+    env := map[string]string{}
+    if _, err := o.get("env", &env); err != nil {
         return core.Fields{}, err
     }
-    if s.Env == nil {
-        s.Env = map[string]string{}
-    }
-    // Prefer common keys in order: AUTH_TOKEN -> API_TOKEN -> API_KEY
-    token := s.Env["ANTHROPIC_AUTH_TOKEN"]
-    if token == "" {
-        token = s.Env["ANTHROPIC_API_TOKEN"]
-    }
-    if token == "" {
-        token = s.Env["ANTHROPIC_API_KEY"]
-    }
+    // only agtok's own helper is managed; a foreign one is left to the user
+    var helper string
+    _, _ = o.get(claudeHelperKey, &helper)
+    if core.KeyHelperAlias(helper) == "" { helper = "" }
     return core.Fields{
-        URL:   s.Env["ANTHROPIC_BASE_URL"],
-        Token: token,
-        Model: s.Env["ANTHROPIC_MODEL"],
+        URL:    env["ANTHROPIC_BASE_URL"],
+        Token:  claudeToken(env),
+        Model:  env["ANTHROPIC_MODEL"],
+        Helper: helper,
+        Headers: parseClaudeHeaders(env[claudeHeadersKey]),
+        Network: readNetworkVars(env, claudeTimeoutKey),
+        Tiers:   readClaudeTiers(o, env),
     }, nil
 }
 
+// claudeMainModelKey is the top-level settings.json model setting (what /model writes).
+const claudeMainModelKey = "model"
+
+// Tier variables; the small/fast key is the older name of the haiku tier, so
+// both carry Tiers.Haiku.
+const (
+    claudeOpusKey      = "ANTHROPIC_DEFAULT_OPUS_MODEL"
+    claudeSonnetKey    = "ANTHROPIC_DEFAULT_SONNET_MODEL"
+    claudeHaikuKey     = "ANTHROPIC_DEFAULT_HAIKU_MODEL"
+    claudeSmallFastKey = "ANTHROPIC_SMALL_FAST_MODEL"
+)
+
+func readClaudeTiers(o jsonObject, env map[string]string) core.ModelTiers {
+    t := core.ModelTiers{Opus: env[claudeOpusKey], Sonnet: env[claudeSonnetKey], Haiku: env[claudeHaikuKey]}
+    if t.Haiku == "" { t.Haiku = env[claudeSmallFastKey] }
+    _, _ = o.get(claudeMainModelKey, &t.Main)
+    return t
+}
+
+// claudeTierVars is the env part of a tier mapping; empty values are removed.
+func claudeTierVars(t core.ModelTiers) map[string]string {
+    return map[string]string{claudeOpusKey: t.Opus, claudeSonnetKey: t.Sonnet, claudeHaikuKey: t.Haiku, claudeSmallFastKey: t.Haiku}
+}
+
+// claudeTimeoutKey is Claude Code's API request timeout, in milliseconds.
+const claudeTimeoutKey = "API_TIMEOUT_MS"
+
+// claudeHeadersKey holds custom headers, one "Name: value" per line.
+const claudeHeadersKey = "ANTHROPIC_CUSTOM_HEADERS"
+
+func parseClaudeHeaders(s string) core.Headers {
+    var h core.Headers
+    for _, ln := range strings.Split(s, "\n") {
+        if i := strings.IndexByte(ln, ':'); i > 0 {
+            h = append(h, core.Header{Name: strings.TrimSpace(ln[:i]), Value: strings.TrimSpace(ln[i+1:])})
+        }
+    }
+    return h
+}
+
+func formatClaudeHeaders(h core.Headers) string {
+    lines := make([]string, 0, len(h))
+    for _, x := range h { lines = append(lines, x.Name+": "+x.Value) }
+    return strings.Join(lines, "\n")
+}
+
+func readClaudeSettings(p string) (jsonObject, error) {
+    b, err := fsx.ReadFile(p)
+    if err != nil { return jsonObject{}, err }
+    return parseJSONObject(b)
+}
+
+// readClaudeEnv returns the "env" block of a Claude settings file (never nil on success).
+func readClaudeEnv(p string) (map[string]string, error) {
+    o, err := readClaudeSettings(p)
+    if err != nil { return nil, err }
+    env := map[string]string{}
+    if _, err := o.get("env", &env); err != nil { return nil, err }
+    if env == nil { env = map[string]string{} }
+    return env, nil
+}
+
+// claudeToken picks the token among common keys in order: AUTH_TOKEN -> API_TOKEN -> API_KEY.
+func claudeToken(env map[string]string) string {
+    for _, k := range claudeTokenKeys {
+        if v := env[k]; v != "" { return v }
+    }
+    return ""
+}
+
+var claudeTokenKeys = []string{"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_API_TOKEN", "ANTHROPIC_API_KEY"}
+
 func (c *claude) Write(ctx context.Context, fields core.Fields) (core.Backup, error) {
-    p := c.Paths()[0]
-    _ = os.MkdirAll(filepath.Dir(p), 0o700)
-    s := claudeSettings{Env: map[string]string{}}
-    if b, err := os.ReadFile(p); err == nil {
-        _ = json.Unmarshal(b, &s)
-        if s.Env == nil { s.Env = map[string]string{} }
+    changes, err := c.Plan(ctx, fields)
+    if err != nil { return core.Backup{}, err }
+    return commit(changes)
+}
+
+func (c *claude) Plan(ctx context.Context, fields core.Fields) ([]core.FileChange, error) {
+    fc, err := currentFile(c.Paths()[0])
+    if err != nil { return nil, err }
+    // only env (and apiKeyHelper) are managed; other top-level keys are kept in place
+    o, err := parseJSONObject(fc.Old)
+    if err != nil { return nil, fmt.Errorf("%s: %w", fc.Path, err) }
+    env := map[string]string{}
+    if _, err := o.get("env", &env); err != nil { return nil, fmt.Errorf("%s: env: %w", fc.Path, err) }
+    if env == nil { env = map[string]string{} }
+    // an empty URL selects the official endpoint: drop the override
+    switch {
+    case KeepingURL(ctx):
+    case fields.URL != "":
+        env["ANTHROPIC_BASE_URL"] = fields.URL
+    default:
+        delete(env, "ANTHROPIC_BASE_URL")
+    }
+    mirror := Mirroring(ctx)
+    if len(fields.Headers) > 0 {
+        env[claudeHeadersKey] = formatClaudeHeaders(fields.Headers)
+    } else if mirror {
+        delete(env, claudeHeadersKey)
+    }
+    setNetworkVars(env, fields.Network, claudeTimeoutKey, mirror)
+    for k, v := range claudeTierVars(fields.Tiers) {
+        if v != "" || mirror { setOrDelete(env, k, v) }
     }
-    s.Env["ANTHROPIC_BASE_URL"] = fields.URL
-    // allow empty token to keep existing; when provided, write only AUTH_TOKEN as requested
-    if fields.Token != "" {
-        s.Env["ANTHROPIC_AUTH_TOKEN"] = fields.Token
+    if fields.Tiers.Main != "" {
+        if err := o.set(claudeMainModelKey, fields.Tiers.Main); err != nil { return nil, err }
+    } else if mirror {
+        o.del(claudeMainModelKey)
+    }
+    var helper string
+    _, _ = o.get(claudeHelperKey, &helper)
+    switch {
+    case fields.Helper != "":
+        // the helper supplies the key: no token may stay behind in the file
+        if err := o.set(claudeHelperKey, fields.Helper); err != nil { return nil, err }
+        for _, k := range claudeTokenKeys { delete(env, k) }
+    case fields.Token != "":
+        // allow empty token to keep existing; when provided, write only AUTH_TOKEN as requested
+        env["ANTHROPIC_AUTH_TOKEN"] = fields.Token
+        // a helper left by an earlier agtok apply would shadow the token
+        if core.KeyHelperAlias(helper) != "" { o.del(claudeHelperKey) }
     }
     // write/clear model based on context and fields
     if v, ok := ctx.Value(CtxKeyClaudeClearModel).(bool); ok && v {
-        delete(s.Env, "ANTHROPIC_MODEL")
+        delete(env, "ANTHROPIC_MODEL")
     } else if fields.Model != "" {
-        s.Env["ANTHROPIC_MODEL"] = fields.Model
-    }
-    out, err := json.MarshalIndent(&s, "", "  ")
-    if err != nil { return core.Backup{}, err }
-    if err := fsx.BackupFile(p); err != nil && !errors.Is(err, os.ErrNotExist) {
-        return core.Backup{}, err
-    }
-    if err := fsx.AtomicWrite(p, out, fs.FileMode(0o600)); err != nil {
-        return core.Backup{}, err
+        env["ANTHROPIC_MODEL"] = fields.Model
     }
-    return core.Backup{}, nil
+    if err := o.set("env", env); err != nil { return nil, err }
+    out, err := o.marshal()
+    if err != nil { return nil, err }
+    fc.New = out
+    return []core.FileChange{fc}, nil
 }
 
 func (c *claude) Validate(f core.Fields) error { return core.ValidateFields(f) }
@@ -5,28 +5,38 @@ import (
     "context"
     "encoding/json"
     "errors"
-    "io/fs"
-    "os"
     "path/filepath"
+    "strconv"
     "strings"
     core "tks/internal/core"
     "tks/internal/fsx"
 )
 
-type codex struct{}
+type codex struct {
+    id   core.AgentID
+    home string // config directory, e.g. ~/.codex
+}
 
-func (c *codex) ID() core.AgentID { return core.AgentCodex }
+func (c *codex) ID() core.AgentID { return c.id }
 
 func (c *codex) Paths() []string {
-    return []string{joinHome(".codex", "config.toml"), joinHome(".codex", "auth.json")}
+    return []string{filepath.Join(c.home, "config.toml"), filepath.Join(c.home, "auth.json")}
 }
 
 func (c *codex) Read(ctx context.Context) (core.Fields, error) {
     paths := c.Paths()
+    return readCodexFiles(paths[0], paths[1]), nil
+}
+
+// readCodexFiles reads url/model from a config.toml and the token from an auth.json.
+// Missing or unreadable files yield empty values.
+func readCodexFiles(tomlPath, authPath string) core.Fields {
     // read base_url from toml; provider can be model_providers.*; prefer codex, fallback to first found
     var url string
     var model string
-    if f, err := os.Open(paths[0]); err == nil {
+    var headers core.Headers
+    var timeout string
+    if f, err := fsx.Open(tomlPath); err == nil {
         defer f.Close()
         s := bufio.NewScanner(f)
         inProviders := false
@@ -34,6 +44,8 @@ func (c *codex) Read(ctx context.Context) (core.Fields, error) {
         // preserve encounter order
         providerOrder := []string{}
         urlByProvider := map[string]string{}
+        hdrByProvider := map[string]core.Headers{}
+        timeoutByProvider := map[string]string{}
         selProvider := "" // from root-level model_provider
         for s.Scan() {
             line := strings.TrimSpace(s.Text())
@@ -53,7 +65,14 @@ func (c *codex) Read(ctx context.Context) (core.Fields, error) {
                 k := strings.TrimSpace(line[:i])
                 v := strings.Trim(strings.TrimSpace(line[i+1:]), "\"'")
                 if inProviders {
-                    if k == "base_url" { urlByProvider[curHeader] = v }
+                    switch k {
+                    case "base_url":
+                        urlByProvider[curHeader] = v
+                    case codexTimeoutKey:
+                        timeoutByProvider[curHeader] = core.TimeoutFromMillis(v)
+                    case "http_headers", "env_http_headers":
+                        hdrByProvider[curHeader] = append(hdrByProvider[curHeader], codexHeaders(k, line[i+1:])...)
+                    }
                 } else {
                     if k == "model" { model = v }
                     if k == "model_provider" { selProvider = v }
@@ -61,39 +80,51 @@ func (c *codex) Read(ctx context.Context) (core.Fields, error) {
             }
         }
         // choose url: prefer selected provider, else codex, else first provider
+        var from string
         if selProvider != "" {
-            if v, ok := urlByProvider["[model_providers."+selProvider+"]"]; ok && v != "" { url = v }
+            if v, ok := urlByProvider["[model_providers."+selProvider+"]"]; ok && v != "" { from = "[model_providers."+selProvider+"]" }
         }
-        if url == "" {
-            if v, ok := urlByProvider["[model_providers.codex]"]; ok && v != "" { url = v }
+        if from == "" {
+            if v, ok := urlByProvider["[model_providers.codex]"]; ok && v != "" { from = "[model_providers.codex]" }
         }
-        if url == "" {
-            for _, h := range providerOrder { if v := urlByProvider[h]; v != "" { url = v; break } }
+        if from == "" {
+            for _, h := range providerOrder { if urlByProvider[h] != "" { from = h; break } }
         }
+        // headers belong to the provider the url came from
+        url, headers = urlByProvider[from], hdrByProvider[from]
+        timeout = timeoutByProvider[from]
     }
     // read token from auth.json
     var token string
-    if b, err := os.ReadFile(paths[1]); err == nil {
+    if b, err := fsx.ReadFile(authPath); err == nil {
         var m map[string]string
         if json.Unmarshal(b, &m) == nil {
             token = m["OPENAI_API_KEY"]
         }
     }
-    return core.Fields{URL: url, Token: token, Model: model}, nil
+    return core.Fields{URL: url, Token: token, Model: model, Headers: headers, Network: core.Network{Timeout: timeout}}
 }
 
 func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, error) {
+    changes, err := c.Plan(ctx, fields)
+    if err != nil { return core.Backup{}, err }
+    return commit(changes)
+}
+
+func (c *codex) Plan(ctx context.Context, fields core.Fields) ([]core.FileChange, error) {
     paths := c.Paths()
-    // ensure dir
-    _ = os.MkdirAll(filepath.Dir(paths[0]), 0o700)
+    tomlFile, err := currentFile(paths[0])
+    if err != nil { return nil, err }
+    authFile, err := currentFile(paths[1])
+    if err != nil { return nil, err }
     // update toml
     // naive update: replace/ensure target provider section's base_url; root-level 'model'
     var lines []string
     var hadTargetSection bool
     var wroteKey bool
     var sawModel bool
-    if b, err := os.ReadFile(paths[0]); err == nil {
-        for _, ln := range strings.Split(string(b), "\n") {
+    if tomlFile.Existed {
+        for _, ln := range strings.Split(string(tomlFile.Old), "\n") {
             lines = append(lines, ln)
         }
     }
@@ -128,12 +159,24 @@ func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, err
     } else if !hasCodex && len(providerOrder) > 0 {
         targetHeader = providerOrder[0]
     }
-    inSection := false
+    // official endpoint: no provider may override base_url, and none is selected
+    keepURL := KeepingURL(ctx)
+    official := !keepURL && strings.TrimSpace(fields.URL) == ""
+    if official && (len(fields.Headers) > 0 || fields.Network.Timeout != "") {
+        return nil, errors.New("codex: custom headers and timeouts need a base url (they live in the provider section)")
+    }
+    mirror := Mirroring(ctx)
+    inSection, inProvider, atRoot := false, false, true
     for _, ln := range lines {
         line := strings.TrimSpace(ln)
         if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+            inProvider, atRoot = strings.HasPrefix(line, "[model_providers."), false
+            if official {
+                out = append(out, ln)
+                continue
+            }
             if inSection && !wroteKey {
-                out = append(out, "base_url = \""+fields.URL+"\"")
+                out = append(out, codexProviderLines(fields, keepURL)...)
                 wroteKey = true
             }
             inSection = (line == targetHeader)
@@ -141,9 +184,14 @@ func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, err
             out = append(out, ln)
             continue
         }
-        if inSection {
+        if official && inProvider {
+            if strings.HasPrefix(line, "base_url") || codexManagedLine(line, fields, mirror) { continue }
+        } else if inSection {
+            // headers are rewritten next to base_url
+            if codexManagedLine(line, fields, mirror) { continue }
             if strings.HasPrefix(line, "base_url") {
-                out = append(out, "base_url = \""+fields.URL+"\"")
+                if keepURL { out = append(out, ln) }
+                out = append(out, codexProviderLines(fields, keepURL)...)
                 wroteKey = true
                 continue
             }
@@ -151,6 +199,7 @@ func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, err
             // root level keys: parse k=v to match exact "model"
             if i := strings.Index(line, "="); i >= 0 {
                 k := strings.TrimSpace(line[:i])
+                if official && atRoot && k == "model_provider" { continue }
                 if k == "model" {
                     sawModel = true
                     if v, ok := ctx.Value(CtxKeyCodexClearModel).(bool); ok && v {
@@ -167,13 +216,15 @@ func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, err
         }
         out = append(out, ln)
     }
-    if !hadTargetSection {
+    switch pl := codexProviderLines(fields, keepURL); {
+    case official, len(pl) == 0:
+        // nothing to create
+    case !hadTargetSection:
         // no target section exists; create it at EOF (prefer codex name or fallback)
         out = append(out, targetHeader)
-        out = append(out, "base_url = \""+fields.URL+"\"")
-        wroteKey = true
-    } else if inSection && !wroteKey {
-        out = append(out, "base_url = \""+fields.URL+"\"")
+        out = append(out, pl...)
+    case inSection && !wroteKey:
+        out = append(out, pl...)
     }
     // ensure model root-level line if needed
     if !sawModel {
@@ -183,22 +234,116 @@ func (c *codex) Write(ctx context.Context, fields core.Fields) (core.Backup, err
             out = append([]string{"model = \""+fields.Model+"\""}, out...)
         }
     }
-    tomlOut := strings.Join(out, "\n")
-    if err := fsx.BackupFile(paths[0]); err != nil && !errors.Is(err, os.ErrNotExist) { return core.Backup{}, err }
-    if err := fsx.AtomicWrite(paths[0], []byte(tomlOut), fs.FileMode(0o600)); err != nil { return core.Backup{}, err }
-
-    // update auth.json
-    auth := map[string]string{}
-    if b, err := os.ReadFile(paths[1]); err == nil {
-        _ = json.Unmarshal(b, &auth)
-    }
-    if fields.Token != "" {
-        auth["OPENAI_API_KEY"] = fields.Token
+    tomlFile.New = []byte(strings.Join(out, "\n"))
+
+    // update auth.json; without a new token an existing file is kept byte for byte
+    authFile.New = authFile.Old
+    if fields.Token != "" || !authFile.Existed {
+        auth := map[string]string{}
+        if authFile.Existed {
+            _ = json.Unmarshal(authFile.Old, &auth)
+        }
+        if fields.Token != "" {
+            auth["OPENAI_API_KEY"] = fields.Token
+        }
+        authFile.New, _ = json.MarshalIndent(auth, "", "  ")
     }
-    jb, _ := json.MarshalIndent(auth, "", "  ")
-    if err := fsx.BackupFile(paths[1]); err != nil && !errors.Is(err, os.ErrNotExist) { return core.Backup{}, err }
-    if err := fsx.AtomicWrite(paths[1], jb, fs.FileMode(0o600)); err != nil { return core.Backup{}, err }
-    return core.Backup{}, nil
+    return []core.FileChange{tomlFile, authFile}, nil
 }
 
 func (c *codex) Validate(f core.Fields) error { return core.ValidateFields(f) }
+
+// codexProviderLines are the provider section keys agtok manages: base_url (unless
+// keepURL), the headers, with env: references sent from the environment
+// (env_http_headers), and the timeout.
+func codexProviderLines(fields core.Fields, keepURL bool) []string {
+    var out []string
+    if !keepURL { out = append(out, "base_url = \""+fields.URL+"\"") }
+    var plain, env []string
+    for _, h := range fields.Headers {
+        if name, ok := strings.CutPrefix(h.Value, "env:"); ok {
+            env = append(env, strconv.Quote(h.Name)+" = "+strconv.Quote(name))
+        } else {
+            plain = append(plain, strconv.Quote(h.Name)+" = "+strconv.Quote(h.Value))
+        }
+    }
+    if len(plain) > 0 { out = append(out, "http_headers = { "+strings.Join(plain, ", ")+" }") }
+    if len(env) > 0 { out = append(out, "env_http_headers = { "+strings.Join(env, ", ")+" }") }
+    if ms := fields.Network.TimeoutMillis(); ms > 0 { out = append(out, codexTimeoutKey+" = "+strconv.FormatInt(ms, 10)) }
+    return out
+}
+
+// codexManagedLine reports whether a provider section line is one codexProviderLines
+// rewrites (besides base_url, which is replaced in place). Unless mirroring, keys
+// the fields leave unset are kept as found.
+func codexManagedLine(line string, fields core.Fields, mirror bool) bool {
+    i := strings.IndexByte(line, '=')
+    if i < 0 { return false }
+    switch strings.TrimSpace(line[:i]) {
+    case "http_headers", "env_http_headers":
+        return mirror || len(fields.Headers) > 0
+    case codexTimeoutKey:
+        return mirror || fields.Network.Timeout != ""
+    }
+    return false
+}
+
+// codexTimeoutKey is the provider option closest to a request timeout: how long a
+// response stream may stay idle, in milliseconds.
+const codexTimeoutKey = "stream_idle_timeout_ms"
+
+// codexHeaders reads an inline table of headers, e.g. { "X-Team" = "a" }; for
+// env_http_headers the values name environment variables and come back as env: references.
+func codexHeaders(key, table string) core.Headers {
+    var h core.Headers
+    for _, kv := range parseInlineTable(table) {
+        v := kv[1]
+        if key == "env_http_headers" { v = "env:" + v }
+        h = append(h, core.Header{Name: kv[0], Value: v})
+    }
+    return h
+}
+
+// parseInlineTable reads the string pairs of a one-line TOML inline table; keys
+// may be bare or quoted. Anything it does not understand ends the table.
+func parseInlineTable(s string) [][2]string {
+    s = strings.TrimSpace(s)
+    if !strings.HasPrefix(s, "{") { return nil }
+    s = s[1:]
+    var out [][2]string
+    for {
+        s = strings.TrimLeft(s, " \t,")
+        if s == "" || s[0] == '}' { return out }
+        k, rest, ok := tomlToken(s)
+        if !ok { return out }
+        rest = strings.TrimSpace(rest)
+        if !strings.HasPrefix(rest, "=") { return out }
+        v, rest, ok := tomlToken(strings.TrimSpace(rest[1:]))
+        if !ok { return out }
+        out = append(out, [2]string{k, v})
+        s = rest
+    }
+}
+
+// tomlToken reads a basic ("..."), literal ('...') or bare string from the start of s.
+func tomlToken(s string) (tok, rest string, ok bool) {
+    switch {
+    case s == "":
+        return "", s, false
+    case s[0] == '"':
+        for i := 1; i < len(s); i++ {
+            if s[i] == '\\' { i++; continue }
+            if s[i] == '"' {
+                v, err := strconv.Unquote(s[:i+1])
+                return v, s[i+1:], err == nil
+            }
+        }
+        return "", s, false
+    case s[0] == '\'':
+        if i := strings.IndexByte(s[1:], '\''); i >= 0 { return s[1 : i+1], s[i+2:], true }
+        return "", s, false
+    }
+    i := strings.IndexAny(s, " \t=,}")
+    if i < 0 { i = len(s) }
+    return s[:i], s[i:], i > 0
+}
@@ -0,0 +1,227 @@
+package providers
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "runtime"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+// hit is a value found in one layer of a precedence chain.
+type hit struct {
+    value  string
+    source string
+}
+
+// layer maps a field name (url|token|model) to the value it defines, if any.
+type layer map[string]hit
+
+// fieldKeys maps a field name to the variable names that may carry it, in priority order.
+type fieldKeys map[string][]string
+
+var (
+    claudeKeys = fieldKeys{"url": {"ANTHROPIC_BASE_URL"}, "token": claudeTokenKeys, "model": {"ANTHROPIC_MODEL"}}
+    geminiKeys = fieldKeys{"url": {"GOOGLE_GEMINI_BASE_URL"}, "token": {"GEMINI_API_KEY"}, "model": {"GEMINI_MODEL"}}
+    codexKeys  = fieldKeys{"url": {"OPENAI_BASE_URL"}, "token": {"OPENAI_API_KEY"}}
+)
+
+// EnvVars returns f as the environment variables the agent reads ("KEY=value"),
+// for running it without touching its config files. Empty fields are left out.
+func EnvVars(id core.AgentID, f core.Fields) []string {
+    var keys fieldKeys
+    switch id.Base() {
+    case core.AgentClaude:
+        keys = claudeKeys
+    case core.AgentGemini:
+        keys = geminiKeys
+        if f.Auth == core.GeminiAuthVertex {
+            return append(geminiVertexEnv(f), networkEnv(id, f.Network)...)
+        }
+    case core.AgentCodex:
+        keys = codexKeys
+    }
+    var out []string
+    for _, kv := range []struct{ field, value string }{{"url", f.URL}, {"token", f.Token}, {"model", f.Model}} {
+        if ks := keys[kv.field]; len(ks) > 0 && kv.value != "" {
+            out = append(out, ks[0]+"="+kv.value)
+        }
+    }
+    if id.Base() == core.AgentClaude {
+        // the top-level model setting has no variable; ANTHROPIC_MODEL is the closest
+        tv := claudeTierVars(f.Tiers)
+        for _, k := range []string{claudeOpusKey, claudeSonnetKey, claudeHaikuKey, claudeSmallFastKey} {
+            if tv[k] != "" { out = append(out, k+"="+tv[k]) }
+        }
+    }
+    return append(out, networkEnv(id, f.Network)...)
+}
+
+// networkEnv is the network part of EnvVars. Codex has no timeout variable and
+// takes its CA bundle from SSL_CERT_FILE.
+func networkEnv(id core.AgentID, n core.Network) []string {
+    timeoutKey := ""
+    if id.Base() == core.AgentClaude { timeoutKey = claudeTimeoutKey }
+    vars := networkVars(n, timeoutKey)
+    if id.Base() == core.AgentCodex { vars["SSL_CERT_FILE"], vars[envCABundle] = n.CABundle, "" }
+    var out []string
+    for _, k := range []string{envProxy, envNoProxy, envCABundle, "SSL_CERT_FILE", claudeTimeoutKey} {
+        if v := vars[k]; v != "" { out = append(out, k+"="+v) }
+    }
+    return out
+}
+
+// geminiVertexEnv is EnvVars for Gemini in Vertex AI mode.
+func geminiVertexEnv(f core.Fields) []string {
+    out := []string{"GOOGLE_GENAI_USE_VERTEXAI=true"}
+    for _, kv := range []struct{ key, value string }{
+        {"GOOGLE_GEMINI_BASE_URL", f.URL}, {"GOOGLE_API_KEY", f.Token}, {"GEMINI_MODEL", f.Model},
+        {"GOOGLE_CLOUD_PROJECT", f.Project}, {"GOOGLE_CLOUD_LOCATION", f.Location},
+    } {
+        if kv.value != "" { out = append(out, kv.key+"="+kv.value) }
+    }
+    return out
+}
+
+// Effective walks the agent's precedence chain (highest first) and reports, for each
+// managed field, the value the agent will actually use and where it comes from.
+// cwd is the project directory the agent would be started in; empty skips project files.
+func Effective(id core.AgentID, cwd string) (core.Effective, error) {
+    prov := NewProvider(id)
+    if prov == nil {
+        return core.Effective{}, fmt.Errorf("provider not available for agent: %s", id)
+    }
+    file, err := prov.Read(context.Background())
+    if err != nil {
+        return core.Effective{}, err
+    }
+    var chain []layer
+    paths := prov.Paths()
+    switch {
+    case id.Base() == core.AgentClaude && len(paths) > 0:
+        chain = claudeChain(cwd, paths[0])
+    case id.Base() == core.AgentGemini && len(paths) > 0:
+        chain = geminiChain(cwd, paths[0])
+    case id.Base() == core.AgentCodex && len(paths) > 1:
+        chain = codexChain(paths)
+    }
+    return core.Effective{
+        Agent: id,
+        URL:   resolveField(chain, "url", file.URL),
+        Token: resolveField(chain, "token", file.Token),
+        Model: resolveField(chain, "model", file.Model),
+    }, nil
+}
+
+func resolveField(chain []layer, field, fileValue string) core.Resolved {
+    for _, l := range chain {
+        if h, ok := l[field]; ok {
+            return core.Resolved{Value: h.value, Source: h.source, FileValue: fileValue}
+        }
+    }
+    return core.Resolved{FileValue: fileValue}
+}
+
+// keyLayer builds a layer by looking up each field's keys in order; empty values count as unset.
+func keyLayer(keys fieldKeys, lookup func(string) (string, bool), source func(key string) string) layer {
+    l := layer{}
+    for field, ks := range keys {
+        for _, k := range ks {
+            if v, ok := lookup(k); ok && v != "" {
+                l[field] = hit{value: v, source: source(k)}
+                break
+            }
+        }
+    }
+    return l
+}
+
+func shellLayer(keys fieldKeys) layer {
+    return keyLayer(keys, os.LookupEnv, func(k string) string { return "env " + k })
+}
+
+func mapLayer(keys fieldKeys, m map[string]string, path string) layer {
+    lookup := func(k string) (string, bool) { v, ok := m[k]; return v, ok }
+    return keyLayer(keys, lookup, func(k string) string { return path + " (" + k + ")" })
+}
+
+// claudeManagedSettingsPath is the system-wide policy file that overrides every other layer.
+func claudeManagedSettingsPath() string {
+    switch runtime.GOOS {
+    case "darwin":
+        return "/Library/Application Support/ClaudeCode/managed-settings.json"
+    case "windows":
+        return `C:\ProgramData\ClaudeCode\managed-settings.json`
+    default:
+        return "/etc/claude-code/managed-settings.json"
+    }
+}
+
+// claudeChain: managed settings -> shell env -> project local -> project shared -> user settings.
+func claudeChain(cwd, userPath string) []layer {
+    settings := func(p string) layer {
+        env, err := readClaudeEnv(p)
+        if err != nil { return layer{} }
+        return mapLayer(claudeKeys, env, p)
+    }
+    var chain []layer
+    if !fsx.Rooted() {
+        // system policy and the host shell do not apply to another root
+        chain = append(chain, settings(claudeManagedSettingsPath()), shellLayer(claudeKeys))
+    }
+    if cwd != "" {
+        chain = append(chain,
+            settings(filepath.Join(cwd, ".claude", "settings.local.json")),
+            settings(filepath.Join(cwd, ".claude", "settings.json")))
+    }
+    return append(chain, settings(userPath))
+}
+
+// geminiChain: shell env -> the single .env file Gemini CLI loads.
+func geminiChain(cwd, userPath string) []layer {
+    var chain []layer
+    if !fsx.Rooted() { chain = append(chain, shellLayer(geminiKeys)) }
+    if p := geminiEnvFile(cwd, userPath); p != "" {
+        if env, err := readDotEnv(p); err == nil {
+            chain = append(chain, mapLayer(geminiKeys, env, p))
+        }
+    }
+    return chain
+}
+
+// geminiEnvFile mirrors Gemini CLI's lookup: walk up from cwd checking .gemini/.env then .env,
+// falling back to the user-level file and ~/.env. Only the first file found is loaded.
+func geminiEnvFile(cwd, userPath string) string {
+    var dirs []string
+    for dir := cwd; dir != ""; {
+        dirs = append(dirs, dir)
+        parent := filepath.Dir(dir)
+        if parent == dir { break }
+        dir = parent
+    }
+    var candidates []string
+    for _, dir := range dirs {
+        candidates = append(candidates, filepath.Join(dir, ".gemini", ".env"), filepath.Join(dir, ".env"))
+    }
+    candidates = append(candidates, userPath, joinHome(".env"))
+    for _, p := range candidates {
+        if st, err := fsx.Stat(p); err == nil && !st.IsDir() {
+            return p
+        }
+    }
+    return ""
+}
+
+// codexChain: shell env -> config.toml/auth.json under the provider's home (CODEX_HOME or ~/.codex).
+func codexChain(paths []string) []layer {
+    tomlPath, authPath := paths[0], paths[1]
+    f := readCodexFiles(tomlPath, authPath)
+    files := layer{}
+    if f.URL != "" { files["url"] = hit{value: f.URL, source: tomlPath + " (base_url)"} }
+    if f.Model != "" { files["model"] = hit{value: f.Model, source: tomlPath + " (model)"} }
+    if f.Token != "" { files["token"] = hit{value: f.Token, source: authPath + " (OPENAI_API_KEY)"} }
+    if fsx.Rooted() { return []layer{files} }
+    return []layer{shellLayer(codexKeys), files}
+}
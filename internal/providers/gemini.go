@@ -4,7 +4,7 @@ import (
     "bufio"
     "context"
     "errors"
-    "io/fs"
+    "fmt"
     "os"
     "path/filepath"
     "strings"
@@ -12,54 +12,107 @@ import (
     "tks/internal/fsx"
 )
 
-type gemini struct{}
+type gemini struct {
+    id   core.AgentID
+    home string // config directory, e.g. ~/.gemini
+}
+
+func (g *gemini) ID() core.AgentID { return g.id }
 
-func (g *gemini) ID() core.AgentID { return core.AgentGemini }
+// Paths: .env carries the keys; settings.json records which auth mode Gemini CLI uses.
+func (g *gemini) Paths() []string {
+    return []string{filepath.Join(g.home, ".env"), filepath.Join(g.home, "settings.json")}
+}
 
-func (g *gemini) Paths() []string { return []string{joinHome(".gemini", ".env")} }
+// Gemini .env keys per auth mode; applying one mode removes the other's.
+var (
+    geminiAPIKeyKeys = []string{"GEMINI_API_KEY"}
+    geminiVertexKeys = []string{"GOOGLE_GENAI_USE_VERTEXAI", "GOOGLE_API_KEY", "GOOGLE_CLOUD_PROJECT", "GOOGLE_CLOUD_LOCATION"}
+)
 
 func (g *gemini) Read(ctx context.Context) (core.Fields, error) {
-    p := g.Paths()[0]
-    f, err := os.Open(p)
+    env, err := readDotEnv(g.Paths()[0])
     if err != nil {
         if errors.Is(err, os.ErrNotExist) { return core.Fields{}, nil }
         return core.Fields{}, err
     }
+    f := core.Fields{URL: env["GOOGLE_GEMINI_BASE_URL"], Token: env["GEMINI_API_KEY"], Model: env["GEMINI_MODEL"], Auth: core.GeminiAuthAPIKey}
+    f.Network = readNetworkVars(env, "") // Gemini CLI has no timeout setting
+    if geminiUsesVertex(env) {
+        f.Auth, f.Token = core.GeminiAuthVertex, env["GOOGLE_API_KEY"]
+        f.Project, f.Location = env["GOOGLE_CLOUD_PROJECT"], env["GOOGLE_CLOUD_LOCATION"]
+    }
+    return f, nil
+}
+
+func geminiUsesVertex(env map[string]string) bool {
+    v := strings.ToLower(env["GOOGLE_GENAI_USE_VERTEXAI"])
+    return v == "true" || v == "1"
+}
+
+// readDotEnv parses KEY=VALUE lines of a .env file; comments and blank lines are skipped.
+func readDotEnv(p string) (map[string]string, error) {
+    f, err := fsx.Open(p)
+    if err != nil { return nil, err }
     defer f.Close()
-    var url, token, model string
+    env := map[string]string{}
     s := bufio.NewScanner(f)
     for s.Scan() {
         line := strings.TrimSpace(s.Text())
         if line == "" || strings.HasPrefix(line, "#") { continue }
         if i := strings.IndexByte(line, '='); i >= 0 {
-            k := strings.TrimSpace(line[:i])
-            v := strings.TrimSpace(line[i+1:])
-            if k == "GOOGLE_GEMINI_BASE_URL" { url = v }
-            if k == "GEMINI_API_KEY" { token = v }
-            if k == "GEMINI_MODEL" { model = v }
+            env[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
         }
     }
-    return core.Fields{URL: url, Token: token, Model: model}, nil
+    return env, s.Err()
 }
 
 func (g *gemini) Write(ctx context.Context, fields core.Fields) (core.Backup, error) {
-    p := g.Paths()[0]
-    _ = os.MkdirAll(filepath.Dir(p), 0o700)
+    changes, err := g.Plan(ctx, fields)
+    if err != nil { return core.Backup{}, err }
+    return commit(changes)
+}
+
+func (g *gemini) Plan(ctx context.Context, fields core.Fields) ([]core.FileChange, error) {
+    fc, err := currentFile(g.Paths()[0])
+    if err != nil { return nil, err }
     content := make(map[string]string)
-    // read existing
-    if b, err := os.ReadFile(p); err == nil {
-        for _, line := range strings.Split(string(b), "\n") {
-            line = strings.TrimSpace(line)
-            if line == "" || strings.HasPrefix(line, "#") { continue }
-            if i := strings.IndexByte(line, '='); i >= 0 {
-                k := strings.TrimSpace(line[:i])
-                v := strings.TrimSpace(line[i+1:])
-                content[k] = v
-            }
+    var order []string // extra keys keep their original order so rewrites stay diffable
+    for _, line := range strings.Split(string(fc.Old), "\n") {
+        line = strings.TrimSpace(line)
+        if line == "" || strings.HasPrefix(line, "#") { continue }
+        if i := strings.IndexByte(line, '='); i >= 0 {
+            k := strings.TrimSpace(line[:i])
+            v := strings.TrimSpace(line[i+1:])
+            if _, seen := content[k]; !seen { order = append(order, k) }
+            content[k] = v
+        }
+    }
+    // an empty URL selects the official endpoint: drop the override
+    switch {
+    case KeepingURL(ctx):
+    case fields.URL != "":
+        content["GOOGLE_GEMINI_BASE_URL"] = fields.URL
+    default:
+        delete(content, "GOOGLE_GEMINI_BASE_URL")
+    }
+    // auth mode: write this mode's keys, drop the other mode's; no mode keeps the current one
+    mode := fields.Auth
+    if mode == "" && geminiUsesVertex(content) { mode = core.GeminiAuthVertex }
+    mode = core.GeminiAuth(mode)
+    if mode == core.GeminiAuthVertex {
+        for _, k := range geminiAPIKeyKeys { delete(content, k) }
+        content["GOOGLE_GENAI_USE_VERTEXAI"] = "true"
+        if fields.Token != "" { content["GOOGLE_API_KEY"] = fields.Token }
+        if fields.Auth != "" {
+            setOrDelete(content, "GOOGLE_CLOUD_PROJECT", fields.Project)
+            setOrDelete(content, "GOOGLE_CLOUD_LOCATION", fields.Location)
         }
+    } else {
+        for _, k := range geminiVertexKeys { delete(content, k) }
+        if fields.Token != "" { content["GEMINI_API_KEY"] = fields.Token }
     }
-    if fields.URL != "" { content["GOOGLE_GEMINI_BASE_URL"] = fields.URL }
-    if fields.Token != "" { content["GEMINI_API_KEY"] = fields.Token }
+    setNetworkVars(content, fields.Network, "", Mirroring(ctx))
     // model write/clear per context
     if v, ok := ctx.Value(CtxKeyGeminiClearModel).(bool); ok && v {
         delete(content, "GEMINI_MODEL")
@@ -68,20 +121,51 @@ func (g *gemini) Write(ctx context.Context, fields core.Fields) (core.Backup, er
     }
     // rebuild .env (MVP: no comments preserved)
     var b strings.Builder
-    keys := []string{"GOOGLE_GEMINI_BASE_URL", "GEMINI_API_KEY", "GEMINI_MODEL"}
-    for k, v := range content {
+    keys := append([]string{"GOOGLE_GEMINI_BASE_URL", "GEMINI_API_KEY", "GEMINI_MODEL"}, geminiVertexKeys...)
+    keys = append(keys, envProxy, envNoProxy, envCABundle)
+    managed := map[string]bool{}
+    for _, k := range keys { managed[k] = true }
+    for _, k := range order {
         // include any extra keys as well
-        if k != keys[0] && k != keys[1] && k != keys[2] {
-            b.WriteString(k + "=" + v + "\n")
+        if !managed[k] {
+            b.WriteString(k + "=" + content[k] + "\n")
         }
     }
     // write our keys last in fixed order
-    if v, ok := content[keys[0]]; ok { b.WriteString(keys[0] + "=" + v + "\n") }
-    if v, ok := content[keys[1]]; ok { b.WriteString(keys[1] + "=" + v + "\n") }
-    if v, ok := content[keys[2]]; ok { b.WriteString(keys[2] + "=" + v + "\n") }
-    if err := fsx.BackupFile(p); err != nil && !errors.Is(err, os.ErrNotExist) { return core.Backup{}, err }
-    if err := fsx.AtomicWrite(p, []byte(b.String()), fs.FileMode(0o600)); err != nil { return core.Backup{}, err }
-    return core.Backup{}, nil
+    for _, k := range keys {
+        if v, ok := content[k]; ok { b.WriteString(k + "=" + v + "\n") }
+    }
+    fc.New = []byte(b.String())
+    // settings.json may hold a mode chosen in Gemini CLI itself (oauth, ...):
+    // only a preset that names its mode, or one applied exactly, overrides it
+    if fields.Auth == "" && !Mirroring(ctx) { return []core.FileChange{fc}, nil }
+    settings, err := g.planSettings(mode)
+    if err != nil { return nil, err }
+    return []core.FileChange{fc, settings}, nil
+}
+
+// planSettings records the auth mode in settings.json, in the nested
+// security.auth.selectedType when the file already uses that layout and in the
+// top-level selectedAuthType otherwise. Other settings are kept in place.
+func (g *gemini) planSettings(mode string) (core.FileChange, error) {
+    fc, err := currentFile(g.Paths()[1])
+    if err != nil { return fc, err }
+    o, err := parseJSONObject(fc.Old)
+    if err != nil { return fc, fmt.Errorf("%s: %w", fc.Path, err) }
+    nested, err := setNestedJSON(&o, []string{"security", "auth", "selectedType"}, mode)
+    if err != nil { return fc, fmt.Errorf("%s: %w", fc.Path, err) }
+    if !nested {
+        if err := o.set("selectedAuthType", mode); err != nil { return fc, err }
+    } else if _, ok := o.vals["selectedAuthType"]; ok {
+        if err := o.set("selectedAuthType", mode); err != nil { return fc, err }
+    }
+    fc.New, err = o.marshal()
+    return fc, err
+}
+
+// setOrDelete sets m[k] to v, or removes k when v is empty.
+func setOrDelete(m map[string]string, k, v string) {
+    if v != "" { m[k] = v } else { delete(m, k) }
 }
 
 func (g *gemini) Validate(f core.Fields) error { return core.ValidateFields(f) }
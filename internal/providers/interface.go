@@ -2,7 +2,14 @@ package providers
 
 import (
     "context"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "time"
     core "tks/internal/core"
+    "tks/internal/fsx"
 )
 
 type Provider interface {
@@ -13,6 +20,21 @@ type Provider interface {
     Validate(fields core.Fields) error
 }
 
+// Planner is implemented by providers that can compute the files a Write would
+// produce without touching disk. Write must commit exactly what Plan returns.
+type Planner interface {
+    Plan(ctx context.Context, fields core.Fields) ([]core.FileChange, error)
+}
+
+// Plan previews a write through prov; ctx carries the same controls as Write.
+func Plan(ctx context.Context, prov Provider, fields core.Fields) ([]core.FileChange, error) {
+    pl, ok := prov.(Planner)
+    if !ok {
+        return nil, fmt.Errorf("provider %s cannot preview writes", prov.ID())
+    }
+    return pl.Plan(ctx, fields)
+}
+
 // Context keys for provider-specific controls
 type ctxKey string
 
@@ -23,3 +45,90 @@ var CtxKeyClaudeClearModel ctxKey = "claude_clear_model"
 // Additional provider-specific context keys for model clearing
 var CtxKeyGeminiClearModel ctxKey = "gemini_clear_model"
 var CtxKeyCodexClearModel ctxKey = "codex_clear_model"
+
+// WithClearModel marks ctx so that Write removes the model key for the agent's kind.
+func WithClearModel(ctx context.Context, id core.AgentID) context.Context {
+    switch id.Base() {
+    case core.AgentClaude:
+        return context.WithValue(ctx, CtxKeyClaudeClearModel, true)
+    case core.AgentGemini:
+        return context.WithValue(ctx, CtxKeyGeminiClearModel, true)
+    case core.AgentCodex:
+        return context.WithValue(ctx, CtxKeyCodexClearModel, true)
+    }
+    return ctx
+}
+
+// CtxKeyMirror: when ctx has this key set to true, Write mirrors the settings a
+// preset carries (headers, network, model tiers) exactly, removing those fields
+// leave unset.
+// Otherwise only the set ones are written and keys edited by hand are kept.
+var CtxKeyMirror ctxKey = "mirror"
+
+// WithMirror marks ctx for a preset apply (see CtxKeyMirror).
+func WithMirror(ctx context.Context) context.Context {
+    return context.WithValue(ctx, CtxKeyMirror, true)
+}
+
+// Mirroring reports whether ctx was marked by WithMirror.
+func Mirroring(ctx context.Context) bool {
+    v, ok := ctx.Value(CtxKeyMirror).(bool)
+    return ok && v
+}
+
+// CtxKeyKeepURL: when ctx has this key set to true, Write leaves the base URL
+// on disk as it is and ignores fields.URL.
+var CtxKeyKeepURL ctxKey = "keep_url"
+
+// WithKeepURL marks ctx for a write that does not touch the base URL.
+func WithKeepURL(ctx context.Context) context.Context {
+    return context.WithValue(ctx, CtxKeyKeepURL, true)
+}
+
+// KeepingURL reports whether ctx was marked by WithKeepURL.
+func KeepingURL(ctx context.Context) bool {
+    v, ok := ctx.Value(CtxKeyKeepURL).(bool)
+    return ok && v
+}
+
+func newBackup() core.Backup {
+    return core.Backup{Files: map[string]string{}, Written: map[string]string{}, Time: time.Now()}
+}
+
+// backupInto snapshots p before it is rewritten and records the copy in bk.
+func backupInto(bk *core.Backup, p string) error {
+    bak, err := fsx.BackupFile(p)
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    bk.Files[p] = bak
+    return nil
+}
+
+// currentFile returns the content of p; existed is false when it does not exist yet.
+func currentFile(p string) (core.FileChange, error) {
+    b, err := fsx.ReadFile(p)
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            return core.FileChange{Path: p}, nil
+        }
+        return core.FileChange{}, err
+    }
+    return core.FileChange{Path: p, Old: b, Existed: true}, nil
+}
+
+// commit backs up and atomically writes every planned file, in order.
+func commit(changes []core.FileChange) (core.Backup, error) {
+    bk := newBackup()
+    for _, c := range changes {
+        _ = fsx.MkdirAll(filepath.Dir(c.Path), 0o700)
+        if err := backupInto(&bk, c.Path); err != nil {
+            return core.Backup{}, err
+        }
+        if err := fsx.AtomicWrite(c.Path, c.New, fs.FileMode(0o600)); err != nil {
+            return core.Backup{}, err
+        }
+        bk.Written[c.Path] = fsx.Sum(c.New)
+    }
+    return bk, nil
+}
@@ -0,0 +1,96 @@
+package providers
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+)
+
+// jsonObject is a JSON object that keeps its keys in file order, so rewriting a
+// few keys leaves the rest of a hand-edited settings file as it was.
+type jsonObject struct {
+    keys []string
+    vals map[string]json.RawMessage
+}
+
+// parseJSONObject reads a top-level object; empty input is an empty object.
+func parseJSONObject(b []byte) (jsonObject, error) {
+    o := jsonObject{vals: map[string]json.RawMessage{}}
+    if len(bytes.TrimSpace(b)) == 0 {
+        return o, nil
+    }
+    dec := json.NewDecoder(bytes.NewReader(b))
+    if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+        return o, errors.New("not a JSON object")
+    }
+    for dec.More() {
+        t, err := dec.Token()
+        if err != nil { return o, err }
+        k, _ := t.(string)
+        var raw json.RawMessage
+        if err := dec.Decode(&raw); err != nil { return o, err }
+        if _, seen := o.vals[k]; !seen { o.keys = append(o.keys, k) }
+        o.vals[k] = raw
+    }
+    return o, nil
+}
+
+// get decodes key k into v; it reports false when k is absent.
+func (o jsonObject) get(k string, v any) (bool, error) {
+    raw, ok := o.vals[k]
+    if !ok { return false, nil }
+    return true, json.Unmarshal(raw, v)
+}
+
+// set stores v under k, appending k when it is new.
+func (o *jsonObject) set(k string, v any) error {
+    raw, err := json.Marshal(v)
+    if err != nil { return err }
+    if _, ok := o.vals[k]; !ok { o.keys = append(o.keys, k) }
+    o.vals[k] = raw
+    return nil
+}
+
+func (o *jsonObject) del(k string) {
+    if _, ok := o.vals[k]; !ok { return }
+    delete(o.vals, k)
+    for i, x := range o.keys {
+        if x == k { o.keys = append(o.keys[:i], o.keys[i+1:]...); break }
+    }
+}
+
+// marshal renders the object with two-space indentation.
+func (o jsonObject) marshal() ([]byte, error) {
+    var b bytes.Buffer
+    b.WriteByte('{')
+    for i, k := range o.keys {
+        if i > 0 { b.WriteByte(',') }
+        kb, _ := json.Marshal(k)
+        b.Write(kb)
+        b.WriteByte(':')
+        b.Write(o.vals[k])
+    }
+    b.WriteByte('}')
+    var out bytes.Buffer
+    if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil { return nil, err }
+    return out.Bytes(), nil
+}
+
+// setNestedJSON sets path (object keys, the last naming the value) to v when every
+// enclosing object already exists; it reports false and changes nothing otherwise.
+func setNestedJSON(o *jsonObject, path []string, v any) (bool, error) {
+    if len(path) == 1 {
+        return true, o.set(path[0], v)
+    }
+    raw, ok := o.vals[path[0]]
+    if !ok { return false, nil }
+    child, err := parseJSONObject(raw)
+    if err != nil { return false, nil } // not an object: leave it alone
+    if ok, err := setNestedJSON(&child, path[1:], v); !ok || err != nil { return ok, err }
+    b, err := child.marshal()
+    if err != nil { return false, err }
+    var c bytes.Buffer
+    if err := json.Compact(&c, b); err != nil { return false, err }
+    o.vals[path[0]] = c.Bytes()
+    return true, nil
+}
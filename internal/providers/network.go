@@ -0,0 +1,41 @@
+package providers
+
+import (
+    "strconv"
+
+    core "tks/internal/core"
+)
+
+// Environment variables Node-based agents (Claude Code, Gemini CLI) read for
+// network settings. The timeout variable differs per agent; "" means none.
+const (
+    envProxy    = "HTTPS_PROXY"
+    envNoProxy  = "NO_PROXY"
+    envCABundle = "NODE_EXTRA_CA_CERTS"
+)
+
+// networkVars maps n to its variables; unset settings map to "" so callers can
+// remove them (see setOrDelete).
+func networkVars(n core.Network, timeoutKey string) map[string]string {
+    m := map[string]string{envProxy: n.Proxy, envNoProxy: n.NoProxy, envCABundle: n.CABundle}
+    if timeoutKey != "" {
+        m[timeoutKey] = ""
+        if ms := n.TimeoutMillis(); ms > 0 { m[timeoutKey] = strconv.FormatInt(ms, 10) }
+    }
+    return m
+}
+
+// readNetworkVars is the inverse of networkVars.
+func readNetworkVars(env map[string]string, timeoutKey string) core.Network {
+    n := core.Network{Proxy: env[envProxy], NoProxy: env[envNoProxy], CABundle: env[envCABundle]}
+    if timeoutKey != "" { n.Timeout = core.TimeoutFromMillis(env[timeoutKey]) }
+    return n
+}
+
+// setNetworkVars writes n into env. When mirroring, settings n leaves unset are
+// removed; otherwise they are kept as found.
+func setNetworkVars(env map[string]string, n core.Network, timeoutKey string, mirror bool) {
+    for k, v := range networkVars(n, timeoutKey) {
+        if v != "" || mirror { setOrDelete(env, k, v) }
+    }
+}
@@ -3,15 +3,12 @@ package providers
 import (
     "os"
     "path/filepath"
-    "runtime"
+    "strings"
+    "tks/internal/fsx"
 )
 
 func userHome() string {
-    h, _ := os.UserHomeDir()
-    if h == "" && runtime.GOOS == "windows" {
-        h = os.Getenv("USERPROFILE")
-    }
-    return h
+    return fsx.Home()
 }
 
 func joinHome(parts ...string) string {
@@ -19,3 +16,41 @@ func joinHome(parts ...string) string {
     return filepath.Join(elems...)
 }
 
+// expandHome resolves a leading "~" so configured homes may be written portably.
+func expandHome(p string) string {
+    if p == "~" {
+        return userHome()
+    }
+    if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+        return joinHome(p[2:])
+    }
+    return p
+}
+
+// agentEnv reads an agent's own override variable; host variables are ignored under --root.
+func agentEnv(key string) string {
+    if fsx.Rooted() {
+        return ""
+    }
+    return os.Getenv(key)
+}
+
+// claudeHome honors CLAUDE_CONFIG_DIR the same way Claude Code does.
+func claudeHome() string {
+    if d := agentEnv("CLAUDE_CONFIG_DIR"); d != "" {
+        return expandHome(d)
+    }
+    return joinHome(".claude")
+}
+
+// codexHome honors CODEX_HOME the same way Codex CLI does.
+func codexHome() string {
+    if d := agentEnv("CODEX_HOME"); d != "" {
+        return expandHome(d)
+    }
+    return joinHome(".codex")
+}
+
+func geminiHome() string {
+    return joinHome(".gemini")
+}
@@ -2,19 +2,56 @@ package providers
 
 import (
     core "tks/internal/core"
+    "tks/internal/store"
 )
 
-// NewProvider returns a concrete provider for an agent.
+// factory builds providers; SetFactory swaps it so callers can be driven with fakes.
+var factory = defaultProvider
+
+// SetFactory replaces the provider constructor used by NewProvider and returns a
+// function that restores the previous one.
+func SetFactory(f func(core.AgentID) Provider) (restore func()) {
+    prev := factory
+    factory = f
+    return func() { factory = prev }
+}
+
+// NewProvider returns a concrete provider for an agent or a named instance
+// ("codex@work"). Instances use the home directory they were registered with.
 func NewProvider(id core.AgentID) Provider {
-    switch id {
+    return factory(id)
+}
+
+func defaultProvider(id core.AgentID) Provider {
+    home := ""
+    if id.Instance() != "" {
+        in, err := store.GetInstance(id)
+        if err != nil {
+            return nil
+        }
+        home = expandHome(in.Home)
+    }
+    switch id.Base() {
     case core.AgentClaude:
-        return &claude{}
+        if home == "" { home = claudeHome() }
+        return &claude{id: id, home: home}
     case core.AgentGemini:
-        return &gemini{}
+        if home == "" { home = geminiHome() }
+        return &gemini{id: id, home: home}
     case core.AgentCodex:
-        return &codex{}
+        if home == "" { home = codexHome() }
+        return &codex{id: id, home: home}
     default:
         return nil
     }
 }
 
+// Agents returns the built-in agents followed by all registered instances.
+func Agents() []core.AgentID {
+    ids := append([]core.AgentID{}, core.BaseAgents...)
+    list, _ := store.LoadInstances()
+    for _, in := range list {
+        ids = append(ids, in.ID())
+    }
+    return ids
+}
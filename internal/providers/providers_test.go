@@ -0,0 +1,157 @@
+package providers
+
+import (
+    "context"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+)
+
+func TestClaudeUnderRoot(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir()) // host overrides do not apply under --root
+    prov := NewProvider(core.AgentClaude)
+    path := filepath.Join(home, ".claude", "settings.json")
+    if got := prov.Paths()[0]; got != path { t.Fatalf("path = %s, want %s", got, path) }
+    fsxtest.WriteFile(t, path, `{"theme": "dark", "env": {"FOO": "bar"}}`)
+
+    want := core.Fields{URL: "https://api.example.com", Token: "sk-one", Model: "m1"}
+    if _, err := prov.Write(context.Background(), want); err != nil { t.Fatal(err) }
+    got, err := prov.Read(context.Background())
+    if err != nil { t.Fatal(err) }
+    if got.URL != want.URL || got.Token != want.Token || got.Model != want.Model {
+        t.Fatalf("read back %+v, want %+v", got, want)
+    }
+    var raw struct {
+        Theme string            `json:"theme"`
+        Env   map[string]string `json:"env"`
+    }
+    if err := json.Unmarshal([]byte(fsxtest.ReadFile(t, path)), &raw); err != nil { t.Fatal(err) }
+    if raw.Theme != "dark" || raw.Env["FOO"] != "bar" { t.Fatalf("unmanaged keys lost: %+v", raw) }
+}
+
+func TestCodexUnderRoot(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("CODEX_HOME", t.TempDir())
+    prov := NewProvider(core.AgentCodex)
+    dir := filepath.Join(home, ".codex")
+    fsxtest.WriteFile(t, filepath.Join(dir, "config.toml"), "model = \"old\"\n\n[model_providers.codex]\nname = \"codex\"\nbase_url = \"https://old.example.com\"\n")
+
+    want := core.Fields{URL: "https://api.example.com/v1", Token: "sk-two", Model: "m2"}
+    if _, err := prov.Write(context.Background(), want); err != nil { t.Fatal(err) }
+    got, err := prov.Read(context.Background())
+    if err != nil { t.Fatal(err) }
+    if got.URL != want.URL || got.Token != want.Token || got.Model != want.Model {
+        t.Fatalf("read back %+v, want %+v", got, want)
+    }
+    if toml := fsxtest.ReadFile(t, filepath.Join(dir, "config.toml")); !strings.Contains(toml, `name = "codex"`) {
+        t.Fatalf("provider name lost:\n%s", toml)
+    }
+    if auth := fsxtest.ReadFile(t, filepath.Join(dir, "auth.json")); !strings.Contains(auth, "sk-two") {
+        t.Fatalf("auth.json = %s", auth)
+    }
+}
+
+func TestClaudeKeepsHandSetKeysUnlessMirroring(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+    prov := NewProvider(core.AgentClaude)
+    path := filepath.Join(home, ".claude", "settings.json")
+    fsxtest.WriteFile(t, path, `{"env": {"HTTPS_PROXY": "http://proxy:3128", "NODE_EXTRA_CA_CERTS": "/ca.pem", "API_TIMEOUT_MS": "90000", "ANTHROPIC_CUSTOM_HEADERS": "X-Team: infra"}}`)
+    ctx := context.Background()
+
+    // ad-hoc apply (apply --url): hand-set keys survive
+    if _, err := prov.Write(ctx, core.Fields{URL: "https://api.example.com", Token: "sk-1"}); err != nil { t.Fatal(err) }
+    got, err := prov.Read(ctx)
+    if err != nil { t.Fatal(err) }
+    want := core.Network{Proxy: "http://proxy:3128", CABundle: "/ca.pem", Timeout: "1m30s"}
+    if got.Network != want || got.Headers.String() != "X-Team: infra" {
+        t.Fatalf("ad-hoc apply changed hand-set keys: network %+v, headers %q", got.Network, got.Headers)
+    }
+
+    // a set value still replaces its key
+    if _, err := prov.Write(ctx, core.Fields{URL: "https://api.example.com", Network: core.Network{Proxy: "http://other:8080"}}); err != nil { t.Fatal(err) }
+    if got, _ = prov.Read(ctx); got.Network.Proxy != "http://other:8080" || got.Network.CABundle != "/ca.pem" {
+        t.Fatalf("network = %+v", got.Network)
+    }
+
+    // preset apply: the preset is mirrored exactly
+    if _, err := prov.Write(WithMirror(ctx), core.Fields{URL: "https://api.example.com"}); err != nil { t.Fatal(err) }
+    if got, _ = prov.Read(ctx); !got.Network.IsZero() || len(got.Headers) != 0 {
+        t.Fatalf("mirroring kept keys the preset does not set: network %+v, headers %q", got.Network, got.Headers)
+    }
+}
+
+func TestCodexKeepsHandSetKeysUnlessMirroring(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("CODEX_HOME", t.TempDir())
+    prov := NewProvider(core.AgentCodex)
+    path := filepath.Join(home, ".codex", "config.toml")
+    fsxtest.WriteFile(t, path, "model_provider = \"gw\"\n\n[model_providers.gw]\nbase_url = \"https://old.example.com\"\nhttp_headers = { \"X-Team\" = \"infra\" }\nstream_idle_timeout_ms = 600000\n")
+    ctx := context.Background()
+
+    if _, err := prov.Write(ctx, core.Fields{URL: "https://api.example.com/v1", Token: "sk-1"}); err != nil { t.Fatal(err) }
+    toml := fsxtest.ReadFile(t, path)
+    for _, k := range []string{`http_headers = { "X-Team" = "infra" }`, "stream_idle_timeout_ms = 600000", `base_url = "https://api.example.com/v1"`} {
+        if !strings.Contains(toml, k) { t.Fatalf("ad-hoc apply lost %s:\n%s", k, toml) }
+    }
+
+    if _, err := prov.Write(WithMirror(ctx), core.Fields{URL: "https://api.example.com/v1"}); err != nil { t.Fatal(err) }
+    toml = fsxtest.ReadFile(t, path)
+    if strings.Contains(toml, "http_headers") || strings.Contains(toml, "stream_idle_timeout_ms") {
+        t.Fatalf("mirroring kept keys the preset does not set:\n%s", toml)
+    }
+}
+
+func TestClaudeTiersMirroredOnlyForPresets(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("CLAUDE_CONFIG_DIR", t.TempDir())
+    prov := NewProvider(core.AgentClaude)
+    path := filepath.Join(home, ".claude", "settings.json")
+    fsxtest.WriteFile(t, path, `{"model": "opus", "env": {"ANTHROPIC_DEFAULT_SONNET_MODEL": "gw-medium", "ANTHROPIC_SMALL_FAST_MODEL": "gw-small"}}`)
+    ctx := context.Background()
+    hand := core.ModelTiers{Main: "opus", Sonnet: "gw-medium", Haiku: "gw-small"}
+
+    if _, err := prov.Write(ctx, core.Fields{URL: "https://api.example.com", Token: "sk-1"}); err != nil { t.Fatal(err) }
+    if got, _ := prov.Read(ctx); got.Tiers != hand { t.Fatalf("ad-hoc apply changed tiers: %+v", got.Tiers) }
+
+    // a preset with tiers replaces exactly the mapping
+    preset := core.ModelTiers{Opus: "gw-large"}
+    if _, err := prov.Write(WithMirror(ctx), core.Fields{URL: "https://api.example.com", Tiers: preset}); err != nil { t.Fatal(err) }
+    if got, _ := prov.Read(ctx); got.Tiers != preset { t.Fatalf("preset apply: tiers %+v, want %+v", got.Tiers, preset) }
+
+    // a preset without tiers removes them
+    if _, err := prov.Write(WithMirror(ctx), core.Fields{URL: "https://api.example.com"}); err != nil { t.Fatal(err) }
+    if got, _ := prov.Read(ctx); !got.Tiers.IsZero() { t.Fatalf("tiers left: %+v", got.Tiers) }
+}
+
+func TestGeminiSettingsOnlyForExplicitAuth(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    prov := NewProvider(core.AgentGemini)
+    dir := filepath.Join(home, ".gemini")
+    settings := filepath.Join(dir, "settings.json")
+    fsxtest.WriteFile(t, filepath.Join(dir, ".env"), "GEMINI_API_KEY=g-old\n")
+    fsxtest.WriteFile(t, settings, `{"selectedAuthType": "oauth-personal", "theme": "dark"}`)
+    ctx := context.Background()
+
+    // set --model and apply --url leave the mode picked in Gemini CLI alone
+    if _, err := prov.Write(WithKeepURL(ctx), core.Fields{Model: "gemini-x"}); err != nil { t.Fatal(err) }
+    if _, err := prov.Write(ctx, core.Fields{URL: "https://gw.example.com", Token: "g-new"}); err != nil { t.Fatal(err) }
+    if got := fsxtest.ReadFile(t, settings); got != `{"selectedAuthType": "oauth-personal", "theme": "dark"}` { t.Fatalf("settings.json = %s", got) }
+
+    // a preset that names its mode records it
+    if _, err := prov.Write(ctx, core.Fields{Token: "g-new", Auth: core.GeminiAuthAPIKey}); err != nil { t.Fatal(err) }
+    if got := fsxtest.ReadFile(t, settings); !strings.Contains(got, `"gemini-api-key"`) || !strings.Contains(got, `"dark"`) { t.Fatalf("settings.json = %s", got) }
+}
+
+func TestGeminiDoesNotCreateSettings(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    prov := NewProvider(core.AgentGemini)
+    if _, err := prov.Write(context.Background(), core.Fields{Token: "g-1"}); err != nil { t.Fatal(err) }
+    if _, err := os.Stat(filepath.Join(home, ".gemini", "settings.json")); !os.IsNotExist(err) { t.Fatalf("settings.json created: %v", err) }
+}
@@ -0,0 +1,92 @@
+package secret
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "time"
+
+    "tks/internal/fsx"
+    "tks/internal/util"
+)
+
+// Timeout bounds a cmd: reference; password managers may prompt, so it is generous.
+var Timeout = 30 * time.Second
+
+// Resolve returns the secret a token value stands for. Plain tokens are returned
+// as is; references (see util.IsSecretRef) are looked up on every call and the
+// result is never written anywhere by this package:
+//
+//   env:VAR            the environment variable VAR
+//   file:/path         the file's content (~/ expands to the home directory)
+//   cmd:pass show x    the first line printed by the command, run through the shell
+//
+// There is no trust boundary here: a cmd: reference runs with the user's
+// privileges and a file: reference reads any file they can. Only the user's own
+// presets should carry them; importers refuse them unless the user allows it
+// (see core.Preset.LocalRefs).
+func Resolve(ctx context.Context, s string) (string, error) {
+    if !util.IsSecretRef(s) {
+        return s, nil
+    }
+    kind, arg, _ := strings.Cut(s, ":")
+    arg = strings.TrimSpace(arg)
+    var v string
+    switch kind {
+    case "env":
+        v = os.Getenv(arg)
+        if v == "" {
+            return "", fmt.Errorf("%s: variable not set", s)
+        }
+    case "file":
+        if rest, ok := strings.CutPrefix(arg, "~/"); ok {
+            arg = filepath.Join(fsx.Home(), rest)
+        }
+        b, err := fsx.ReadFile(arg)
+        if err != nil {
+            return "", fmt.Errorf("%s: %w", s, err)
+        }
+        v = strings.TrimSpace(string(b))
+    case "cmd":
+        out, err := run(ctx, arg)
+        if err != nil {
+            return "", fmt.Errorf("%s: %w", s, err)
+        }
+        v, _, _ = strings.Cut(strings.TrimSpace(out), "\n")
+        v = strings.TrimSpace(v)
+    }
+    if v == "" {
+        return "", fmt.Errorf("%s: resolved to an empty token", s)
+    }
+    return v, nil
+}
+
+func run(ctx context.Context, command string) (string, error) {
+    ctx, cancel := context.WithTimeout(ctx, Timeout)
+    defer cancel()
+    var c *exec.Cmd
+    if runtime.GOOS == "windows" {
+        c = exec.CommandContext(ctx, "cmd", "/C", command)
+    } else {
+        c = exec.CommandContext(ctx, "sh", "-c", command)
+    }
+    var stdout, stderr bytes.Buffer
+    c.Stdout, c.Stderr = &stdout, &stderr
+    c.Stdin = os.Stdin // pass/op may ask for a passphrase
+    if err := c.Run(); err != nil {
+        if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+            return "", fmt.Errorf("timed out after %s", Timeout)
+        }
+        if msg := strings.TrimSpace(stderr.String()); msg != "" {
+            return "", fmt.Errorf("%w: %s", err, msg)
+        }
+        return "", err
+    }
+    return stdout.String(), nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "time"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+type gatewayFile struct {
+    Version  int            `json:"version"`
+    Gateways []core.Gateway `json:"gateways"`
+}
+
+func gatewaysPath() string {
+    return filepath.Join(BaseDir(), "gateways.json")
+}
+
+// LoadGateways returns all gateway presets.
+func LoadGateways() ([]core.Gateway, error) {
+    b, err := fsx.ReadFile(gatewaysPath())
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return nil, nil }
+        return nil, err
+    }
+    var f gatewayFile
+    if err := json.Unmarshal(b, &f); err != nil { return nil, err }
+    return f.Gateways, nil
+}
+
+// GetGateway finds a gateway by name.
+func GetGateway(name string) (core.Gateway, error) {
+    list, err := LoadGateways()
+    if err != nil { return core.Gateway{}, err }
+    for _, g := range list {
+        if g.Name == name { return g, nil }
+    }
+    return core.Gateway{}, fmt.Errorf("gateway not found: %s", name)
+}
+
+// AddGateway stores a new gateway and expands it into its derived presets.
+func AddGateway(g core.Gateway) error {
+    // the name is also the alias of the derived presets
+    if err := core.ValidateAlias(g.Name); err != nil { return err }
+    list, err := LoadGateways()
+    if err != nil { return err }
+    for _, x := range list {
+        if x.Name == g.Name { return fmt.Errorf("gateway already exists: %s", g.Name) }
+    }
+    // check every agent first so a conflict leaves nothing half-expanded
+    for _, agent := range g.Agents() {
+        if _, err := derivedIndex(agent, g); err != nil { return err }
+    }
+    if err := writeGateways(append(list, g)); err != nil { return err }
+    return SyncGateway(g)
+}
+
+// SetGatewayKey replaces a gateway's key and rewrites every derived preset.
+func SetGatewayKey(name, key string) (core.Gateway, error) {
+    list, err := LoadGateways()
+    if err != nil { return core.Gateway{}, err }
+    for i := range list {
+        if list[i].Name != name { continue }
+        list[i].Key = key
+        if err := writeGateways(list); err != nil { return core.Gateway{}, err }
+        return list[i], SyncGateway(list[i])
+    }
+    return core.Gateway{}, fmt.Errorf("gateway not found: %s", name)
+}
+
+// RemoveGateway deletes a gateway together with its derived presets.
+func RemoveGateway(name string) error {
+    g, err := GetGateway(name)
+    if err != nil { return err }
+    for _, agent := range g.Agents() {
+        f, err := loadPresetFile(agent)
+        if err != nil { return err }
+        kept := f.Presets[:0]
+        for _, p := range f.Presets {
+            if p.Gateway != name { kept = append(kept, p) }
+        }
+        if len(kept) == len(f.Presets) { continue }
+        f.Presets = kept
+        if err := writePresetFile(agent, f); err != nil { return err }
+    }
+    list, _ := LoadGateways()
+    kept := make([]core.Gateway, 0, len(list))
+    for _, x := range list {
+        if x.Name != name { kept = append(kept, x) }
+    }
+    return writeGateways(kept)
+}
+
+// SyncGateway writes the gateway's URL and key into the derived preset of each of
+// its agents, creating missing ones under the gateway's name. Derived presets are
+// found by their gateway link, so renaming one keeps it in sync.
+func SyncGateway(g core.Gateway) error {
+    for _, agent := range g.Agents() {
+        f, err := loadPresetFile(agent)
+        if err != nil { return err }
+        i, err := derivedIndex(agent, g)
+        if err != nil { return err }
+        if i < 0 {
+            f.Presets = append(f.Presets, g.Derive(agent, core.Preset{Alias: g.Name, AddedAt: core.Timestamp(time.Now())}))
+        } else {
+            f.Presets[i] = g.Derive(agent, f.Presets[i])
+        }
+        if err := writePresetFile(agent, f); err != nil { return err }
+    }
+    return nil
+}
+
+// derivedIndex locates the agent's preset derived from g, or -1 when there is none
+// yet. A plain preset already using the gateway's name is a conflict.
+func derivedIndex(agent core.AgentID, g core.Gateway) (int, error) {
+    ps, err := LoadPresets(agent)
+    if err != nil { return -1, err }
+    for i, p := range ps {
+        if p.Gateway == g.Name { return i, nil }
+    }
+    for _, p := range ps {
+        if p.Alias == g.Name {
+            return -1, fmt.Errorf("%s: alias already exists and is not from gateway %s", agent, g.Name)
+        }
+    }
+    return -1, nil
+}
+
+func writeGateways(list []core.Gateway) error {
+    if err := fsx.MkdirAll(BaseDir(), 0o700); err != nil { return err }
+    data, _ := json.MarshalIndent(&gatewayFile{Version: 1, Gateways: list}, "", "  ")
+    return fsx.AtomicWrite(gatewaysPath(), data, fs.FileMode(0o600))
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+)
+
+func TestGatewayLifecycle(t *testing.T) {
+    fsxtest.UseRoot(t)
+    g := core.Gateway{Name: "corp", Host: "https://gw.corp/", Key: "sk-gw-1", Paths: map[core.AgentID]string{
+        core.AgentClaude: "/anthropic", core.AgentCodex: "openai/v1",
+    }}
+    if err := AddGateway(g); err != nil { t.Fatal(err) }
+    if err := AddGateway(g); err == nil { t.Fatal("duplicate gateway accepted") }
+    if got, err := GetGateway("corp"); err != nil || got.Key != "sk-gw-1" { t.Fatalf("GetGateway = %+v, %v", got, err) }
+
+    derived := func(agent core.AgentID) core.Preset {
+        t.Helper()
+        ps, _ := LoadPresets(agent)
+        for _, p := range ps {
+            if p.Gateway == "corp" { return p }
+        }
+        t.Fatalf("%s: no preset derived from corp in %+v", agent, ps)
+        return core.Preset{}
+    }
+    if p := derived(core.AgentClaude); p.Alias != "corp" || p.URL != "https://gw.corp/anthropic" || p.Token != "sk-gw-1" { t.Fatalf("claude preset = %+v", p) }
+    if p := derived(core.AgentCodex); p.URL != "https://gw.corp/openai/v1" { t.Fatalf("codex preset = %+v", p) }
+    if ps, _ := LoadPresets(core.AgentGemini); len(ps) != 0 { t.Fatalf("gemini got presets: %+v", ps) }
+
+    // a renamed derived preset still follows the gateway's key
+    if err := RenamePreset(core.AgentClaude, "corp", "corp-claude"); err != nil { t.Fatal(err) }
+    if _, err := SetGatewayKey("corp", "sk-gw-2"); err != nil { t.Fatal(err) }
+    if p := derived(core.AgentClaude); p.Alias != "corp-claude" || p.Token != "sk-gw-2" { t.Fatalf("after rotation: %+v", p) }
+    if p := derived(core.AgentCodex); p.Token != "sk-gw-2" { t.Fatalf("codex after rotation: %+v", p) }
+    if _, err := SetGatewayKey("nope", "x"); err == nil { t.Fatal("unknown gateway accepted") }
+
+    if err := RemoveGateway("corp"); err != nil { t.Fatal(err) }
+    for _, agent := range []core.AgentID{core.AgentClaude, core.AgentCodex} {
+        if ps, _ := LoadPresets(agent); len(ps) != 0 { t.Fatalf("%s presets left: %+v", agent, ps) }
+    }
+    if list, _ := LoadGateways(); len(list) != 0 { t.Fatalf("gateways left: %+v", list) }
+}
+
+func TestAddGatewayConflictLeavesNothing(t *testing.T) {
+    fsxtest.UseRoot(t)
+    if err := AddPreset(core.AgentCodex, core.Preset{Alias: "corp", URL: "https://mine.example.com", Token: "sk-mine"}); err != nil { t.Fatal(err) }
+    g := core.Gateway{Name: "corp", Host: "https://gw.corp", Key: "sk-gw", Paths: map[core.AgentID]string{core.AgentClaude: "", core.AgentCodex: "/v1"}}
+    if err := AddGateway(g); err == nil { t.Fatal("gateway over a plain preset accepted") }
+    if list, _ := LoadGateways(); len(list) != 0 { t.Fatalf("gateway stored: %+v", list) }
+    if ps, _ := LoadPresets(core.AgentClaude); len(ps) != 0 { t.Fatalf("claude half-expanded: %+v", ps) }
+    if p, _ := GetPreset(core.AgentCodex, "corp"); p.Token != "sk-mine" || p.Gateway != "" { t.Fatalf("plain preset changed: %+v", p) }
+    if err := AddGateway(core.Gateway{Name: "bad name", Host: "https://gw.corp"}); err == nil { t.Fatal("unsafe gateway name accepted") }
+}
+
+func TestCopyPreset(t *testing.T) {
+    fsxtest.UseRoot(t)
+    src := core.Preset{Alias: "work", URL: "https://gw.example.com", Token: "sk-1", Model: "claude-x",
+        Tags: []string{"team"}, Notes: "n", UseCount: 3, LastUsedAt: "2026-01-01T00:00:00Z", Gateway: "corp", KeyHelper: true,
+        Headers: core.Headers{{Name: "X-Team", Value: "a"}}, Tiers: core.ModelTiers{Opus: "o"}, Network: core.Network{Timeout: "1m0s"}}
+    if err := AddPreset(core.AgentClaude, src); err != nil { t.Fatal(err) }
+
+    p, err := CopyPreset(core.AgentClaude, core.AgentCodex, "work", "")
+    if err != nil { t.Fatal(err) }
+    if p.Alias != "work" || p.Token != "sk-1" || p.Model != "" || p.KeyHelper || !p.Tiers.IsZero() { t.Fatalf("codex copy = %+v", p) }
+    if p.UseCount != 0 || p.LastUsedAt != "" || p.Gateway != "" { t.Fatalf("usage or gateway travelled: %+v", p) }
+    if len(p.Tags) != 1 || p.Notes != "n" || len(p.Headers) != 1 || p.Network.Timeout != "1m0s" { t.Fatalf("metadata lost: %+v", p) }
+    if stored, err := GetPreset(core.AgentCodex, "work"); err != nil || stored.URL != src.URL { t.Fatalf("stored = %+v, %v", stored, err) }
+
+    // same kind keeps the model; gemini drops headers and rejects the timeout
+    if p, err := CopyPreset(core.AgentClaude, core.AgentClaude, "work", "work2"); err != nil || p.Model != "claude-x" || !p.KeyHelper { t.Fatalf("claude copy = %+v, %v", p, err) }
+    if _, err := CopyPreset(core.AgentClaude, core.AgentGemini, "work", ""); err == nil { t.Fatal("gemini copy with a timeout accepted") }
+    if err := SetPresetMeta(core.AgentClaude, "work", PresetMeta{Network: &core.Network{}}); err != nil { t.Fatal(err) }
+    if p, err := CopyPreset(core.AgentClaude, core.AgentGemini, "work", ""); err != nil || p.Headers != nil { t.Fatalf("gemini copy = %+v, %v", p, err) }
+
+    if _, err := CopyPreset(core.AgentClaude, core.AgentClaude, "work", ""); err == nil { t.Fatal("copy onto itself accepted") }
+    if _, err := CopyPreset(core.AgentClaude, core.AgentCodex, "work", ""); err == nil { t.Fatal("copy over an existing alias accepted") }
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strconv"
+    "time"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+// HistoryPath returns the append-only apply log (one JSON entry per line).
+func HistoryPath() string {
+    return filepath.Join(BaseDir(), "history.jsonl")
+}
+
+// AppendHistory adds an entry to the end of the log; ID and Time are filled if empty.
+// The line is appended in one write, so concurrent applies (CLI and TUI) keep both entries.
+func AppendHistory(e core.HistoryEntry) error {
+    if e.Time.IsZero() { e.Time = time.Now() }
+    if e.ID == "" { e.ID = strconv.FormatInt(e.Time.UnixNano(), 36) }
+    line, err := json.Marshal(&e)
+    if err != nil { return err }
+    p := HistoryPath()
+    if err := fsx.MkdirAll(filepath.Dir(p), 0o700); err != nil { return err }
+    return fsx.AppendFile(p, append(line, '\n'), fs.FileMode(0o600))
+}
+
+// LoadHistory returns entries oldest first, filtered by agent when non-empty.
+// Malformed lines are skipped so a damaged log never blocks applies.
+func LoadHistory(agent core.AgentID) ([]core.HistoryEntry, error) {
+    b, err := fsx.ReadFile(HistoryPath())
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return nil, nil }
+        return nil, err
+    }
+    var out []core.HistoryEntry
+    s := bufio.NewScanner(bytes.NewReader(b))
+    s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+    for s.Scan() {
+        var e core.HistoryEntry
+        if json.Unmarshal(s.Bytes(), &e) != nil { continue }
+        if agent != "" && e.Agent != agent { continue }
+        out = append(out, e)
+    }
+    return out, s.Err()
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+    "sync"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+)
+
+func TestAppendHistoryConcurrent(t *testing.T) {
+    fsxtest.UseRoot(t)
+    const n = 50
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            e := core.HistoryEntry{ID: string(rune('A' + i)), Agent: core.AgentClaude, Action: core.ActionApply, Source: "test"}
+            if err := AppendHistory(e); err != nil { t.Error(err) }
+        }(i)
+    }
+    wg.Wait()
+    hist, err := LoadHistory(core.AgentClaude)
+    if err != nil { t.Fatal(err) }
+    if len(hist) != n { t.Fatalf("got %d entries, want %d", len(hist), n) }
+}
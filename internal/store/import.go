@@ -0,0 +1,86 @@
+package store
+
+import (
+    "fmt"
+
+    core "tks/internal/core"
+)
+
+// Merge strategies for an imported preset whose alias is already in use.
+const (
+    MergeSkip      = "skip"      // keep the existing preset
+    MergeOverwrite = "overwrite" // replace it
+    MergeRename    = "rename"    // store the import under a -2, -3... suffix
+)
+
+// MergeStrategies lists the valid strategies.
+var MergeStrategies = []string{MergeSkip, MergeOverwrite, MergeRename}
+
+// ImportResult is the outcome for one preset passed to ImportPresets.
+type ImportResult struct {
+    Requested string // alias the preset came with
+    Alias     string // alias it is stored under; differs from Requested after a rename
+    Duplicate string // existing preset with the same values; nothing was stored
+    Conflict  bool   // alias in use and kept (MergeSkip); nothing was stored
+    Replaced  bool   // alias in use and overwritten (MergeOverwrite)
+}
+
+// ImportPresets adds presets to an agent in one atomic write of its preset file.
+// A preset whose values match an existing one (as on init), or one added earlier
+// in the same call, is skipped; an alias already in use is handled by strategy.
+// Every alias is validated first, so a crafted file cannot store one that is
+// unsafe in a key-helper command; nothing is written if any is invalid.
+// With dryRun the results are computed but nothing is written.
+func ImportPresets(agent core.AgentID, ps []core.Preset, strategy string, dryRun bool) ([]ImportResult, error) {
+    switch strategy {
+    case MergeSkip, MergeOverwrite, MergeRename:
+    default:
+        return nil, fmt.Errorf("unknown merge strategy %q (want skip, overwrite or rename)", strategy)
+    }
+    for _, pr := range ps {
+        if err := core.ValidateAlias(pr.Alias); err != nil { return nil, err }
+        if err := core.CheckNetwork(agent, pr.Network); err != nil { return nil, fmt.Errorf("preset %s: %w", pr.Alias, err) }
+    }
+    f, err := loadPresetFile(agent)
+    if err != nil { return nil, err }
+    index := func(alias string) int {
+        for i, p := range f.Presets {
+            if p.Alias == alias { return i }
+        }
+        return -1
+    }
+    out := make([]ImportResult, 0, len(ps))
+    changed := 0
+    for _, pr := range ps {
+        r := ImportResult{Requested: pr.Alias, Alias: pr.Alias}
+        for _, p := range f.Presets {
+            if core.Matches(p, pr.Fields()) { r.Duplicate = p.Alias; break }
+        }
+        i := index(pr.Alias)
+        switch {
+        case r.Duplicate != "":
+            r.Alias = ""
+        case i < 0:
+            f.Presets = append(f.Presets, pr)
+        case strategy == MergeSkip:
+            r.Conflict = true
+        case strategy == MergeOverwrite:
+            r.Replaced, f.Presets[i] = true, pr
+        default:
+            for n := 2; index(r.Alias) >= 0; n++ {
+                r.Alias = core.SuffixAlias(pr.Alias, fmt.Sprintf("-%d", n))
+            }
+            pr.Alias = r.Alias
+            f.Presets = append(f.Presets, pr)
+        }
+        if r.Duplicate == "" && !r.Conflict { changed++ }
+        out = append(out, r)
+    }
+    if dryRun || changed == 0 { return out, nil }
+    if err := writePresetFile(agent, f); err != nil { return out, err }
+    // a replaced preset may point elsewhere: its cached model list is stale
+    for _, r := range out {
+        if r.Replaced { moveModelCache(agent, r.Alias, "") }
+    }
+    return out, nil
+}
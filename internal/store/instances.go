@@ -0,0 +1,70 @@
+package store
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+type instanceFile struct {
+    Version   int             `json:"version"`
+    Instances []core.Instance `json:"instances"`
+}
+
+func instancesPath() string {
+    return filepath.Join(BaseDir(), "instances.json")
+}
+
+// LoadInstances returns all configured named agent instances.
+func LoadInstances() ([]core.Instance, error) {
+    b, err := fsx.ReadFile(instancesPath())
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return nil, nil }
+        return nil, err
+    }
+    var f instanceFile
+    if err := json.Unmarshal(b, &f); err != nil { return nil, err }
+    return f.Instances, nil
+}
+
+// GetInstance finds an instance by its full id (e.g. codex@work).
+func GetInstance(id core.AgentID) (core.Instance, error) {
+    list, err := LoadInstances()
+    if err != nil { return core.Instance{}, err }
+    for _, in := range list {
+        if in.ID() == id { return in, nil }
+    }
+    return core.Instance{}, fmt.Errorf("instance not found: %s", id)
+}
+
+// AddInstance registers a named instance; the id must be unique.
+func AddInstance(in core.Instance) error {
+    list, err := LoadInstances()
+    if err != nil { return err }
+    for _, x := range list {
+        if x.ID() == in.ID() { return fmt.Errorf("instance already exists: %s", in.ID()) }
+    }
+    return writeInstances(append(list, in))
+}
+
+// RemoveInstance deletes an instance by id. Its presets file is left in place.
+func RemoveInstance(id core.AgentID) error {
+    list, err := LoadInstances()
+    if err != nil { return err }
+    kept := make([]core.Instance, 0, len(list))
+    for _, x := range list {
+        if x.ID() != id { kept = append(kept, x) }
+    }
+    if len(kept) == len(list) { return fmt.Errorf("instance not found: %s", id) }
+    return writeInstances(kept)
+}
+
+func writeInstances(list []core.Instance) error {
+    data, _ := json.MarshalIndent(&instanceFile{Version: 1, Instances: list}, "", "  ")
+    return fsx.AtomicWrite(instancesPath(), data, fs.FileMode(0o600))
+}
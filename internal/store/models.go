@@ -0,0 +1,54 @@
+package store
+
+import (
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "time"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+// ModelCache is the model list last fetched from a preset's endpoint.
+type ModelCache struct {
+    FetchedAt string   `json:"fetched_at"` // RFC 3339
+    Models    []string `json:"models"`
+}
+
+func modelCachePath(agent core.AgentID, alias string) string {
+    return filepath.Join(BaseDir(), "models", string(agent), alias+".json")
+}
+
+// LoadModelCache returns the cached models of a preset; false when nothing is cached.
+func LoadModelCache(agent core.AgentID, alias string) (ModelCache, bool, error) {
+    b, err := fsx.ReadFile(modelCachePath(agent, alias))
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return ModelCache{}, false, nil }
+        return ModelCache{}, false, err
+    }
+    var c ModelCache
+    if err := json.Unmarshal(b, &c); err != nil { return ModelCache{}, false, err }
+    return c, true, nil
+}
+
+// SaveModelCache replaces the cached models of a preset.
+func SaveModelCache(agent core.AgentID, alias string, models []string, at time.Time) error {
+    p := modelCachePath(agent, alias)
+    if err := fsx.MkdirAll(filepath.Dir(p), 0o700); err != nil { return err }
+    data, _ := json.MarshalIndent(ModelCache{FetchedAt: core.Timestamp(at), Models: models}, "", "  ")
+    return fsx.AtomicWrite(p, data, fs.FileMode(0o600))
+}
+
+// moveModelCache follows a preset rename (to == "" drops the cache); best effort,
+// since a stale cache only costs a refetch.
+func moveModelCache(agent core.AgentID, from, to string) {
+    if from == to { return }
+    src := modelCachePath(agent, from)
+    if to == "" {
+        _ = fsx.Remove(src)
+        return
+    }
+    _ = fsx.Rename(src, modelCachePath(agent, to))
+}
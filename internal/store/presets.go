@@ -8,41 +8,61 @@ import (
     "os"
     "path/filepath"
     "runtime"
+    "time"
     core "tks/internal/core"
     "tks/internal/fsx"
+    "tks/internal/util"
     verinfo "tks/internal/version"
 )
 
+// SchemaVersion is the preset file layout written by this build.
+// v2 adds metadata (tags, notes, expiry, usage) and stores times as RFC 3339.
+const SchemaVersion = 2
+
 type presetFile struct {
     Version int            `json:"version"`
     ConfigVersion string   `json:"config_version,omitempty"`
     Presets []core.Preset  `json:"presets"`
+    from    int            // schema version found on disk before migration; 0 for a new file
 }
 
 func configDir() string {
-    // Windows: prefer %APPDATA%\token-switcher\presets
+    return filepath.Join(BaseDir(), "presets")
+}
+
+// BaseDir returns agtok's config directory (presets live in BaseDir/presets).
+// Priority: --config-dir / AGTOK_HOME -> --root -> platform default.
+func BaseDir() string {
+    r := fsx.CurrentRoot()
+    if r.ConfigDir != "" {
+        return r.ConfigDir
+    }
+    if r.Home != "" {
+        return filepath.Join(r.Home, ".config", "token-switcher")
+    }
+    // Windows: prefer %APPDATA%\token-switcher
     if runtime.GOOS == "windows" {
         if d := os.Getenv("APPDATA"); d != "" {
-            return filepath.Join(d, "token-switcher", "presets")
+            return filepath.Join(d, "token-switcher")
         }
         // Fallback to USERPROFILE\.config
-        if h, _ := os.UserHomeDir(); h != "" {
-            return filepath.Join(h, ".config", "token-switcher", "presets")
+        if h := fsx.Home(); h != "" {
+            return filepath.Join(h, ".config", "token-switcher")
         }
-        return filepath.Join(".", ".config", "token-switcher", "presets")
+        return filepath.Join(".", ".config", "token-switcher")
     }
     // Unix-like
     if d := os.Getenv("XDG_CONFIG_HOME"); d != "" {
-        return filepath.Join(d, "token-switcher", "presets")
+        return filepath.Join(d, "token-switcher")
     }
-    h, _ := os.UserHomeDir()
+    h := fsx.Home()
     if h == "" { h = "." }
-    return filepath.Join(h, ".config", "token-switcher", "presets")
+    return filepath.Join(h, ".config", "token-switcher")
 }
 
 // legacyConfigDir returns the previous default directory used on non-Windows.
 func legacyConfigDir() string {
-    h, _ := os.UserHomeDir()
+    h := fsx.Home()
     if h == "" { h = "." }
     return filepath.Join(h, ".config", "token-switcher", "presets")
 }
@@ -66,33 +86,55 @@ func LoadPresets(agent core.AgentID) ([]core.Preset, error) {
 // loadPresetFile reads the full preset file including metadata.
 func loadPresetFile(agent core.AgentID) (presetFile, error) {
     p := pathFor(agent)
-    b, err := os.ReadFile(p)
+    b, err := fsx.ReadFile(p)
     if err != nil {
-        if errors.Is(err, os.ErrNotExist) { return presetFile{Version: 1}, nil }
+        if errors.Is(err, os.ErrNotExist) { return presetFile{Version: SchemaVersion}, nil }
         return presetFile{}, err
     }
     var f presetFile
     if err := json.Unmarshal(b, &f); err != nil { return presetFile{}, err }
     if f.Version == 0 { f.Version = 1 }
+    f.from = f.Version
+    migratePresetFile(&f)
     return f, nil
 }
 
+// migratePresetFile upgrades an older layout in memory; the next write persists it.
+func migratePresetFile(f *presetFile) {
+    if f.Version < 2 {
+        // v1 stamped added_at as 20060102-1504
+        for i := range f.Presets {
+            f.Presets[i].AddedAt = core.NormalizeTime(f.Presets[i].AddedAt)
+        }
+    }
+    f.Version = SchemaVersion
+}
+
+// MigratePresets rewrites an agent's preset file in the current schema.
+// It reports the version found on disk (0 when there is no file).
+func MigratePresets(agent core.AgentID) (int, error) {
+    f, err := loadPresetFile(agent)
+    if err != nil { return 0, err }
+    if f.from == 0 || f.from == SchemaVersion { return f.from, nil }
+    return f.from, writePresetFile(agent, f)
+}
+
 // writePresetFile writes the full preset file and stamps config_version.
 func writePresetFile(agent core.AgentID, f presetFile) error {
-    if f.Version == 0 { f.Version = 1 }
+    f.Version = SchemaVersion
     f.ConfigVersion = verinfo.Version
     data, _ := json.MarshalIndent(&f, "", "  ")
     path := pathFor(agent)
     dir := filepath.Dir(path)
-    if err := os.MkdirAll(dir, 0o700); err != nil { return err }
+    if err := fsx.MkdirAll(dir, 0o700); err != nil { return err }
     // Windows one-time migration: if writing to new location but legacy file exists, copy it
     if runtime.GOOS == "windows" {
         // If target file does not exist, but legacy file exists, copy bytes over (first write migration)
-        if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+        if _, err := fsx.Stat(path); errors.Is(err, os.ErrNotExist) {
             oldPath := filepath.Join(legacyConfigDir(), fmt.Sprintf("%s.json", string(agent)))
-            if b, err2 := os.ReadFile(oldPath); err2 == nil {
+            if b, err2 := fsx.ReadFile(oldPath); err2 == nil {
                 // ensure dir exists and write the legacy content first (best-effort)
-                _ = os.MkdirAll(dir, 0o700)
+                _ = fsx.MkdirAll(dir, 0o700)
                 _ = fsx.AtomicWrite(path, b, fs.FileMode(0o600))
             }
         }
@@ -100,8 +142,10 @@ func writePresetFile(agent core.AgentID, f presetFile) error {
     return fsx.AtomicWrite(path, data, fs.FileMode(0o600))
 }
 
-// AddPreset appends a preset; alias must be unique within agent.
+// AddPreset appends a preset; alias must be valid and unique within agent.
 func AddPreset(agent core.AgentID, pr core.Preset) error {
+    if err := core.ValidateAlias(pr.Alias); err != nil { return err }
+    if err := core.CheckNetwork(agent, pr.Network); err != nil { return err }
     f, _ := loadPresetFile(agent)
     list := f.Presets
     for _, p := range list {
@@ -136,11 +180,14 @@ func RemovePreset(agent core.AgentID, alias string) error {
     }
     if !removed { return fmt.Errorf("preset not found: %s", alias) }
     f.Presets = kept
-    return writePresetFile(agent, f)
+    if err := writePresetFile(agent, f); err != nil { return err }
+    moveModelCache(agent, alias, "")
+    return nil
 }
 
 // RenamePreset renames a preset alias, ensuring uniqueness within the agent.
 func RenamePreset(agent core.AgentID, oldAlias, newAlias string) error {
+    if err := core.ValidateAlias(newAlias); err != nil { return err }
     f, err := loadPresetFile(agent)
     if err != nil { return err }
     list := f.Presets
@@ -152,12 +199,17 @@ func RenamePreset(agent core.AgentID, oldAlias, newAlias string) error {
     if found < 0 { return fmt.Errorf("preset not found: %s", oldAlias) }
     list[found].Alias = newAlias
     f.Presets = list
-    return writePresetFile(agent, f)
+    if err := writePresetFile(agent, f); err != nil { return err }
+    moveModelCache(agent, oldAlias, newAlias)
+    return nil
 }
 
 // UpdatePreset updates fields of a preset. url/token/model are optional via pointers.
 // clearToken/clearModel indicate explicit clearing.
 func UpdatePreset(agent core.AgentID, oldAlias, newAlias string, url *string, token *string, model *string, clearToken bool, clearModel bool) error {
+    if newAlias != "" && newAlias != oldAlias {
+        if err := core.ValidateAlias(newAlias); err != nil { return err }
+    }
     f, err := loadPresetFile(agent)
     if err != nil { return err }
     list := f.Presets
@@ -175,22 +227,25 @@ func UpdatePreset(agent core.AgentID, oldAlias, newAlias string, url *string, to
     if url != nil { list[idx].URL = *url }
     // token (three-state)
     if clearToken { list[idx].Token = "" } else if token != nil { list[idx].Token = *token }
+    if clearToken || token != nil { list[idx].TokenFP = "" }
     // model (three-state), only meaningful for Claude but harmless elsewhere
     if clearModel { list[idx].Model = "" } else if model != nil { list[idx].Model = *model }
     f.Presets = list
-    return writePresetFile(agent, f)
+    if err := writePresetFile(agent, f); err != nil { return err }
+    if newAlias != "" { moveModelCache(agent, oldAlias, newAlias) }
+    return nil
 }
 
-// MigrateOnInit backfills missing model for Gemini/Codex when schema version==1,
+// MigrateOnInit backfills missing model for Gemini/Codex when the file was schema v1,
 // and updates config_version to current. Claude is skipped for backfill.
 func MigrateOnInit(agent core.AgentID, diskModel string) error {
     f, err := loadPresetFile(agent)
     if err != nil { return err }
-    if f.Version != 1 {
+    if f.from != 1 {
         // still stamp config version on init
         return writePresetFile(agent, f)
     }
-    if (agent == core.AgentGemini || agent == core.AgentCodex) && diskModel != "" {
+    if (agent.Base() == core.AgentGemini || agent.Base() == core.AgentCodex) && diskModel != "" {
         for i := range f.Presets {
             if f.Presets[i].Model == "" { f.Presets[i].Model = diskModel }
         }
@@ -198,3 +253,85 @@ func MigrateOnInit(agent core.AgentID, diskModel string) error {
     // write back (also stamps config_version)
     return writePresetFile(agent, f)
 }
+
+// RecordUse bumps a preset's use_count and sets last_used_at to at. tokenFP is
+// the fingerprint of the token written; it is kept for presets holding a reference.
+func RecordUse(agent core.AgentID, alias string, at time.Time, tokenFP string) error {
+    f, err := loadPresetFile(agent)
+    if err != nil { return err }
+    for i := range f.Presets {
+        if f.Presets[i].Alias == alias {
+            f.Presets[i].UseCount++
+            f.Presets[i].LastUsedAt = core.Timestamp(at)
+            f.Presets[i].TokenFP = ""
+            if util.IsSecretRef(f.Presets[i].Token) { f.Presets[i].TokenFP = tokenFP }
+            return writePresetFile(agent, f)
+        }
+    }
+    return fmt.Errorf("preset not found: %s", alias)
+}
+
+// PresetMeta holds metadata edits; nil fields are left unchanged.
+type PresetMeta struct {
+    Tags      *[]string
+    Notes     *string
+    ExpiresAt *string // "" clears the expiry
+    KeyHelper *bool
+    Headers   *core.Headers // empty clears
+    Network   *core.Network // replaces all network settings
+    Tiers     *core.ModelTiers // replaces the whole mapping
+}
+
+// SetPresetMeta updates a preset's tags, notes, expiry, key-helper mode, headers,
+// network settings or model tiers.
+func SetPresetMeta(agent core.AgentID, alias string, m PresetMeta) error {
+    f, err := loadPresetFile(agent)
+    if err != nil { return err }
+    for i := range f.Presets {
+        p := &f.Presets[i]
+        if p.Alias != alias { continue }
+        if m.Tags != nil { p.Tags = *m.Tags }
+        if m.Notes != nil { p.Notes = *m.Notes }
+        if m.KeyHelper != nil { p.KeyHelper = *m.KeyHelper }
+        if m.Headers != nil { p.Headers = *m.Headers }
+        if m.Tiers != nil { p.Tiers = *m.Tiers }
+        if m.Network != nil {
+            n := *m.Network
+            if err := n.Validate(); err != nil { return err }
+            if err := core.CheckNetwork(agent, n); err != nil { return err }
+            p.Network = n
+        }
+        if m.ExpiresAt != nil {
+            if *m.ExpiresAt != "" {
+                if _, ok := core.ParseTime(*m.ExpiresAt); !ok {
+                    return fmt.Errorf("invalid expiry %q (use 2006-01-02 or RFC 3339)", *m.ExpiresAt)
+                }
+            }
+            p.ExpiresAt = core.NormalizeTime(*m.ExpiresAt)
+        }
+        return writePresetFile(agent, f)
+    }
+    return fmt.Errorf("preset not found: %s", alias)
+}
+
+// CopyPreset copies a preset to another agent under newAlias (the same alias when
+// empty). Metadata travels with the key; usage stats start over, the gateway link
+// is dropped and the model is kept only between agents of the same kind, since
+// model names differ per protocol. Network settings the target cannot hold are
+// an error (see core.CheckNetwork), not dropped.
+func CopyPreset(from, to core.AgentID, alias, newAlias string) (core.Preset, error) {
+    p, err := GetPreset(from, alias)
+    if err != nil { return core.Preset{}, err }
+    if newAlias == "" { newAlias = alias }
+    if from == to && newAlias == alias {
+        return core.Preset{}, fmt.Errorf("copy onto itself: %s", alias)
+    }
+    p.Alias, p.AddedAt = newAlias, core.Timestamp(time.Now())
+    p.LastUsedAt, p.UseCount, p.Gateway = "", 0, ""
+    if from.Base() != to.Base() { p.Model = "" }
+    if to.Base() != core.AgentClaude { p.KeyHelper, p.Tiers = false, core.ModelTiers{} }
+    if to.Base() != core.AgentGemini { p.Auth, p.Project, p.Location = "", "", "" }
+    // gemini has no place for headers
+    if to.Base() == core.AgentGemini { p.Headers = nil }
+    return p, AddPreset(to, p)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/fsx/fsxtest"
+)
+
+func TestBaseDirFollowsRoot(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // host settings do not apply under --root
+    if got, want := BaseDir(), filepath.Join(home, ".config", "token-switcher"); got != want {
+        t.Fatalf("BaseDir = %s, want %s", got, want)
+    }
+    cfg := filepath.Join(home, "cfg")
+    fsx.SetRoot(fsx.Root{Home: home, ConfigDir: cfg})
+    if got, want := PresetsDir(), filepath.Join(cfg, "presets"); got != want {
+        t.Fatalf("PresetsDir = %s, want %s", got, want)
+    }
+}
+
+func TestPresetLifecycleUnderRoot(t *testing.T) {
+    home := fsxtest.UseRoot(t)
+    p := core.Preset{Alias: "work", URL: "https://api.example.com", Token: "sk-work"}
+    if err := AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+    if err := AddPreset(core.AgentClaude, p); err == nil { t.Fatal("duplicate alias accepted") }
+
+    path := filepath.Join(home, ".config", "token-switcher", "presets", "claude.json")
+    fi, err := fsx.Stat(path)
+    if err != nil { t.Fatalf("preset file not under root: %v", err) }
+    if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+        t.Fatalf("mode = %v, want 0600", fi.Mode().Perm())
+    }
+
+    if err := RenamePreset(core.AgentClaude, "work", "office"); err != nil { t.Fatal(err) }
+    got, err := GetPreset(core.AgentClaude, "office")
+    if err != nil { t.Fatal(err) }
+    if got.URL != p.URL || got.Token != p.Token { t.Fatalf("renamed preset = %+v", got) }
+    if err := RemovePreset(core.AgentClaude, "office"); err != nil { t.Fatal(err) }
+    if list, _ := LoadPresets(core.AgentClaude); len(list) != 0 { t.Fatalf("presets left: %+v", list) }
+}
+
+func TestInvalidAliasRejected(t *testing.T) {
+    fsxtest.UseRoot(t)
+    bad := core.Preset{Alias: "x; touch /tmp/pwned", URL: "https://api.example.com", Token: "sk-1"}
+    if err := AddPreset(core.AgentClaude, bad); err == nil { t.Fatal("AddPreset accepted an unsafe alias") }
+    if _, err := ImportPresets(core.AgentClaude, []core.Preset{bad}, MergeRename, false); err == nil {
+        t.Fatal("ImportPresets accepted an unsafe alias")
+    }
+    ok := core.Preset{Alias: "ok", URL: "https://api.example.com", Token: "sk-1"}
+    if err := AddPreset(core.AgentClaude, ok); err != nil { t.Fatal(err) }
+    if err := RenamePreset(core.AgentClaude, "ok", bad.Alias); err == nil { t.Fatal("RenamePreset accepted an unsafe alias") }
+    if list, _ := LoadPresets(core.AgentClaude); len(list) != 1 || list[0].Alias != "ok" { t.Fatalf("presets = %+v", list) }
+}
+
+func TestImportRenameKeepsAliasValid(t *testing.T) {
+    fsxtest.UseRoot(t)
+    long := strings.Repeat("a", core.MaxAliasLen)
+    if err := AddPreset(core.AgentClaude, core.Preset{Alias: long, URL: "https://one.example.com", Token: "sk-1"}); err != nil { t.Fatal(err) }
+    res, err := ImportPresets(core.AgentClaude, []core.Preset{{Alias: long, URL: "https://two.example.com", Token: "sk-2"}}, MergeRename, false)
+    if err != nil { t.Fatal(err) }
+    if err := core.ValidateAlias(res[0].Alias); err != nil || res[0].Alias == long { t.Fatalf("renamed to %q: %v", res[0].Alias, err) }
+}
+
+func TestCodexProxyRejectedEverywhere(t *testing.T) {
+    fsxtest.UseRoot(t)
+    proxied := core.Preset{Alias: "corp", URL: "https://gw.corp", Token: "sk-1", Network: core.Network{Proxy: "http://proxy:3128", Timeout: "1m0s"}}
+    want := core.CheckNetwork(core.AgentCodex, proxied.Network)
+    if want == nil { t.Fatal("CheckNetwork accepted a codex proxy") }
+    same := func(what string, err error) {
+        t.Helper()
+        if err == nil || !strings.Contains(err.Error(), want.Error()) { t.Errorf("%s: err = %v, want %v", what, err, want) }
+    }
+
+    same("AddPreset", AddPreset(core.AgentCodex, proxied))
+    _, err := ImportPresets(core.AgentCodex, []core.Preset{proxied}, MergeSkip, false)
+    same("ImportPresets", err)
+    if err := AddPreset(core.AgentClaude, proxied); err != nil { t.Fatal(err) }
+    _, err = CopyPreset(core.AgentClaude, core.AgentCodex, "corp", "")
+    same("CopyPreset", err)
+
+    // the timeout alone is fine for codex
+    plain := core.Preset{Alias: "plain", URL: "https://gw.corp", Token: "sk-1", Network: core.Network{Timeout: "1m0s"}}
+    if err := AddPreset(core.AgentCodex, plain); err != nil { t.Fatal(err) }
+    n := proxied.Network
+    same("SetPresetMeta", SetPresetMeta(core.AgentCodex, "plain", PresetMeta{Network: &n}))
+    if list, _ := LoadPresets(core.AgentCodex); len(list) != 1 || !list[0].Network.Equal(plain.Network) { t.Fatalf("codex presets = %+v", list) }
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "os"
+    "path/filepath"
+    core "tks/internal/core"
+    "tks/internal/fsx"
+)
+
+// Settings are user preferences kept in settings.json under BaseDir.
+type Settings struct {
+    // Trusted lists, per agent id, the preset aliases the TUI applies without
+    // asking for confirmation. "*" trusts every preset of that agent.
+    Trusted map[core.AgentID][]string `json:"trusted_presets,omitempty"`
+}
+
+// SettingsPath returns the location of settings.json.
+func SettingsPath() string {
+    return filepath.Join(BaseDir(), "settings.json")
+}
+
+// LoadSettings reads settings.json; a missing file yields zero settings.
+func LoadSettings() (Settings, error) {
+    b, err := fsx.ReadFile(SettingsPath())
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) { return Settings{}, nil }
+        return Settings{}, err
+    }
+    var s Settings
+    if err := json.Unmarshal(b, &s); err != nil { return Settings{}, err }
+    return s, nil
+}
+
+// SaveSettings writes settings.json atomically.
+func SaveSettings(s Settings) error {
+    data, _ := json.MarshalIndent(&s, "", "  ")
+    if err := fsx.MkdirAll(BaseDir(), 0o700); err != nil { return err }
+    return fsx.AtomicWrite(SettingsPath(), data, fs.FileMode(0o600))
+}
+
+// IsTrusted reports whether applying agent's preset alias skips confirmation.
+func (s Settings) IsTrusted(agent core.AgentID, alias string) bool {
+    for _, a := range s.Trusted[agent] {
+        if a == alias || a == "*" { return true }
+    }
+    return false
+}
+
+// TrustPreset adds alias to the agent's trusted presets.
+func TrustPreset(agent core.AgentID, alias string) error {
+    s, err := LoadSettings()
+    if err != nil { return err }
+    if s.IsTrusted(agent, alias) { return nil }
+    if s.Trusted == nil { s.Trusted = map[core.AgentID][]string{} }
+    s.Trusted[agent] = append(s.Trusted[agent], alias)
+    return SaveSettings(s)
+}
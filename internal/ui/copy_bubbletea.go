@@ -0,0 +1,76 @@
+package ui
+
+import (
+    "fmt"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/store"
+)
+
+// copyPresetCmd copies a preset into each target agent; one failure does not stop the rest.
+func copyPresetCmd(from core.AgentID, alias string, targets []core.AgentID) tea.Cmd {
+    return func() tea.Msg {
+        var done, failed []string
+        for _, to := range targets {
+            if _, err := store.CopyPreset(from, to, alias, ""); err != nil {
+                failed = append(failed, fmt.Sprintf("%s: %v", agentTitle(to), err))
+                continue
+            }
+            done = append(done, agentTitle(to))
+        }
+        msg := opDoneMsg{id: from, also: targets}
+        if len(done) > 0 {
+            msg.status = "copied '" + alias + "' to " + strings.Join(done, ", ")
+        }
+        if len(failed) > 0 {
+            msg.err = fmt.Errorf("copy failed: %s", strings.Join(failed, "; "))
+        }
+        return msg
+    }
+}
+
+// copyTargets lists the groups a preset of the active group can be copied to.
+func (m model) copyTargets() []int {
+    var out []int
+    for i := range m.groups {
+        if i != m.active { out = append(out, i) }
+    }
+    return out
+}
+
+func (m model) updateCopyKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    from := m.groups[m.active].id
+    switch k := msg.String(); k {
+    case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+        i := int(msg.Runes[0] - '1')
+        if i >= len(m.groups) || i == m.active {
+            m.status = "pick another agent"
+            return m, nil
+        }
+        m.m = modeTable
+        m.status = "copying '" + m.copyAlias + "'…"
+        return m, copyPresetCmd(from, m.copyAlias, []core.AgentID{m.groups[i].id})
+    case "a":
+        var ids []core.AgentID
+        for _, i := range m.copyTargets() { ids = append(ids, m.groups[i].id) }
+        m.m = modeTable
+        m.status = "copying '" + m.copyAlias + "'…"
+        return m, copyPresetCmd(from, m.copyAlias, ids)
+    case "esc", "n", "q":
+        m.m = modeTable
+    }
+    return m, nil
+}
+
+func (m model) renderCopy() string {
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("\nCopy '%s' to:\n", m.copyAlias))
+    for _, i := range m.copyTargets() {
+        b.WriteString(fmt.Sprintf("  %s %s\n", styleKey.Render(fmt.Sprintf("[%d]", i+1)), agentTitle(m.groups[i].id)))
+    }
+    b.WriteString(styleMuted.Render("Model is kept only for the same agent kind; an existing alias is not overwritten.") + "\n")
+    return b.String()
+}
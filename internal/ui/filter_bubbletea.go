@@ -0,0 +1,207 @@
+package ui
+
+import (
+    "fmt"
+    "net/url"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// fuzzyMatch reports whether the runes of q appear in s in order (case-insensitive).
+func fuzzyMatch(q, s string) bool {
+    q, s = strings.ToLower(q), strings.ToLower(s)
+    i := 0
+    rs := []rune(q)
+    for _, r := range s {
+        if i < len(rs) && r == rs[i] { i++ }
+    }
+    return i == len(rs)
+}
+
+// urlHost returns the host of a base URL, or the raw string when it has none.
+func urlHost(s string) string {
+    if u, err := url.Parse(s); err == nil && u.Host != "" {
+        return u.Host
+    }
+    return s
+}
+
+// rowMatches applies the filter: every whitespace-separated term must fuzzy-match
+// the alias, URL host, model or one of the tags of the row.
+func rowMatches(r row, q string) bool {
+    for _, term := range strings.Fields(q) {
+        ok := fuzzyMatch(term, r.alias) || fuzzyMatch(term, urlHost(r.url)) || fuzzyMatch(term, r.model)
+        for _, t := range r.meta.Tags {
+            ok = ok || fuzzyMatch(term, t)
+        }
+        if !ok { return false }
+    }
+    return true
+}
+
+// visibleRows lists the row indexes shown under the current filter.
+// The active row is always kept so every group still shows what is on disk.
+func (m model) visibleRows(g group) []int {
+    q := strings.TrimSpace(m.filterIn.Value())
+    var out []int
+    for i, r := range g.rows {
+        if r.kind == rowCurrent || q == "" || rowMatches(r, q) {
+            out = append(out, i)
+        }
+    }
+    return out
+}
+
+// moveSelection steps the active group's cursor by delta over visible rows.
+func (m *model) moveSelection(delta int) {
+    g := &m.groups[m.active]
+    vis := m.visibleRows(*g)
+    pos := 0
+    for i, idx := range vis {
+        if idx == g.index { pos = i }
+    }
+    pos += delta
+    if pos < 0 { pos = 0 }
+    if pos >= len(vis) { pos = len(vis) - 1 }
+    g.index = vis[pos]
+}
+
+// refilter moves cursors that the filter hid onto the first match of their group.
+func (m *model) refilter() {
+    for i := range m.groups {
+        g := &m.groups[i]
+        vis := m.visibleRows(*g)
+        hidden := true
+        for _, idx := range vis {
+            if idx == g.index { hidden = false }
+        }
+        if hidden {
+            // prefer the first matching preset over the always-shown active row
+            g.index = vis[0]
+            if len(vis) > 1 { g.index = vis[1] }
+        }
+    }
+}
+
+// inactiveRows caps the viewport of groups other than the selected one.
+const inactiveRows = 3
+
+// viewportRows is how many rows group gi shows. Other groups keep a short
+// viewport; the selected group gets the height left after the top bar,
+// details panel and help.
+func (m model) viewportRows(gi int) int {
+    if m.height == 0 || len(m.groups) == 0 {
+        return 1 << 30 // size unknown yet: render everything
+    }
+    visible := func(i int) int {
+        n := len(m.visibleRows(m.groups[i]))
+        if n > inactiveRows { n = inactiveRows }
+        return n
+    }
+    if gi != m.active {
+        return visible(gi)
+    }
+    avail := m.height - 1 - 11 - 3
+    if m.m == modeFilter || m.filterIn.Value() != "" { avail-- }
+    // per group: top border, header, separator, hint and a blank line; two lines per row
+    for i := range m.groups {
+        if i != m.active { avail -= 5 + 2*visible(i) }
+    }
+    n := (avail - 5) / 2
+    if n < 2 { n = 2 }
+    return n
+}
+
+// syncViewports scrolls each group so its selected row stays inside the viewport.
+func (m *model) syncViewports() {
+    for i := range m.groups {
+        n := m.viewportRows(i)
+        g := &m.groups[i]
+        vis := m.visibleRows(*g)
+        pos := 0
+        for j, idx := range vis {
+            if idx == g.index { pos = j }
+        }
+        if pos < g.offset { g.offset = pos }
+        if pos >= g.offset+n { g.offset = pos - n + 1 }
+        if last := len(vis) - n; g.offset > last { g.offset = last }
+        if g.offset < 0 { g.offset = 0 }
+    }
+}
+
+func (m model) updateFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "enter":
+        // keep the filter and return to navigation
+        m.m = modeTable
+        m.filterIn.Blur()
+        return m, nil
+    case "esc":
+        m.m = modeTable
+        m.filterIn.Blur()
+        m.filterIn.SetValue("")
+        return m, nil
+    case "up", "down":
+        if msg.String() == "up" { m.moveSelection(-1) } else { m.moveSelection(1) }
+        return m, nil
+    }
+    var cmd tea.Cmd
+    m.filterIn, cmd = m.filterIn.Update(msg)
+    m.refilter()
+    return m, cmd
+}
+
+func (m model) updateOverviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "up", "k":
+        if m.active > 0 { m.active-- }
+    case "down", "j":
+        if m.active < len(m.groups)-1 { m.active++ }
+    case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+        if i := int(msg.Runes[0] - '1'); i < len(m.groups) { m.active = i }
+    case "enter", "o", "esc":
+        // open the selected agent's table
+        m.m = modeTable
+    case "q", "ctrl+c":
+        return m, tea.Quit
+    }
+    return m, nil
+}
+
+// renderOverview shows one line per agent with just its active preset.
+func (m model) renderOverview() string {
+    wAgent, _, wAlias, wURL := m.computeWidths()
+    var b strings.Builder
+    b.WriteString(styleHeader.Render(fmt.Sprintf("%-*s  %-*s  %-*s  %s", wAgent+4, "Agent", wAlias, "Active", wURL, "URL", "Model")) + "\n")
+    for i, g := range m.groups {
+        cur := g.rows[0]
+        alias := cur.alias
+        switch {
+        case g.err != nil:
+            alias = "error"
+        case g.loading && cur.url == "" && alias == "":
+            alias = "loading…"
+        case alias == "" && cur.near != "":
+            alias = "~" + cur.near
+        case alias == "" && cur.url == "" && cur.token == "":
+            alias = "-"
+        case alias == "":
+            alias = "(unmanaged)"
+        }
+        mark := " "
+        if len(g.eff.Shadowed()) > 0 { mark = "!" }
+        line := fmt.Sprintf("[%d] %-*s%s %-*s  %-*s  %s", i+1, wAgent, truncate(agentTitle(g.id), wAgent), mark,
+            wAlias, truncate(alias, wAlias), wURL, truncate(urlHost(cur.urlLabel()), wURL), cur.model)
+        switch {
+        case i == m.active:
+            line = styleAliasSel.Render(line)
+        case g.err != nil:
+            line = styleStatusErr.Render(line)
+        case cur.near != "":
+            line = styleMuted.Render(line)
+        }
+        b.WriteString(line + "\n")
+    }
+    return b.String()
+}
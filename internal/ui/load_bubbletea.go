@@ -0,0 +1,293 @@
+package ui
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// All disk and process I/O of the TUI runs inside tea.Cmds defined here; Update
+// only turns their results into model state. Providers are obtained through
+// providers.NewProvider, so providers.SetFactory swaps them for the TUI and for
+// the ops it calls alike (the tests drive the model with fakes this way).
+
+// groupData is everything read from disk for one agent group.
+type groupData struct {
+    fields  core.Fields
+    presets []core.Preset
+    eff     core.Effective
+}
+
+// groupLoadedMsg carries a finished group load. gen identifies the request so a
+// slow, stale load cannot overwrite a newer one.
+type groupLoadedMsg struct {
+    id   core.AgentID
+    gen  int
+    data groupData
+    err  error
+}
+
+// agentsLoadedMsg lists the base agents plus registered instances.
+type agentsLoadedMsg struct{ ids []core.AgentID }
+
+// opDoneMsg reports the outcome of a write. id is the group to reload (also lists
+// further groups the write touched); focus is the alias to select afterwards.
+// form marks errors that belong to the open form.
+type opDoneMsg struct {
+    id     core.AgentID
+    also   []core.AgentID
+    status string
+    focus  string
+    err    error
+    form   bool
+}
+
+func loadGroupCmd(id core.AgentID, gen int) tea.Cmd {
+    return func() tea.Msg {
+        data, err := loadGroup(id)
+        return groupLoadedMsg{id: id, gen: gen, data: data, err: err}
+    }
+}
+
+func loadGroup(id core.AgentID) (groupData, error) {
+    var d groupData
+    prov := providers.NewProvider(id)
+    if prov == nil {
+        return d, fmt.Errorf("provider not available for agent: %s", id)
+    }
+    f, err := prov.Read(context.Background())
+    if err != nil {
+        return d, err
+    }
+    d.fields = f
+    ps, err := store.LoadPresets(id)
+    if err != nil {
+        return d, err
+    }
+    sort.Slice(ps, func(i, j int) bool { return ps[i].Alias < ps[j].Alias })
+    d.presets = ps
+    // warn when env vars or project/managed settings override the file value
+    cwd, _ := os.Getwd()
+    d.eff, _ = providers.Effective(id, cwd)
+    return d, nil
+}
+
+func loadAgentsCmd() tea.Msg { return agentsLoadedMsg{ids: providers.Agents()} }
+
+// buildRows turns loaded data into the active row followed by the remaining
+// presets in sortKey order (see core.SortPresets).
+func buildRows(d groupData, sortKey string) []row {
+    f := d.fields
+    cur := row{kind: rowCurrent, url: f.URL, token: f.Token, model: f.Model, extra: f}
+    ps := append([]core.Preset(nil), d.presets...)
+    _ = core.SortPresets(ps, sortKey)
+    var rest []row
+    for _, p := range ps {
+        if cur.alias == "" && core.Matches(p, f) {
+            cur.alias, cur.meta = p.Alias, p
+            continue // do not duplicate in list
+        }
+        rest = append(rest, row{kind: rowPreset, alias: p.Alias, url: p.URL, token: p.Token, model: p.Model, meta: p, extra: p.Fields()})
+    }
+    // without an exact match, report the nearest preset and what drifted
+    if cur.alias == "" && (f.URL != "" || f.Token != "") {
+        if nm, ok := core.Nearest(d.presets, f); ok {
+            cur.near, cur.diffs = nm.Alias, nm.Diffs
+        }
+    }
+    return append([]row{cur}, rest...)
+}
+
+func applyPresetCmd(id core.AgentID, p core.Preset) tea.Cmd {
+    return func() tea.Msg {
+        // model is strictly mirrored for all agents
+        if _, err := ops.ApplyPreset(context.Background(), id, p, ops.SourceTUI); err != nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("apply failed: %w", err)}
+        }
+        return opDoneMsg{id: id, status: "applied '" + p.Alias + "'"}
+    }
+}
+
+// initPresetCmd snapshots the agent's current disk config as a new preset.
+func initPresetCmd(id core.AgentID) tea.Cmd {
+    return func() tea.Msg {
+        fail := func(err error) tea.Msg { return opDoneMsg{id: id, err: fmt.Errorf("init failed: %w", err)} }
+        prov := providers.NewProvider(id)
+        if prov == nil {
+            return fail(errors.New("provider not available"))
+        }
+        cur, err := prov.Read(context.Background())
+        if err != nil {
+            return fail(err)
+        }
+        // migrate old presets on init: backfill missing model for Gemini/Codex; stamp config_version
+        _ = store.MigrateOnInit(id, cur.Model)
+        if cur.Unset() {
+            return opDoneMsg{id: id, err: errors.New("skip: nothing configured")}
+        }
+        if err := core.ValidateFields(cur); err != nil {
+            return opDoneMsg{id: id, err: errors.New("skip: current config invalid")}
+        }
+        ps, _ := store.LoadPresets(id)
+        for _, p := range ps {
+            if core.Matches(p, cur) {
+                return opDoneMsg{id: id, status: "identical preset exists: " + p.Alias, focus: p.Alias}
+            }
+        }
+        // alias selection: snap-default or timestamped if exists
+        alias := "snap-default"
+        if _, err := store.GetPreset(id, alias); err == nil {
+            alias = alias + "-" + time.Now().Format("20060102-1504")
+        }
+        pr := core.Preset{Alias: alias, URL: cur.URL, Token: cur.Token, Model: cur.Model, Headers: cur.Headers, Network: cur.Network, Tiers: cur.Tiers, AddedAt: core.Timestamp(time.Now())}
+        if cur.Auth == core.GeminiAuthVertex { pr.Auth, pr.Project, pr.Location = cur.Auth, cur.Project, cur.Location }
+        if err := store.AddPreset(id, pr); err != nil {
+            return fail(err)
+        }
+        return opDoneMsg{id: id, status: "added preset '" + alias + "'", focus: alias}
+    }
+}
+
+// addPresetCmd stores a preset entered in the add form; an empty alias gets a timestamp.
+func addPresetCmd(id core.AgentID, pr core.Preset) tea.Cmd {
+    return func() tea.Msg {
+        fail := func(err error) tea.Msg { return opDoneMsg{id: id, err: err, form: true} }
+        // duplicate by value (include model)
+        ps, err := store.LoadPresets(id)
+        if err != nil {
+            return fail(err)
+        }
+        for _, p := range ps {
+            if core.Matches(p, pr.Fields()) {
+                return fail(errors.New("preset with same values exists: " + p.Alias))
+            }
+        }
+        if pr.Alias == "" {
+            pr.Alias = time.Now().Format("20060102-1504")
+        }
+        if err := store.AddPreset(id, pr); err != nil {
+            if strings.Contains(err.Error(), "already exists") {
+                err = errors.New("alias exists")
+            }
+            return fail(err)
+        }
+        return opDoneMsg{id: id, status: "added", focus: pr.Alias}
+    }
+}
+
+func removePresetCmd(id core.AgentID, alias string) tea.Cmd {
+    return func() tea.Msg {
+        if err := store.RemovePreset(id, alias); err != nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("delete failed: %w", err)}
+        }
+        return opDoneMsg{id: id, status: "deleted"}
+    }
+}
+
+func renamePresetCmd(id core.AgentID, old, newA string) tea.Cmd {
+    return func() tea.Msg {
+        if err := store.RenamePreset(id, old, newA); err != nil {
+            return opDoneMsg{id: id, err: err, form: true}
+        }
+        return opDoneMsg{id: id, status: fmt.Sprintf("renamed '%s' -> '%s'", old, newA), focus: newA}
+    }
+}
+
+// presetUpdate is the submitted update form; nil pointers leave a field unchanged.
+type presetUpdate struct {
+    old, alias         string
+    url, token, model  *string
+    clearTok, clearMdl bool
+    apply              bool // the edited preset is active: write the change to disk too
+    meta               store.PresetMeta
+}
+
+func updatePresetCmd(id core.AgentID, u presetUpdate) tea.Cmd {
+    return func() tea.Msg {
+        if err := store.UpdatePreset(id, u.old, u.alias, u.url, u.token, u.model, u.clearTok, u.clearMdl); err != nil {
+            return opDoneMsg{id: id, err: err, form: true}
+        }
+        if err := store.SetPresetMeta(id, u.alias, u.meta); err != nil {
+            return opDoneMsg{id: id, err: err, form: true}
+        }
+        // a new key on a gateway-derived preset is the gateway's key: every agent follows
+        var also []core.AgentID
+        if p, err := store.GetPreset(id, u.alias); err == nil && p.Gateway != "" && u.token != nil {
+            g, err := store.GetGateway(p.Gateway)
+            if err == nil {
+                _, err = ops.UpdateGatewayKey(context.Background(), g.Name, *u.token, ops.SourceTUI)
+            }
+            if err != nil {
+                return opDoneMsg{id: id, err: fmt.Errorf("updated, but syncing gateway %s failed: %w", p.Gateway, err)}
+            }
+            also = g.Agents()
+        }
+        if !u.apply {
+            return opDoneMsg{id: id, also: also, status: "updated '" + u.alias + "'", focus: u.alias}
+        }
+        prov := providers.NewProvider(id)
+        if prov == nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("update failed to apply: provider not available")}
+        }
+        // cur is the whole config with the edits on top: mirror it, so a
+        // header removed in the form is removed from disk too
+        ctx := providers.WithMirror(context.Background())
+        cur, err := prov.Read(ctx)
+        if err != nil {
+            // mirroring a config that could not be read would wipe it
+            return opDoneMsg{id: id, err: fmt.Errorf("update failed to apply: %w", err)}
+        }
+        if u.url != nil { cur.URL = *u.url }
+        if u.token != nil { cur.Token = *u.token }
+        if u.model != nil { cur.Model = *u.model }
+        if u.meta.Headers != nil { cur.Headers = *u.meta.Headers }
+        if _, err := ops.Apply(ctx, id, u.alias, cur, u.clearMdl, ops.SourceTUI); err != nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("update failed to apply: %w", err)}
+        }
+        return opDoneMsg{id: id, also: also, status: "updated & applied '" + u.alias + "'", focus: u.alias}
+    }
+}
+
+// planReadyMsg carries the preview of an apply; trusted presets skip confirmation.
+// patch is set for a partial apply of preset.
+type planReadyMsg struct {
+    id      core.AgentID
+    preset  core.Preset
+    plan    ops.Plan
+    patch   *ops.Patch
+    trusted bool
+    err     error
+}
+
+func planPresetCmd(id core.AgentID, p core.Preset) tea.Cmd {
+    return func() tea.Msg {
+        plan, err := ops.PlanPreset(context.Background(), id, p)
+        if err != nil {
+            return planReadyMsg{id: id, preset: p, err: fmt.Errorf("apply failed: %w", err)}
+        }
+        // unreadable settings only mean nothing is trusted
+        st, _ := store.LoadSettings()
+        return planReadyMsg{id: id, preset: p, plan: plan, trusted: st.IsTrusted(id, p.Alias)}
+    }
+}
+
+// trustApplyCmd remembers the preset as trusted and applies it.
+func trustApplyCmd(id core.AgentID, p core.Preset) tea.Cmd {
+    return func() tea.Msg {
+        if err := store.TrustPreset(id, p.Alias); err != nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("trust failed: %w", err)}
+        }
+        return applyPresetCmd(id, p)()
+    }
+}
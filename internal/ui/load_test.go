@@ -0,0 +1,211 @@
+package ui
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/fsx/fsxtest"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// fakeProvider stands in for an agent on a slow home directory: Read and Write
+// block until their gate is closed.
+type fakeProvider struct {
+    id        core.AgentID
+    readGate  chan struct{}
+    writeGate chan struct{}
+
+    mu      sync.Mutex
+    fields  core.Fields
+    readErr error
+    writes  int
+}
+
+func (f *fakeProvider) ID() core.AgentID { return f.id }
+func (f *fakeProvider) Paths() []string  { return nil }
+func (f *fakeProvider) Validate(core.Fields) error { return nil }
+
+func (f *fakeProvider) Read(ctx context.Context) (core.Fields, error) {
+    <-f.readGate
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if f.readErr != nil { return core.Fields{}, f.readErr }
+    return f.fields, nil
+}
+
+func (f *fakeProvider) Write(ctx context.Context, fields core.Fields) (core.Backup, error) {
+    <-f.writeGate
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.fields = fields
+    f.writes++
+    return core.Backup{Files: map[string]string{}, Written: map[string]string{}, Time: time.Now()}, nil
+}
+
+func (f *fakeProvider) Plan(ctx context.Context, fields core.Fields) ([]core.FileChange, error) {
+    return nil, nil
+}
+
+func (f *fakeProvider) writeCount() int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.writes
+}
+
+// useFakes roots the store in a temp dir and serves every agent from a fake.
+func useFakes(t *testing.T) map[core.AgentID]*fakeProvider {
+    t.Helper()
+    fsxtest.UseRoot(t)
+    fakes := map[core.AgentID]*fakeProvider{}
+    for _, id := range core.BaseAgents {
+        fakes[id] = &fakeProvider{id: id, readGate: make(chan struct{}), writeGate: make(chan struct{}),
+            fields: core.Fields{URL: "https://" + string(id) + ".example.com", Token: "sk-disk"}}
+    }
+    t.Cleanup(providers.SetFactory(func(id core.AgentID) providers.Provider {
+        if f, ok := fakes[id]; ok { return f }
+        return nil
+    }))
+    return fakes
+}
+
+// run executes cmd the way the Bubble Tea runtime would, expanding batches, and
+// returns every message produced.
+func run(cmd tea.Cmd) []tea.Msg {
+    if cmd == nil { return nil }
+    switch msg := cmd().(type) {
+    case nil:
+        return nil
+    case tea.BatchMsg:
+        var out []tea.Msg
+        for _, c := range msg { out = append(out, run(c)...) }
+        return out
+    default:
+        return []tea.Msg{msg}
+    }
+}
+
+// runAsync starts cmd in the background, as the runtime does.
+func runAsync(cmd tea.Cmd) <-chan []tea.Msg {
+    ch := make(chan []tea.Msg, 1)
+    go func() { ch <- run(cmd) }()
+    return ch
+}
+
+func await(t *testing.T, ch <-chan []tea.Msg) []tea.Msg {
+    t.Helper()
+    select {
+    case msgs := <-ch:
+        return msgs
+    case <-time.After(5 * time.Second):
+        t.Fatal("command did not finish")
+        return nil
+    }
+}
+
+// send feeds msg to the model and fails if Update does not return promptly,
+// which is what the user would see as a frozen screen.
+func send(t *testing.T, m model, msg tea.Msg) (model, tea.Cmd) {
+    t.Helper()
+    type result struct {
+        m   tea.Model
+        cmd tea.Cmd
+    }
+    done := make(chan result, 1)
+    go func() { nm, cmd := m.Update(msg); done <- result{nm, cmd} }()
+    select {
+    case r := <-done:
+        return r.m.(model), r.cmd
+    case <-time.After(time.Second):
+        t.Fatalf("Update blocked on %T", msg)
+        return m, nil
+    }
+}
+
+func key(s string) tea.KeyMsg {
+    switch s {
+    case "enter":
+        return tea.KeyMsg{Type: tea.KeyEnter}
+    case "down":
+        return tea.KeyMsg{Type: tea.KeyDown}
+    }
+    return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// deliver feeds the results of finished commands back into the model.
+func deliver(t *testing.T, m model, msgs []tea.Msg) model {
+    t.Helper()
+    for _, msg := range msgs { m, _ = send(t, m, msg) }
+    return m
+}
+
+func TestSlowLoadKeepsUIResponsive(t *testing.T) {
+    fakes := useFakes(t)
+    m := initialModel()
+    loads := []<-chan []tea.Msg{runAsync(m.initCmd)} // blocks in the fakes' Read
+
+    for _, k := range []string{"down", "2", "1", "k"} {
+        var cmd tea.Cmd
+        m, cmd = send(t, m, key(k))
+        loads = append(loads, runAsync(cmd))
+    }
+    if !m.groups[0].loading { t.Fatal("group not marked loading while its read is in flight") }
+
+    for _, f := range fakes { close(f.readGate) }
+    for _, l := range loads { m = deliver(t, m, await(t, l)) }
+    for _, g := range m.groups {
+        if g.loading || g.err != nil { t.Fatalf("%s: loading=%v err=%v", g.id, g.loading, g.err) }
+    }
+    if got := m.groups[0].rows[0].url; got != "https://claude.example.com" { t.Fatalf("active row url = %q", got) }
+}
+
+func TestApplyRunsOffTheUpdateLoop(t *testing.T) {
+    fakes := useFakes(t)
+    p := core.Preset{Alias: "work", URL: "https://work.example.com", Token: "sk-work"}
+    if err := store.AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+    for _, f := range fakes { close(f.readGate) }
+    m := initialModel()
+    m = deliver(t, m, run(m.initCmd))
+
+    m, _ = send(t, m, key("down"))
+    m, cmd := send(t, m, key("enter"))
+    m = deliver(t, m, run(cmd))
+    if m.m != modeConfirmApply { t.Fatalf("mode = %v, want confirm (status %q)", m.m, m.status) }
+
+    m, cmd = send(t, m, key("y"))
+    applied := runAsync(cmd) // blocks in the fake's Write
+    m, _ = send(t, m, key("down"))
+    if n := fakes[core.AgentClaude].writeCount(); n != 0 { t.Fatalf("write happened inside Update (%d)", n) }
+    if m.status != "applying 'work'…" { t.Fatalf("status = %q", m.status) }
+
+    close(fakes[core.AgentClaude].writeGate)
+    m = deliver(t, m, await(t, applied))
+    if m.status != "applied 'work'" { t.Fatalf("status = %q", m.status) }
+    if f := fakes[core.AgentClaude]; f.writeCount() != 1 || f.fields.URL != p.URL {
+        t.Fatalf("fake got %d writes, fields %+v", f.writeCount(), f.fields)
+    }
+}
+
+func TestUpdateDoesNotMirrorUnreadableConfig(t *testing.T) {
+    fakes := useFakes(t)
+    f := fakes[core.AgentClaude]
+    close(f.readGate)
+    close(f.writeGate)
+    p := core.Preset{Alias: "work", URL: "https://work.example.com", Token: "sk-work"}
+    if err := store.AddPreset(core.AgentClaude, p); err != nil { t.Fatal(err) }
+    f.readErr = errors.New("settings.json: invalid character")
+
+    model := "m2"
+    msgs := run(updatePresetCmd(core.AgentClaude, presetUpdate{old: "work", alias: "work", model: &model, apply: true}))
+    if len(msgs) != 1 { t.Fatalf("msgs = %v", msgs) }
+    done, ok := msgs[0].(opDoneMsg)
+    if !ok || done.err == nil || !strings.Contains(done.err.Error(), "invalid character") { t.Fatalf("msg = %+v", msgs[0]) }
+    if n := f.writeCount(); n != 0 { t.Fatalf("config written %d times after a failed read", n) }
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+)
+
+// pickerRows bounds the model suggestions shown under the form's Model field.
+const pickerRows = 6
+
+// modelChoicesMsg carries the model list offered by the form's picker. fetched
+// marks a list that was just asked from the endpoint.
+type modelChoicesMsg struct {
+    id      core.AgentID
+    ids     []string
+    source  string
+    fetched bool
+    err     error
+}
+
+// modelChoicesCmd loads the cached list of a preset, or the catalog.
+func modelChoicesCmd(id core.AgentID, alias string) tea.Cmd {
+    return func() tea.Msg {
+        ids, source := ops.ModelChoices(id, alias)
+        return modelChoicesMsg{id: id, ids: ids, source: source}
+    }
+}
+
+// fetchModelsCmd asks the preset's endpoint for its models and caches them.
+func fetchModelsCmd(id core.AgentID, alias string) tea.Cmd {
+    return func() tea.Msg {
+        ids, _, err := ops.FetchModels(context.Background(), id, alias)
+        if err != nil {
+            return modelChoicesMsg{id: id, err: fmt.Errorf("fetching models failed: %w", err)}
+        }
+        return modelChoicesMsg{id: id, ids: ids, source: "fetched just now", fetched: true}
+    }
+}
+
+// openPicker resets the picker and loads the choices for a form on alias.
+func (m *model) openPicker(id core.AgentID, alias string) tea.Cmd {
+    m.mdlChoices, m.mdlSource, m.mdlPick, m.mdlQuery = nil, "", -1, ""
+    if !agentSupportsModel(id) { return nil }
+    return modelChoicesCmd(id, alias)
+}
+
+func (m model) onModelChoices(msg modelChoicesMsg) (tea.Model, tea.Cmd) {
+    if (m.m != modeNew && m.m != modeUpdate) || m.groups[m.active].id != msg.id {
+        return m, nil // the form was closed meanwhile
+    }
+    if msg.err != nil {
+        m.formErr = msg.err.Error()
+        return m, nil
+    }
+    m.mdlChoices, m.mdlSource, m.mdlPick = msg.ids, msg.source, -1
+    if msg.fetched { m.formErr = fmt.Sprintf("%d model(s) fetched", len(msg.ids)) }
+    return m, nil
+}
+
+// modelMatches filters the choices by what was typed into the Model field.
+func (m model) modelMatches() []string {
+    var out []string
+    for _, id := range m.mdlChoices {
+        if fuzzyMatch(m.mdlQuery, id) { out = append(out, id) }
+    }
+    return out
+}
+
+// pickerKey handles the picker keys while the Model field has focus: up/down
+// step through the matches, ctrl+r fetches from the endpoint (update form only,
+// since it needs a stored preset). It reports whether the key was consumed.
+func (m *model) pickerKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+    if !m.modelIn.Focused() { return false, nil }
+    switch msg.String() {
+    case "up", "down":
+        ms := m.modelMatches()
+        if len(ms) == 0 { return true, nil }
+        if msg.String() == "down" { m.mdlPick++ } else { m.mdlPick-- }
+        m.mdlPick = (m.mdlPick + len(ms)) % len(ms)
+        m.modelIn.SetValue(ms[m.mdlPick])
+        m.modelIn.CursorEnd()
+        return true, nil
+    case "ctrl+r":
+        if m.m != modeUpdate {
+            m.formErr = "save the preset first to fetch its models"
+            return true, nil
+        }
+        m.formErr = "fetching models…"
+        return true, fetchModelsCmd(m.groups[m.active].id, m.updOldAlias)
+    }
+    return false, nil
+}
+
+// syncModelQuery makes typed text the new filter; a picked value keeps the old one.
+func (m *model) syncModelQuery() {
+    ms := m.modelMatches()
+    if m.mdlPick >= 0 && m.mdlPick < len(ms) && ms[m.mdlPick] == m.modelIn.Value() { return }
+    m.mdlQuery, m.mdlPick = m.modelIn.Value(), -1
+}
+
+// renderPicker lists the matching models under the Model field while it has focus.
+func (m model) renderPicker(indent string) string {
+    if !m.modelIn.Focused() || len(m.mdlChoices) == 0 { return "" }
+    ms := m.modelMatches()
+    // keep the picked entry inside the window
+    start := 0
+    if m.mdlPick >= pickerRows { start = m.mdlPick - pickerRows + 1 }
+    var b strings.Builder
+    for i := start; i < len(ms) && i < start+pickerRows; i++ {
+        if i == m.mdlPick {
+            b.WriteString(indent + styleAliasSel.Render("> "+ms[i]) + "\n")
+        } else {
+            b.WriteString(indent + "  " + ms[i] + "\n")
+        }
+    }
+    hint := fmt.Sprintf("%d of %d, %s; ↑/↓ pick", len(ms), len(m.mdlChoices), m.mdlSource)
+    if m.m == modeUpdate { hint += ", ctrl+r fetch" }
+    b.WriteString(indent + styleMuted.Render(hint) + "\n")
+    return b.String()
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+    "tks/internal/util"
+)
+
+// partialFields are the preset fields a partial apply can pick, in display order.
+var partialFields = []string{"URL", "Token", "Model"}
+
+// presetPatch builds the patch writing the picked fields of p. A preset without
+// a model clears it, as applying the whole preset would.
+func presetPatch(p core.Preset, pick map[string]bool) ops.Patch {
+    var pt ops.Patch
+    if pick["URL"] { u := p.URL; pt.URL = &u }
+    if pick["Token"] { t := p.Token; pt.Token = &t }
+    if pick["Model"] {
+        if p.Model == "" { pt.ClearModel = true } else { md := p.Model; pt.Model = &md }
+    }
+    return pt
+}
+
+// pickedLabel names the preset and the picked fields, e.g. "dev (Model)".
+func pickedLabel(alias string, pick map[string]bool) string {
+    var names []string
+    for _, f := range partialFields {
+        if pick[f] { names = append(names, f) }
+    }
+    return alias + " (" + strings.Join(names, ", ") + ")"
+}
+
+func planPatchCmd(id core.AgentID, p core.Preset, pt ops.Patch, label string) tea.Cmd {
+    return func() tea.Msg {
+        plan, err := ops.PlanPatch(context.Background(), id, pt)
+        if err != nil {
+            return planReadyMsg{id: id, preset: p, err: fmt.Errorf("apply failed: %w", err)}
+        }
+        plan.Alias = label
+        return planReadyMsg{id: id, preset: p, plan: plan, patch: &pt}
+    }
+}
+
+func applyPatchCmd(id core.AgentID, pt ops.Patch, label string) tea.Cmd {
+    return func() tea.Msg {
+        if _, err := ops.ApplyPatch(context.Background(), id, pt, ops.SourceTUI); err != nil {
+            return opDoneMsg{id: id, err: fmt.Errorf("apply failed: %w", err)}
+        }
+        return opDoneMsg{id: id, status: "applied " + label}
+    }
+}
+
+// partialAvailable reports why a field of p cannot be applied alone ("" when it can).
+func partialAvailable(p core.Preset, field string) string {
+    if field == "Token" && p.KeyHelper { return "key helper preset: apply it whole" }
+    return ""
+}
+
+func (m model) updatePartialKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "up", "k":
+        m.partialIdx = (m.partialIdx + len(partialFields) - 1) % len(partialFields)
+    case "down", "j":
+        m.partialIdx = (m.partialIdx + 1) % len(partialFields)
+    case " ", "x":
+        f := partialFields[m.partialIdx]
+        if why := partialAvailable(m.partialPreset, f); why != "" {
+            m.status = why
+            return m, nil
+        }
+        m.partialPick[f] = !m.partialPick[f]
+    case "enter":
+        pt := presetPatch(m.partialPreset, m.partialPick)
+        if pt.Empty() {
+            m.status = "pick at least one field"
+            return m, nil
+        }
+        label := pickedLabel(m.partialPreset.Alias, m.partialPick)
+        m.m = modeTable
+        m.status = "preparing diff for " + label + "…"
+        return m, planPatchCmd(m.groups[m.active].id, m.partialPreset, pt, label)
+    case "esc", "q":
+        m.m = modeTable
+    }
+    return m, nil
+}
+
+func (m model) renderPartial() string {
+    p := m.partialPreset
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("\nApply fields of '%s' (the rest of the config is kept):\n", p.Alias))
+    for i, f := range partialFields {
+        box := "[ ]"
+        if m.partialPick[f] { box = "[x]" }
+        var v string
+        switch f {
+        case "URL":
+            v = core.DisplayURL(p.URL)
+        case "Token":
+            v = util.Mask(p.Token)
+        case "Model":
+            v = p.Model
+            if v == "" { v = "(clear)" }
+        }
+        line := fmt.Sprintf("%s %-6s %s", box, f, v)
+        if why := partialAvailable(p, f); why != "" { line = styleMuted.Render(line + "  (" + why + ")") }
+        cur := "  "
+        if i == m.partialIdx { cur, line = "> ", styleAliasSel.Render(line) }
+        b.WriteString(cur + line + "\n")
+    }
+    return b.String()
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+
+    core "tks/internal/core"
+    "tks/internal/ops"
+    "tks/internal/util"
+)
+
+// rotateCmd replaces the key identified by fp everywhere (see ops.Rotate).
+func rotateCmd(id core.AgentID, fp, tok string, all []core.AgentID) tea.Cmd {
+    return func() tea.Msg {
+        rep, err := ops.Rotate(context.Background(), fp, tok, ops.SourceTUI)
+        if err != nil {
+            return opDoneMsg{id: id, err: err, form: true}
+        }
+        applied := 0
+        var failed []string
+        for _, r := range rep.Presets {
+            if r.Applied { applied++ }
+            if r.Err != nil { failed = append(failed, fmt.Sprintf("%s/%s: %v", r.Agent, r.Alias, r.Err)) }
+        }
+        msg := opDoneMsg{id: id, also: all,
+            status: fmt.Sprintf("rotated %d preset(s), %d re-applied", len(rep.Presets)-len(failed), applied)}
+        if len(failed) > 0 {
+            msg.err = errors.New("rotate failed: " + strings.Join(failed, "; "))
+        }
+        return msg
+    }
+}
+
+// rotateScope counts the loaded presets sharing the key identified by fp.
+func (m model) rotateScope(fp string) (presets, agents int) {
+    for _, g := range m.groups {
+        n := 0
+        for _, p := range g.data.presets {
+            if util.MatchesFingerprint(p.Token, fp) { n++ }
+        }
+        if n > 0 { presets, agents = presets+n, agents+1 }
+    }
+    return presets, agents
+}
+
+func (m model) updateRotateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "enter":
+        tok := strings.TrimSpace(m.rotateIn.Value())
+        if tok == "" {
+            m.formErr = "new key is required"
+            return m, nil
+        }
+        if m.pending { return m, nil }
+        var all []core.AgentID
+        for _, g := range m.groups { all = append(all, g.id) }
+        m.pending, m.formErr = true, ""
+        return m, rotateCmd(m.groups[m.active].id, m.rotateFP, tok, all)
+    case "esc":
+        m.m, m.formErr = modeTable, ""
+        return m, nil
+    }
+    var cmd tea.Cmd
+    m.rotateIn, cmd = m.rotateIn.Update(msg)
+    return m, cmd
+}
+
+func (m model) renderRotate() string {
+    var b strings.Builder
+    n, agents := m.rotateScope(m.rotateFP)
+    b.WriteString("\nRotate Key:\n")
+    b.WriteString(fmt.Sprintf("Old: sha:%s  used by %d preset(s) on %d agent(s); active ones are re-applied\n", m.rotateFP, n, agents))
+    b.WriteString("New: " + m.rotateIn.View() + "\n")
+    if m.formErr != "" {
+        b.WriteString(styleStatusErr.Render(m.formErr) + "\n")
+    }
+    return b.String()
+}
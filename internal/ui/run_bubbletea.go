@@ -1,10 +1,8 @@
 package ui
 
 import (
-    "context"
     "fmt"
-    "regexp"
-    "sort"
+    "path/filepath"
     "strings"
     "time"
 
@@ -13,8 +11,8 @@ import (
     "github.com/charmbracelet/lipgloss"
 
     core "tks/internal/core"
+    "tks/internal/ops"
     verinfo "tks/internal/version"
-    "tks/internal/providers"
     "tks/internal/store"
     "tks/internal/util"
 )
@@ -32,7 +30,25 @@ type row struct {
     url   string
     token string
     model string
-    added string // create time (AddedAt) for presets; empty for active
+    meta  core.Preset // stored preset behind the row (tags, notes, expiry, usage); zero when unmanaged
+    extra core.Fields // the rest of what the row describes: Gemini auth, custom headers
+    // drift (active row only): nearest preset and the fields where disk differs from it
+    near  string
+    diffs []string
+}
+
+// urlLabel is the URL to display: "(official)" for a preset or configured
+// active row without a base URL override, empty when nothing is configured.
+func (r row) urlLabel() string {
+    if r.url == "" && (r.kind == rowPreset || r.alias != "" || r.token != "") { return core.OfficialLabel }
+    return r.url
+}
+
+func (r row) differs(field string) bool {
+    for _, d := range r.diffs {
+        if d == field { return true }
+    }
+    return false
 }
 
 type group struct {
@@ -41,6 +57,14 @@ type group struct {
     index int
     ver   string
     inst  bool
+    eff   core.Effective // resolved values after env/project overrides
+    data  groupData      // last successful load, kept so rows can be re-sorted
+    // async loading: gen tags the latest load request; err is the last load failure
+    loading bool
+    err     error
+    gen     int
+    focus   string // alias to select once the pending load lands
+    offset  int    // first visible row (by position among filtered rows) in the viewport
 }
 
 type mode int
@@ -51,6 +75,12 @@ const (
     modeConfirmDel
     modeRename
     modeUpdate
+    modeConfirmApply
+    modeFilter   // typing into the / filter
+    modeOverview // one line per agent
+    modeCopy     // picking the agent to copy a preset to
+    modeRotate   // entering a new key for every preset sharing the selected one
+    modePartial  // picking which fields of a preset to apply
 )
 
 type model struct {
@@ -63,8 +93,19 @@ type model struct {
     urlIn   textinput.Model
     tokIn   textinput.Model
     modelIn textinput.Model
+    tagsIn  textinput.Model
+    notesIn textinput.Model
+    expIn   textinput.Model
+    hdrIn   textinput.Model
     formErr string
 
+    // model picker under the form's Model field: choices, their source, the
+    // typed filter and the picked match (-1: none)
+    mdlChoices []string
+    mdlSource  string
+    mdlQuery   string
+    mdlPick    int
+
     status string
     width  int
     height int
@@ -81,6 +122,38 @@ type model struct {
 
     // update state
     updOldAlias string
+
+    // copy-to-agent state
+    copyAlias string
+
+    // key rotation: fingerprint of the key being replaced and the new key
+    rotateFP string
+    rotateIn textinput.Model
+
+    // fuzzy filter over alias, URL host, model and tags; applies while non-empty
+    filterIn textinput.Model
+    // preset order within groups, one of core.SortKeys
+    sortKey string
+
+    // apply confirmation: the previewed preset and whether file diffs are shown
+    plan       ops.Plan
+    planPreset core.Preset
+    planPatch  *ops.Patch // set when the preview is of a partial apply
+    showFiles  bool
+
+    // partial apply: the preset, the picked fields and the cursor
+    partialPreset core.Preset
+    partialPick   map[string]bool
+    partialIdx    int
+
+    // pending is set while a form's write is in flight; initCmd loads the first data
+    pending bool
+    initCmd tea.Cmd
+
+    // live reload: file watcher and transient notice
+    watch     *watcher
+    notice    string
+    noticeSeq int
 }
 
 type verState struct {
@@ -98,6 +171,7 @@ var (
     styleKey        = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
     styleStatusOK   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
     styleStatusErr  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+    styleNotice     = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
 )
 
 func initialModel() model {
@@ -107,70 +181,126 @@ func initialModel() model {
     m.urlIn = textinput.New()
     m.tokIn = textinput.New()
     m.modelIn = textinput.New()
-    m.urlIn.Placeholder = "https://..."
+    m.urlIn.Placeholder = "https://... (empty: official endpoint)"
     m.aliasIn.Placeholder = "(optional)"
     m.tokIn.Placeholder = "(optional)"
     // Model input is optional; show a gentle placeholder for clarity
     m.modelIn.Placeholder = "(optional)"
+    m.tagsIn = textinput.New()
+    m.tagsIn.Placeholder = "(optional) tag1,tag2"
+    m.notesIn = textinput.New()
+    m.notesIn.Placeholder = "(optional)"
+    m.expIn = textinput.New()
+    m.expIn.Placeholder = "(optional) 2006-01-02"
+    m.hdrIn = textinput.New()
+    m.hdrIn.Placeholder = "(optional) X-Team-Id: a; X-Cost-Center: b"
     m.urlIn.Focus()
     m.verCache = map[core.AgentID]verState{}
     m.renameIn = textinput.New()
     m.renameIn.Placeholder = "new-alias"
-    m.reloadAll()
+    m.rotateIn = textinput.New()
+    m.rotateIn.Placeholder = "new key"
+    m.rotateIn.EchoMode = textinput.EchoPassword
+    m.filterIn = textinput.New()
+    m.filterIn.Prompt = "/"
+    m.filterIn.Placeholder = "alias, host, model or tag"
+    // groups start as placeholders; their data arrives from loadGroupCmd
+    m.setAgents(core.BaseAgents)
+    m.initCmd = tea.Batch(loadAgentsCmd, m.reloadGroups())
+    m.status = "Loading…"
     return m
 }
 
 const verTTL = 60 * time.Second
 
-func (m *model) reloadAll() {
+func (m *model) newGroup(id core.AgentID) group {
+    g := group{id: id, rows: []row{{kind: rowCurrent}}, loading: true}
+    // version: prefer cached within TTL; otherwise show loading placeholder
+    if st, ok := m.verCache[id]; ok && time.Since(st.at) < verTTL {
+        g.ver, g.inst = st.text, st.installed
+    } else {
+        g.ver, g.inst = "…", true
+    }
+    return g
+}
+
+// setAgents replaces the group list, keeping loaded state and the selection for
+// agents that remain. It returns the loads for groups that are new.
+func (m *model) setAgents(ids []core.AgentID) tea.Cmd {
+    prevActive := core.AgentID("")
+    if m.active < len(m.groups) { prevActive = m.groups[m.active].id }
+    old := map[core.AgentID]group{}
+    for _, g := range m.groups { old[g.id] = g }
     m.groups = nil
-    ids := []core.AgentID{core.AgentClaude, core.AgentGemini, core.AgentCodex}
+    var fresh []core.AgentID
     for _, id := range ids {
-        g := group{id: id}
-        var f core.Fields
-        if prov := providers.NewProvider(id); prov != nil {
-            f, _ = prov.Read(context.Background())
-        }
-        // load presets and detect active preset by value
-        ps, _ := store.LoadPresets(id)
-        sort.Slice(ps, func(i, j int) bool { return ps[i].Alias < ps[j].Alias })
-        activeAlias := ""
-        activeAdded := ""
-        filtered := make([]storePreset, 0, len(ps))
-        for _, p := range ps {
-            if p.URL == f.URL && p.Token == f.Token && p.Model == f.Model {
-                activeAlias = p.Alias
-                activeAdded = p.AddedAt
-                continue // do not duplicate in list
-            }
-            // include Model as well so details reflect latest preset content
-            filtered = append(filtered, storePreset{
-                Alias: p.Alias, URL: p.URL, Token: p.Token, Model: p.Model, AddedAt: p.AddedAt,
-            })
-        }
-        // version: prefer cached within TTL; otherwise show loading placeholder
-        if st, ok := m.verCache[id]; ok && time.Since(st.at) < verTTL {
-            g.ver = st.text
-            g.inst = st.installed
-        } else {
-            g.ver = "…"
-            g.inst = true
+        if g, ok := old[id]; ok {
+            m.groups = append(m.groups, g)
+            continue
         }
-        // active row at top
-        g.rows = append(g.rows, row{kind: rowCurrent, alias: activeAlias, url: f.URL, token: f.Token, model: f.Model, added: activeAdded})
-        // preset rows
-        for _, p := range filtered {
-            g.rows = append(g.rows, row{kind: rowPreset, alias: p.Alias, url: p.URL, token: p.Token, model: p.Model, added: p.AddedAt})
-        }
-        m.groups = append(m.groups, g)
+        m.groups = append(m.groups, m.newGroup(id))
+        fresh = append(fresh, id)
     }
     m.active = 0
-    m.status = "Loaded"
+    for i, g := range m.groups {
+        if g.id == prevActive { m.active = i }
+    }
+    if len(fresh) == 0 { return nil } // reloadGroups() with no ids would reload them all
+    return m.reloadGroups(fresh...)
 }
 
-func (m model) Init() tea.Cmd { return m.scheduleVersionCmds() }
+func (m *model) groupIndex(id core.AgentID) int {
+    for i, g := range m.groups {
+        if g.id == id { return i }
+    }
+    return -1
+}
+
+// reloadGroups starts loads for the given agents, or for every group when none are given.
+func (m *model) reloadGroups(ids ...core.AgentID) tea.Cmd {
+    if len(ids) == 0 && len(m.groups) > 0 {
+        for _, g := range m.groups { ids = append(ids, g.id) }
+    }
+    var cmds []tea.Cmd
+    for _, id := range ids {
+        i := m.groupIndex(id)
+        if i < 0 { continue }
+        g := &m.groups[i]
+        g.gen++
+        g.loading = true
+        cmds = append(cmds, loadGroupCmd(id, g.gen))
+    }
+    return tea.Batch(cmds...)
+}
+
+func (m *model) loading() bool {
+    for _, g := range m.groups {
+        if g.loading { return true }
+    }
+    return false
+}
+
+func (m model) Init() tea.Cmd {
+    return tea.Batch(m.initCmd, m.scheduleVersionCmds(), m.watch.next())
+}
+
+// noticeTTL is how long the "changed externally" notice stays in the top bar.
+const noticeTTL = 4 * time.Second
+
+type clearNoticeMsg struct{ seq int }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+    next, cmd := m.update(msg)
+    if nm, ok := next.(model); ok {
+        // keep selections inside the filtered set and the scrolled viewports
+        if len(nm.groups) > 0 { nm.refilter() }
+        nm.syncViewports()
+        next = nm
+    }
+    return next, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
     switch msg := msg.(type) {
     case tea.WindowSizeMsg:
         m.width, m.height = msg.Width, msg.Height
@@ -187,7 +317,101 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
             return m.updateRenameKey(msg)
         case modeUpdate:
             return m.updateUpdateKey(msg)
+        case modeConfirmApply:
+            return m.updateConfirmApplyKey(msg)
+        case modeFilter:
+            return m.updateFilterKey(msg)
+        case modeOverview:
+            return m.updateOverviewKey(msg)
+        case modeCopy:
+            return m.updateCopyKey(msg)
+        case modeRotate:
+            return m.updateRotateKey(msg)
+        case modePartial:
+            return m.updatePartialKey(msg)
+        }
+    case groupLoadedMsg:
+        i := m.groupIndex(msg.id)
+        if i < 0 || m.groups[i].gen != msg.gen {
+            return m, nil // group gone or a newer load is in flight
+        }
+        g := &m.groups[i]
+        g.loading, g.err = false, msg.err
+        if msg.err == nil {
+            // keep the cursor in place (live reload must not jump it) unless an op asked for a row
+            g.data, g.rows, g.eff = msg.data, buildRows(msg.data, m.sortKey), msg.data.eff
+        }
+        if g.focus != "" {
+            for j, r := range g.rows {
+                if r.alias == g.focus { g.index = j; break }
+            }
+            g.focus = ""
+        }
+        if g.index >= len(g.rows) { g.index = len(g.rows) - 1 }
+        if m.status == "Loading…" && !m.loading() { m.status = "Loaded" }
+        return m, nil
+    case planReadyMsg:
+        if msg.err != nil {
+            m.status = msg.err.Error()
+            return m, nil
         }
+        if msg.trusted && msg.patch == nil {
+            m.status = "applying trusted preset '" + msg.preset.Alias + "'…"
+            return m, applyPresetCmd(msg.id, msg.preset)
+        }
+        if m.m != modeTable {
+            return m, nil // a form was opened while the preview was loading
+        }
+        m.m, m.plan, m.planPreset, m.planPatch, m.showFiles = modeConfirmApply, msg.plan, msg.preset, msg.patch, false
+        m.status = "confirm apply '" + msg.plan.Alias + "'"
+        if st, desc := msg.preset.Expiry(time.Now()); st == core.ExpirySoon || st == core.ExpiryPast {
+            m.status += " — key " + desc
+        }
+        return m, nil
+    case modelChoicesMsg:
+        return m.onModelChoices(msg)
+    case agentsLoadedMsg:
+        return m, tea.Batch(m.setAgents(msg.ids), m.scheduleVersionCmds())
+    case opDoneMsg:
+        // results of writes arrive here; only the affected agent is re-read
+        fromForm := m.pending
+        m.pending = false
+        if msg.err != nil && msg.form && fromForm {
+            m.formErr = msg.err.Error()
+            return m, nil
+        }
+        if msg.err != nil {
+            m.status = msg.err.Error()
+        } else {
+            m.status = msg.status
+        }
+        if fromForm {
+            m.m, m.formErr, m.delAlias = modeTable, "", ""
+        }
+        if i := m.groupIndex(msg.id); i >= 0 && msg.focus != "" {
+            m.groups[i].focus = msg.focus
+        }
+        return m, m.reloadGroups(append([]core.AgentID{msg.id}, msg.also...)...)
+    case fsChangedMsg:
+        ids, all := m.watch.affected(msg.paths)
+        cmds := []tea.Cmd{m.watch.syncCmd(), m.watch.next()}
+        if all {
+            cmds = append(cmds, loadAgentsCmd, m.reloadGroups())
+        } else if len(ids) > 0 {
+            cmds = append(cmds, m.reloadGroups(ids...))
+        }
+        if len(msg.external) > 0 {
+            names := make([]string, 0, len(msg.external))
+            for _, p := range msg.external { names = append(names, filepath.Base(p)) }
+            m.noticeSeq++
+            m.notice = "changed externally: " + strings.Join(names, ", ")
+            seq := m.noticeSeq
+            cmds = append(cmds, tea.Tick(noticeTTL, func(time.Time) tea.Msg { return clearNoticeMsg{seq: seq} }))
+        }
+        return m, tea.Batch(cmds...)
+    case clearNoticeMsg:
+        if msg.seq == m.noticeSeq { m.notice = "" }
+        return m, nil
     case verMsg:
         // async version backfill
         m.verCache[msg.id] = verState{text: msg.text, installed: msg.installed, at: msg.at}
@@ -206,100 +430,103 @@ func (m model) updateTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
     g := &m.groups[m.active]
     switch msg.String() {
     case "up", "k":
-        if g.index > 0 { g.index-- }
+        m.moveSelection(-1)
     case "down", "j":
-        if g.index < len(g.rows)-1 { g.index++ }
-    case "1", "2", "3":
+        m.moveSelection(1)
+    case "pgup":
+        m.moveSelection(-m.viewportRows(m.active))
+    case "pgdown":
+        m.moveSelection(m.viewportRows(m.active))
+    case "/":
+        m.m = modeFilter
+        m.filterIn.CursorEnd()
+        return m, m.filterIn.Focus()
+    case "o":
+        m.m = modeOverview
+    case "c":
+        sel := g.rows[g.index]
+        if sel.alias == "" {
+            m.status = "no preset to copy"
+            return m, nil
+        }
+        if len(m.groups) < 2 {
+            m.status = "no other agent to copy to"
+            return m, nil
+        }
+        m.m = modeCopy
+        m.copyAlias = sel.alias
+    case "R":
+        sel := g.rows[g.index]
+        if sel.token == "" {
+            m.status = "no key to rotate"
+            return m, nil
+        }
+        if util.IsSecretRef(sel.token) {
+            m.status = "token is a reference; rotate it at " + sel.token
+            return m, nil
+        }
+        m.m, m.formErr = modeRotate, ""
+        m.rotateFP = util.Fingerprint(sel.token)
+        m.rotateIn.SetValue("")
+        return m, m.rotateIn.Focus()
+    case "1", "2", "3", "4", "5", "6", "7", "8", "9":
         m.active = int(msg.Runes[0]-'1')
         if m.active < 0 || m.active >= len(m.groups) { m.active = 0 }
         m.groups[m.active].index = 0
     case "r":
-        m.reloadAll()
-        return m, m.scheduleVersionCmds()
+        m.status = "Loading…"
+        return m, tea.Batch(loadAgentsCmd, m.reloadGroups(), m.scheduleVersionCmds())
     case "enter":
         sel := g.rows[g.index]
         if sel.kind == rowPreset {
-            // apply preset
-            if prov := providers.NewProvider(g.id); prov != nil {
-                old, _ := prov.Read(context.Background())
-                fields := core.Fields{URL: sel.url, Token: sel.token}
-                ctx := context.Background()
-                // Strictly mirror model for all agents
-                if sel.model == "" {
-                    switch g.id {
-                    case core.AgentClaude:
-                        ctx = context.WithValue(ctx, providers.CtxKeyClaudeClearModel, true)
-                    case core.AgentGemini:
-                        ctx = context.WithValue(ctx, providers.CtxKeyGeminiClearModel, true)
-                    case core.AgentCodex:
-                        ctx = context.WithValue(ctx, providers.CtxKeyCodexClearModel, true)
-                    }
-                } else {
-                    fields.Model = sel.model
-                }
-                diff := core.Diff(old, fields)
-                if _, err := prov.Write(ctx, fields); err != nil {
-                    m.status = fmt.Sprintf("apply failed: %v", err)
-                } else {
-                    m.status = "applied"
-                }
-                _ = diff // reserved: show in detail in future
-                // refresh current row
-                m.reloadAll()
-                return m, m.scheduleVersionCmds()
-            }
+            // preview first; the confirmation mode shows what the write changes
+            m.status = "preparing diff for '" + sel.alias + "'…"
+            return m, planPresetCmd(g.id, sel.meta)
         } else {
             m.status = "cannot apply active row"
         }
+    case "P":
+        sel := g.rows[g.index]
+        if sel.kind != rowPreset {
+            m.status = "pick a preset row to apply fields from"
+            return m, nil
+        }
+        m.m, m.partialPreset, m.partialIdx = modePartial, sel.meta, 0
+        m.partialPick = map[string]bool{}
     case "a":
         m.m = modeNew
         m.formErr = ""
         m.aliasIn.SetValue("")
         m.urlIn.SetValue("")
         m.tokIn.SetValue("")
-        m.urlIn.Focus(); m.aliasIn.Blur(); m.tokIn.Blur()
+        m.modelIn.SetValue("")
+        m.resetForm()
+        return m, m.openPicker(g.id, "")
+    case "s":
+        // cycle the preset order; the selection follows its alias
+        for i, k := range core.SortKeys {
+            if k == m.sortKey || (m.sortKey == "" && k == "alias") {
+                m.sortKey = core.SortKeys[(i+1)%len(core.SortKeys)]
+                break
+            }
+        }
+        for i := range m.groups {
+            gi := &m.groups[i]
+            if gi.loading && len(gi.data.presets) == 0 { continue }
+            alias := gi.rows[gi.index].alias
+            gi.rows = buildRows(gi.data, m.sortKey)
+            for j, r := range gi.rows {
+                if r.alias == alias { gi.index = j; break }
+            }
+        }
+        m.status = "sorted by " + m.sortKey
     case "p":
         // Show presets directory in status
         m.status = "Presets dir: " + store.PresetsDir()
     case "i":
         // Init from current disk config -> quick add preset for active agent
-        prov := providers.NewProvider(g.id)
-        if prov == nil {
-            m.status = "init failed: provider not available"
-            return m, nil
-        }
-        cur, err := prov.Read(context.Background())
-        if err != nil {
-            m.status = "init failed: " + err.Error()
-            return m, nil
-        }
-        // migrate old presets on init: backfill missing model for Gemini/Codex; stamp config_version
-        _ = store.MigrateOnInit(g.id, cur.Model)
-        if err := core.ValidateFields(cur); err != nil {
-            m.status = "skip: current config invalid"
-            return m, nil
-        }
-        // duplicate by value
-        ps, _ := store.LoadPresets(g.id)
-        for _, p := range ps {
-            if p.URL == cur.URL && p.Token == cur.Token && p.Model == cur.Model {
-                m.status = "identical preset exists: " + p.Alias
-                return m, nil
-            }
-        }
-        // alias selection: snap-default or timestamped if exists
-        alias := "snap-default"
-        if _, err := store.GetPreset(g.id, alias); err == nil {
-            alias = alias + "-" + time.Now().Format("20060102-1504")
-        }
-        pr := core.Preset{Alias: alias, URL: cur.URL, Token: cur.Token, Model: cur.Model, AddedAt: time.Now().Format("20060102-1504")}
-        if err := store.AddPreset(g.id, pr); err != nil {
-            m.status = "init failed: " + err.Error()
-            return m, nil
-        }
-        m.status = "added preset '" + alias + "'"
-        m.reloadAll()
-        return m, m.scheduleVersionCmds()
+        m.status = "snapshotting current config…"
+        return m, initPresetCmd(g.id)
     case "d":
         sel := g.rows[g.index]
         if sel.kind == rowCurrent {
@@ -343,36 +570,28 @@ func (m model) updateTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
         m.urlIn.SetValue(sel.url)
         m.tokIn.SetValue("")
         m.modelIn.SetValue(sel.model)
-        m.urlIn.Focus(); m.aliasIn.Blur(); m.tokIn.Blur(); m.modelIn.Blur()
-    case "q", "esc", "ctrl+c":
+        m.resetForm()
+        m.tagsIn.SetValue(strings.Join(sel.meta.Tags, ","))
+        m.notesIn.SetValue(sel.meta.Notes)
+        m.expIn.SetValue(sel.meta.ExpiresAt)
+        return m, m.openPicker(g.id, targetAlias)
+    case "esc":
+        if m.filterIn.Value() != "" {
+            m.filterIn.SetValue("")
+            return m, nil
+        }
+        return m, tea.Quit
+    case "q", "ctrl+c":
         return m, tea.Quit
     }
     return m, nil
 }
 
 func (m model) updateNewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    if ok, cmd := m.pickerKey(msg); ok { return m, cmd }
     switch msg.String() {
     case "tab":
-        g := &m.groups[m.active]
-        if agentSupportsModel(g.id) {
-            if m.urlIn.Focused() {
-                m.aliasIn.Focus(); m.urlIn.Blur()
-            } else if m.aliasIn.Focused() {
-                m.tokIn.Focus(); m.aliasIn.Blur()
-            } else if m.tokIn.Focused() {
-                m.modelIn.Focus(); m.tokIn.Blur()
-            } else {
-                m.urlIn.Focus(); m.modelIn.Blur()
-            }
-        } else {
-            if m.urlIn.Focused() {
-                m.aliasIn.Focus(); m.urlIn.Blur()
-            } else if m.aliasIn.Focused() {
-                m.tokIn.Focus(); m.aliasIn.Blur()
-            } else {
-                m.urlIn.Focus(); m.tokIn.Blur()
-            }
-        }
+        m.focusNext()
     case "enter":
         // validate and add
         alias := strings.TrimSpace(m.aliasIn.Value())
@@ -382,50 +601,77 @@ func (m model) updateNewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
         if err := core.ValidateFields(core.Fields{URL: url, Token: tok}); err != nil {
             m.formErr = err.Error(); return m, nil
         }
-        g := &m.groups[m.active]
-        // duplicate by value (include model)
-        ps, _ := store.LoadPresets(g.id)
-        for _, p := range ps { if p.URL==url && p.Token==tok && p.Model==strings.TrimSpace(model) { m.formErr = "preset with same values exists: "+p.Alias; return m, nil } }
-        if alias == "" { alias = time.Now().Format("20060102-1504") }
-        // duplicate alias
-        if _, err := store.GetPreset(g.id, alias); err == nil { m.formErr = "alias exists"; return m, nil }
-        pr := core.Preset{Alias: alias, URL: url, Token: tok, AddedAt: time.Now().Format("20060102-1504")}
-        if agentSupportsModel(g.id) && strings.TrimSpace(model) != "" { pr.Model = strings.TrimSpace(model) }
-        if err := store.AddPreset(g.id, pr); err != nil {
+        exp := strings.TrimSpace(m.expIn.Value())
+        if _, ok := core.ParseTime(exp); exp != "" && !ok {
+            m.formErr = "invalid expiry (use 2006-01-02 or RFC 3339)"; return m, nil
+        }
+        hdr, err := core.ParseHeaders(m.hdrIn.Value())
+        if err != nil {
             m.formErr = err.Error(); return m, nil
         }
-        m.m = modeTable
-        m.status = "added"
-        m.reloadAll()
-        // focus new row
-        gg := &m.groups[m.active]
-        for i, r := range gg.rows { if r.kind==rowPreset && r.alias==alias { gg.index=i; break } }
+        if m.pending { return m, nil }
+        g := &m.groups[m.active]
+        pr := core.Preset{
+            Alias: alias, URL: url, Token: tok, AddedAt: core.Timestamp(time.Now()),
+            Tags: core.ParseTags(m.tagsIn.Value()), Notes: strings.TrimSpace(m.notesIn.Value()), ExpiresAt: core.NormalizeTime(exp),
+        }
+        if agentSupportsModel(g.id) && strings.TrimSpace(model) != "" { pr.Model = strings.TrimSpace(model) }
+        if agentSupportsHeaders(g.id) { pr.Headers = hdr }
+        m.pending, m.formErr = true, ""
+        return m, addPresetCmd(g.id, pr)
     case "esc", "q":
         m.m = modeTable
     default:
-        // delegate inputs
-        var cmd tea.Cmd
-        if m.urlIn.Focused() { m.urlIn, cmd = m.urlIn.Update(msg); return m, cmd }
-        if m.aliasIn.Focused() { m.aliasIn, cmd = m.aliasIn.Update(msg); return m, cmd }
-        if m.tokIn.Focused() { m.tokIn, cmd = m.tokIn.Update(msg); return m, cmd }
-        if m.modelIn.Focused() { m.modelIn, cmd = m.modelIn.Update(msg); return m, cmd }
+        cmd := m.updateFocusedInput(msg)
+        m.syncModelQuery()
+        return m, cmd
     }
     return m, nil
 }
 
+// formInputs lists the add/update form inputs in tab order.
+func (m *model) formInputs() []*textinput.Model {
+    in := []*textinput.Model{&m.urlIn, &m.aliasIn, &m.tokIn}
+    if agentSupportsModel(m.groups[m.active].id) { in = append(in, &m.modelIn) }
+    if agentSupportsHeaders(m.groups[m.active].id) { in = append(in, &m.hdrIn) }
+    return append(in, &m.tagsIn, &m.notesIn, &m.expIn)
+}
+
+// focusNext moves form focus to the next input, wrapping around.
+func (m *model) focusNext() {
+    in := m.formInputs()
+    cur := len(in) - 1
+    for i, x := range in {
+        if x.Focused() { cur = i }
+        x.Blur()
+    }
+    in[(cur+1)%len(in)].Focus()
+}
+
+// resetForm clears the metadata inputs and focuses URL, the first form field.
+func (m *model) resetForm() {
+    for _, x := range m.formInputs() { x.Blur() }
+    m.tagsIn.SetValue(""); m.notesIn.SetValue(""); m.expIn.SetValue(""); m.hdrIn.SetValue("")
+    m.urlIn.Focus()
+}
+
+func (m *model) updateFocusedInput(msg tea.KeyMsg) tea.Cmd {
+    for _, x := range m.formInputs() {
+        if x.Focused() {
+            var cmd tea.Cmd
+            *x, cmd = x.Update(msg)
+            return cmd
+        }
+    }
+    return nil
+}
+
 func (m model) updateConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
     switch msg.String() {
     case "y", "Y":
-        g := m.groups[m.active]
-        if err := store.RemovePreset(g.id, m.delAlias); err != nil {
-            m.status = "delete failed: " + err.Error()
-        } else {
-            m.status = "deleted"
-            m.reloadAll()
-            return m, m.scheduleVersionCmds()
-        }
-        m.m = modeTable
-        m.delAlias = ""
+        if m.pending { return m, nil }
+        m.pending = true
+        return m, removePresetCmd(m.groups[m.active].id, m.delAlias)
     case "n", "esc", "q":
         m.m = modeTable
         m.delAlias = ""
@@ -433,7 +679,29 @@ func (m model) updateConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
     return m, nil
 }
 
-var aliasRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+func (m model) updateConfirmApplyKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    switch msg.String() {
+    case "y", "Y":
+        m.m = modeTable
+        if m.planPatch != nil {
+            m.status = "applying " + m.plan.Alias + "…"
+            return m, applyPatchCmd(m.plan.Agent, *m.planPatch, m.plan.Alias)
+        }
+        m.status = "applying '" + m.planPreset.Alias + "'…"
+        return m, applyPresetCmd(m.plan.Agent, m.planPreset)
+    case "t":
+        if m.planPatch != nil { return m, nil } // trust covers whole presets only
+        m.m = modeTable
+        m.status = "trusting and applying '" + m.planPreset.Alias + "'…"
+        return m, trustApplyCmd(m.plan.Agent, m.planPreset)
+    case "f":
+        m.showFiles = !m.showFiles
+    case "n", "esc", "q":
+        m.m = modeTable
+        m.status = "apply cancelled"
+    }
+    return m, nil
+}
 
 func (m model) updateRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
     switch msg.String() {
@@ -446,21 +714,13 @@ func (m model) updateRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
             m.m = modeTable
             return m, nil
         }
-        if !aliasRe.MatchString(newA) {
+        if core.ValidateAlias(newA) != nil {
             m.formErr = "invalid alias (allowed: A-Za-z0-9_- , len 1-32)"
             return m, nil
         }
-        if err := store.RenamePreset(g.id, old, newA); err != nil {
-            m.formErr = err.Error()
-            return m, nil
-        }
-        m.status = fmt.Sprintf("renamed '%s' -> '%s'", old, newA)
-        m.m = modeTable
-        m.reloadAll()
-        // focus new alias
-        gg := &m.groups[m.active]
-        for i, r := range gg.rows { if r.kind==rowPreset && r.alias==newA { gg.index=i; break } }
-        return m, nil
+        if m.pending { return m, nil }
+        m.pending, m.formErr = true, ""
+        return m, renamePresetCmd(g.id, old, newA)
     case "esc", "q":
         m.m = modeTable
         m.formErr = ""
@@ -473,38 +733,19 @@ func (m model) updateRenameKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateUpdateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+    if ok, cmd := m.pickerKey(msg); ok { return m, cmd }
     switch msg.String() {
     case "tab":
-        g := &m.groups[m.active]
-        if agentSupportsModel(g.id) {
-            if m.urlIn.Focused() {
-                m.aliasIn.Focus(); m.urlIn.Blur()
-            } else if m.aliasIn.Focused() {
-                m.tokIn.Focus(); m.aliasIn.Blur()
-            } else if m.tokIn.Focused() {
-                m.modelIn.Focus(); m.tokIn.Blur()
-            } else {
-                m.urlIn.Focus(); m.modelIn.Blur()
-            }
-            return m, nil
-        }
-        // non-Claude: cycle url -> alias -> token -> url
-        if m.urlIn.Focused() {
-            m.aliasIn.Focus(); m.urlIn.Blur()
-        } else if m.aliasIn.Focused() {
-            m.tokIn.Focus(); m.aliasIn.Blur()
-        } else {
-            m.urlIn.Focus(); m.tokIn.Blur()
-        }
+        m.focusNext()
         return m, nil
     case "enter":
         g := m.groups[m.active]
         old := m.updOldAlias
         newAlias := strings.TrimSpace(m.aliasIn.Value())
-        // URL tri-state: blank -> unchanged; '-' -> unchanged; filled -> change
+        // URL tri-state: blank -> unchanged; '-' -> unchanged; "official" -> no override; filled -> change
         var urlPtr *string
         urlVal := strings.TrimSpace(m.urlIn.Value())
-        if urlVal != "" && urlVal != "-" { urlPtr = &urlVal }
+        if urlVal == "official" { urlVal = ""; urlPtr = &urlVal } else if urlVal != "" && urlVal != "-" { urlPtr = &urlVal }
         // Token tri-state: blank -> unchanged; '-' -> clear; filled -> change
         var tokPtr *string
         tokVal := strings.TrimSpace(m.tokIn.Value())
@@ -517,67 +758,62 @@ func (m model) updateUpdateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
         if mdlVal == "" { mdlClear = true } else { mdlPtr = &mdlVal }
         // alias validation (allow unchanged)
         if newAlias == "" { newAlias = old }
-        if newAlias != old && !aliasRe.MatchString(newAlias) {
+        if newAlias != old && core.ValidateAlias(newAlias) != nil {
             m.formErr = "invalid alias (allowed: A-Za-z0-9_- , len 1-32)"
             return m, nil
         }
-        if err := store.UpdatePreset(g.id, old, newAlias, urlPtr, tokPtr, mdlPtr, tokClear, mdlClear); err != nil {
-            m.formErr = err.Error()
+        // metadata is always rewritten from the form; an empty expiry clears it
+        tags := core.ParseTags(m.tagsIn.Value())
+        notes := strings.TrimSpace(m.notesIn.Value())
+        exp := strings.TrimSpace(m.expIn.Value())
+        if _, ok := core.ParseTime(exp); exp != "" && !ok {
+            m.formErr = "invalid expiry (use 2006-01-02 or RFC 3339)"
             return m, nil
         }
-        // Apply if updating active row
-        applyNow := (g.rows[g.index].kind == rowCurrent)
-        if applyNow {
-            if prov := providers.NewProvider(g.id); prov != nil {
-                ctx := context.Background()
-                cur, _ := prov.Read(ctx)
-                if urlPtr != nil { cur.URL = *urlPtr }
-                if tokPtr != nil { cur.Token = *tokPtr }
-                if mdlClear {
-                    switch g.id {
-                    case core.AgentClaude:
-                        ctx = context.WithValue(ctx, providers.CtxKeyClaudeClearModel, true)
-                    case core.AgentGemini:
-                        ctx = context.WithValue(ctx, providers.CtxKeyGeminiClearModel, true)
-                    case core.AgentCodex:
-                        ctx = context.WithValue(ctx, providers.CtxKeyCodexClearModel, true)
-                    }
-                }
-                if mdlPtr != nil { cur.Model = *mdlPtr }
-                if _, err := prov.Write(ctx, cur); err != nil {
-                    m.status = "update failed to apply: " + err.Error()
-                } else {
-                    m.status = "updated & applied '" + newAlias + "'"
-                }
+        // Headers tri-state like Token (values may be secrets): blank -> unchanged; '-' -> clear; filled -> replace
+        meta := store.PresetMeta{Tags: &tags, Notes: &notes, ExpiresAt: &exp}
+        if hv := strings.TrimSpace(m.hdrIn.Value()); hv == "-" {
+            meta.Headers = &core.Headers{}
+        } else if hv != "" {
+            h, err := core.ParseHeaders(hv)
+            if err != nil {
+                m.formErr = err.Error()
+                return m, nil
             }
-        } else {
-            m.status = "updated '" + newAlias + "'"
+            meta.Headers = &h
         }
-        m.m = modeTable
-        m.reloadAll()
-        // focus updated alias if present among presets
-        gg := &m.groups[m.active]
-        for i, r := range gg.rows { if r.kind==rowPreset && r.alias==newAlias { gg.index=i; break } }
-        return m, m.scheduleVersionCmds()
+        if m.pending { return m, nil }
+        m.pending, m.formErr = true, ""
+        // updating the active row also writes the change to disk
+        return m, updatePresetCmd(g.id, presetUpdate{
+            old: old, alias: newAlias, url: urlPtr, token: tokPtr, model: mdlPtr,
+            clearTok: tokClear, clearMdl: mdlClear, apply: g.rows[g.index].kind == rowCurrent,
+            meta: meta,
+        })
     case "esc", "q":
         m.m = modeTable
         m.formErr = ""
         return m, nil
     default:
-        var cmd tea.Cmd
-        if m.urlIn.Focused() { m.urlIn, cmd = m.urlIn.Update(msg); return m, cmd }
-        if m.aliasIn.Focused() { m.aliasIn, cmd = m.aliasIn.Update(msg); return m, cmd }
-        if m.tokIn.Focused() { m.tokIn, cmd = m.tokIn.Update(msg); return m, cmd }
-        if m.modelIn.Focused() { m.modelIn, cmd = m.modelIn.Update(msg); return m, cmd }
-        return m, nil
+        cmd := m.updateFocusedInput(msg)
+        m.syncModelQuery()
+        return m, cmd
     }
 }
 
 func (m model) View() string {
     // top bar: app name + version + status (single-line if fits, otherwise 2 lines)
     top := m.renderTop()
-    // tables full width
-    tables := m.renderTable()
+    // tables full width, or the one-line-per-agent overview
+    var tables string
+    if m.m == modeOverview {
+        tables = m.renderOverview()
+    } else {
+        tables = m.renderTable()
+    }
+    if m.m == modeFilter || m.filterIn.Value() != "" {
+        tables = m.filterIn.View() + "\n" + tables
+    }
     // bottom details
     details := m.renderDetailBottom()
     return top + "\n" + tables + details + "\n" + m.help()
@@ -597,6 +833,11 @@ func (m model) renderTop() string {
     if strings.Contains(ls, "failed") || strings.Contains(ls, "error") || strings.Contains(ls, "cannot") {
         stStyled = styleStatusErr.Render(st)
     }
+    if m.notice != "" {
+        // transient external-change notice rides along with the status
+        st += " | " + m.notice
+        stStyled += styleMuted.Render(" | ") + styleNotice.Render(m.notice)
+    }
     rightRaw := "Status: " + st
     sep := " | "
     // decide single vs double line based on raw rune widths
@@ -653,6 +894,74 @@ func (m model) help() string {
         b.WriteString(styleKey.Render("[Esc]"))
         b.WriteString(" Cancel")
         return b.String()
+    } else if m.m == modeFilter {
+        b.WriteString("Filter: ")
+        b.WriteString(styleKey.Render("[↑/↓]"))
+        b.WriteString(" Move  ")
+        b.WriteString(styleKey.Render("[Enter]"))
+        b.WriteString(" Keep  ")
+        b.WriteString(styleKey.Render("[Esc]"))
+        b.WriteString(" Clear")
+        return b.String()
+    } else if m.m == modeOverview {
+        b.WriteString("Overview: ")
+        b.WriteString(styleKey.Render("[↑/↓]"))
+        b.WriteString(" Agent  ")
+        b.WriteString(styleKey.Render("[Enter/o]"))
+        b.WriteString(" Open  ")
+        b.WriteString(styleKey.Render("[q]"))
+        b.WriteString(" Quit")
+        return b.String()
+    } else if m.m == modeRotate {
+        b.WriteString("Rotate: ")
+        b.WriteString(styleKey.Render("sha:" + m.rotateFP))
+        b.WriteString("  ")
+        b.WriteString(styleKey.Render("[Enter]"))
+        b.WriteString(" Rotate everywhere  ")
+        b.WriteString(styleKey.Render("[Esc]"))
+        b.WriteString(" Cancel")
+        return b.String()
+    } else if m.m == modeCopy {
+        b.WriteString("Copy: ")
+        b.WriteString(styleKey.Render(m.copyAlias))
+        b.WriteString("  ")
+        b.WriteString(styleKey.Render("[1-9]"))
+        b.WriteString(" Agent  ")
+        b.WriteString(styleKey.Render("[a]"))
+        b.WriteString(" All others  ")
+        b.WriteString(styleKey.Render("[Esc]"))
+        b.WriteString(" Cancel")
+        return b.String()
+    } else if m.m == modeConfirmApply {
+        b.WriteString("Apply: ")
+        b.WriteString(styleKey.Render(agentTitle(m.plan.Agent)))
+        b.WriteString("  ")
+        b.WriteString(styleKey.Render(m.planPreset.Alias))
+        b.WriteString("  ")
+        b.WriteString(styleKey.Render("[y]"))
+        b.WriteString(" Apply  ")
+        if m.planPatch == nil {
+            b.WriteString(styleKey.Render("[t]"))
+            b.WriteString(" Trust & Apply  ")
+        }
+        b.WriteString(styleKey.Render("[f]"))
+        b.WriteString(" File diff  ")
+        b.WriteString(styleKey.Render("[n/Esc]"))
+        b.WriteString(" Cancel")
+        return b.String()
+    } else if m.m == modePartial {
+        b.WriteString("Partial: ")
+        b.WriteString(styleKey.Render(m.partialPreset.Alias))
+        b.WriteString("  ")
+        b.WriteString(styleKey.Render("[↑/↓]"))
+        b.WriteString(" Move  ")
+        b.WriteString(styleKey.Render("[Space]"))
+        b.WriteString(" Toggle  ")
+        b.WriteString(styleKey.Render("[Enter]"))
+        b.WriteString(" Preview  ")
+        b.WriteString(styleKey.Render("[Esc]"))
+        b.WriteString(" Cancel")
+        return b.String()
     } else if m.m == modeUpdate {
         g := m.groups[m.active]
         b.WriteString("Update: ")
@@ -671,17 +980,13 @@ func (m model) help() string {
     // Table mode
     // Agent group selector row with explicit mapping
     b.WriteString("Agent: ")
-    b.WriteString(styleKey.Render("[1]"))
-    b.WriteString(" ")
-    b.WriteString(agentTitle(core.AgentClaude))
-    b.WriteString("  ")
-    b.WriteString(styleKey.Render("[2]"))
-    b.WriteString(" ")
-    b.WriteString(agentTitle(core.AgentGemini))
-    b.WriteString("  ")
-    b.WriteString(styleKey.Render("[3]"))
-    b.WriteString(" ")
-    b.WriteString(agentTitle(core.AgentCodex))
+    for i, g := range m.groups {
+        if i >= 9 { break }
+        if i > 0 { b.WriteString("  ") }
+        b.WriteString(styleKey.Render(fmt.Sprintf("[%d]", i+1)))
+        b.WriteString(" ")
+        b.WriteString(agentTitle(g.id))
+    }
     b.WriteString("\n")
     // Actions row
     b.WriteString("Actions: ")
@@ -689,6 +994,8 @@ func (m model) help() string {
     b.WriteString(" Move  ")
     b.WriteString(styleKey.Render("[Enter]"))
     b.WriteString(" Apply  ")
+    b.WriteString(styleKey.Render("[P]"))
+    b.WriteString(" Apply fields  ")
     b.WriteString(styleKey.Render("[a]"))
     b.WriteString(" Add  ")
     b.WriteString(styleKey.Render("[i]"))
@@ -701,6 +1008,18 @@ func (m model) help() string {
     b.WriteString(" Update  ")
     b.WriteString(styleKey.Render("[d]"))
     b.WriteString(" Delete  ")
+    b.WriteString(styleKey.Render("[c]"))
+    b.WriteString(" Copy  ")
+    b.WriteString(styleKey.Render("[R]"))
+    b.WriteString(" Rotate  ")
+    b.WriteString(styleKey.Render("[/]"))
+    b.WriteString(" Filter  ")
+    b.WriteString(styleKey.Render("[s]"))
+    b.WriteString(" Sort")
+    if m.sortKey != "" && m.sortKey != "alias" { b.WriteString(":" + m.sortKey) }
+    b.WriteString("  ")
+    b.WriteString(styleKey.Render("[o]"))
+    b.WriteString(" Overview  ")
     b.WriteString(styleKey.Render("[r]"))
     b.WriteString(" Reload  ")
     b.WriteString(styleKey.Render("[q]"))
@@ -709,24 +1028,29 @@ func (m model) help() string {
 }
 
 func agentTitle(id core.AgentID) string {
-    switch id {
+    suffix := ""
+    if in := id.Instance(); in != "" { suffix = "@" + in }
+    switch id.Base() {
     case core.AgentClaude:
-        return "claude-code"
+        return "claude-code" + suffix
     case core.AgentGemini:
-        return "gemini-cli"
+        return "gemini-cli" + suffix
     case core.AgentCodex:
-        return "codex-cli"
+        return "codex-cli" + suffix
     default:
         return string(id)
     }
 }
 
-// storePreset is a local mirror used to sort and filter
-type storePreset struct{ Alias, URL, Token, Model, AddedAt string }
-
 // agentSupportsModel indicates whether the agent supports Model management.
+// agentSupportsHeaders reports whether the agent can send custom HTTP headers.
+func agentSupportsHeaders(id core.AgentID) bool {
+    b := id.Base()
+    return b == core.AgentClaude || b == core.AgentCodex
+}
+
 func agentSupportsModel(id core.AgentID) bool {
-    switch id {
+    switch id.Base() {
     case core.AgentClaude, core.AgentGemini, core.AgentCodex:
         return true
     default:
@@ -783,7 +1107,7 @@ func (m model) renderTable() string {
         out.WriteString(drawBorder("╭", "┬", "╮", false) + "\n")
         // header row: first column shows agent name (blue if this table is active), others are column titles
         agentHdr := fmt.Sprintf("[%d] %s", gi+1, agentTitle(g.id))
-        agentCell := fmt.Sprintf("%-*s", wAgent, agentHdr)
+        agentCell := fmt.Sprintf("%-*s", wAgent, truncate(agentHdr, wAgent))
         if gi == m.active { agentCell = styleAliasSel.Render(agentCell) }
         activeCell := fmt.Sprintf("%-*s", wActive, "Active")
         aliasCell := fmt.Sprintf("%-*s", wAlias, "Alias")
@@ -793,15 +1117,31 @@ func (m model) renderTable() string {
         // header separator (underline), always visible (no color) to ensure consistent rendering
         out.WriteString(drawBorder("├", "┼", "┤", false) + "\n")
 
-        // rows
-        last := len(g.rows) - 1
-        for i, r := range g.rows {
+        // rows: the filtered set, scrolled to the group's viewport
+        vis := m.visibleRows(g)
+        end := g.offset + m.viewportRows(gi)
+        if end > len(vis) { end = len(vis) }
+        window := vis[g.offset:end]
+        last := len(window) - 1
+        for wi, i := range window {
+            r := g.rows[i]
             isSel := gi == m.active && i == g.index
             activeMark := ""
-            if r.kind == rowCurrent { activeMark = check() }
-            // raw contents (truncated)
-            aliasRaw := truncate(r.alias, wAlias)
-            urlRaw := truncate(r.url, wURL)
+            if r.kind == rowCurrent {
+                activeMark = check()
+                if len(g.eff.Shadowed()) > 0 { activeMark += "!" }
+            }
+            // raw contents (truncated); a drifted active row shows its nearest preset as ~alias
+            aliasText := r.alias
+            if aliasText == "" && r.near != "" { aliasText = "~" + r.near }
+            aliasRaw := truncate(aliasText, wAlias)
+            urlText := r.urlLabel()
+            if i == 0 && g.err != nil {
+                urlText = "error: " + g.err.Error()
+            } else if i == 0 && g.loading && r.url == "" && r.alias == "" {
+                urlText = "loading…"
+            }
+            urlRaw := truncate(urlText, wURL)
             // pad each cell to fixed width first
             // Agent column: show version on active row only
             verText := ""
@@ -819,6 +1159,19 @@ func (m model) renderTable() string {
                 aliasCell = styleAliasSel.Render(aliasCell)
                 urlCell = styleAliasSel.Render(urlCell)
             }
+            if r.near != "" {
+                aliasCell = styleMuted.Render(fmt.Sprintf("%-*s", wAlias, aliasRaw))
+            }
+            // keys past or near their expiry are flagged on the alias
+            switch st, _ := r.meta.Expiry(time.Now()); st {
+            case core.ExpiryPast:
+                aliasCell = styleStatusErr.Render(fmt.Sprintf("%-*s", wAlias, aliasRaw))
+            case core.ExpirySoon:
+                aliasCell = styleNotice.Render(fmt.Sprintf("%-*s", wAlias, aliasRaw))
+            }
+            if r.differs("URL") || (i == 0 && g.err != nil) {
+                urlCell = styleStatusErr.Render(fmt.Sprintf("%-*s", wURL, urlRaw))
+            }
             // vertical bars: all default color; no colored borders for selection
             vbL := "│"
             vbR := "│"
@@ -826,36 +1179,87 @@ func (m model) renderTable() string {
                 vbL, agentCell, aCell, aliasCell, urlCell, vbR)
             out.WriteString(rowStr)
             // row separator or bottom border
-            if i < last {
+            if wi < last {
                 out.WriteString(drawBorder("├", "┼", "┤", false) + "\n")
             } else {
                 out.WriteString(drawBorder("╰", "┴", "╯", false) + "\n")
             }
         }
+        if more := scrollHint(g.offset, len(vis)-end, len(g.rows)-len(vis)); more != "" {
+            out.WriteString(styleMuted.Render(more) + "\n")
+        }
         if gi < len(m.groups)-1 { out.WriteString("\n") }
     }
     return out.String()
 }
 
+// scrollHint describes rows outside the viewport and rows hidden by the filter.
+func scrollHint(above, below, filtered int) string {
+    var parts []string
+    if above > 0 { parts = append(parts, fmt.Sprintf("↑ %d more", above)) }
+    if below > 0 { parts = append(parts, fmt.Sprintf("↓ %d more", below)) }
+    if filtered > 0 { parts = append(parts, fmt.Sprintf("%d filtered out", filtered)) }
+    return strings.Join(parts, " · ")
+}
+
 func (m model) renderDetailBottom() string {
     g := m.groups[m.active]
     r := g.rows[g.index]
     var b strings.Builder
     b.WriteString(lipgloss.NewStyle().Bold(true).Render("\nDetails")+"\n")
     b.WriteString(fmt.Sprintf("Agent: %s\n", agentTitle(g.id)))
+    if g.err != nil {
+        b.WriteString(styleStatusErr.Render("Load failed: "+g.err.Error()) + "\n")
+    } else if g.loading {
+        b.WriteString(styleMuted.Render("loading…") + "\n")
+    }
     // Active mark + alias + create time
     activeMark := ""
     if r.kind == rowCurrent { activeMark = "✔" }
-    b.WriteString(fmt.Sprintf("Active: %s  Alias: %s  CreateTime: %s\n", activeMark, r.alias, r.added))
-    b.WriteString(fmt.Sprintf("URL: %s\n", r.url))
-    b.WriteString(fmt.Sprintf("Token: %s\n", util.Mask(r.token)))
+    b.WriteString(fmt.Sprintf("Active: %s  Alias: %s  Added: %s\n", activeMark, r.alias, fmtTime(r.meta.AddedAt)))
+    b.WriteString(renderMeta(r.meta))
+    if r.near != "" {
+        b.WriteString(styleStatusErr.Render("Drift: "+core.Match{Alias: r.near, Diffs: r.diffs}.Describe()) + "\n")
+    }
+    // differing fields of a drifted active row are highlighted
+    field := func(name, v string) string {
+        if r.differs(name) { return styleStatusErr.Render(v) }
+        return v
+    }
+    hdr := ""
+    if len(r.extra.Headers) > 0 || r.differs("Headers") {
+        h := r.extra.Headers.Masked()
+        if h == "" { h = "(none)" }
+        hdr = "  Headers: " + field("Headers", h)
+    }
+    if !r.extra.Network.IsZero() || r.differs("Network") {
+        n := r.extra.Network.String()
+        if n == "" { n = "(none)" }
+        hdr += "  Network: " + field("Network", n)
+    }
+    b.WriteString(fmt.Sprintf("URL: %s%s\n", field("URL", r.urlLabel()), hdr))
+    tok := field("Token", util.Mask(r.token))
+    if r.meta.KeyHelper { tok += styleMuted.Render(" (via apiKeyHelper)") }
+    if g.id.Base() == core.AgentGemini {
+        auth := renderAuth(r.extra)
+        if r.differs("Auth") || r.differs("Project") || r.differs("Location") { auth = styleStatusErr.Render(auth) }
+        tok += "  Auth: " + auth
+    }
+    b.WriteString(fmt.Sprintf("Token: %s\n", tok))
     // Show Model for all agents
-    mv := r.model
-    if mv == "" {
+    mv := field("Model", r.model)
+    if r.model == "" {
         // render placeholder in muted color, consistent with top bar version color
         mv = styleMuted.Render("(not set)")
+        if r.differs("Model") { mv = styleStatusErr.Render("(not set)") }
     }
     b.WriteString(fmt.Sprintf("Model: %s\n", mv))
+    if !r.extra.Tiers.IsZero() || r.differs("Tiers") {
+        b.WriteString("Tiers: " + field("Tiers", renderTiers(r.extra.Tiers)) + "\n")
+    }
+    if r.kind == rowCurrent {
+        b.WriteString(renderShadowed(g.eff))
+    }
     if m.m == modeNew {
         b.WriteString("\nAdd Preset for ")
         b.WriteString(agentTitle(g.id))
@@ -864,9 +1268,26 @@ func (m model) renderDetailBottom() string {
         b.WriteString("Alias: "+m.aliasIn.View()+"\n")
         b.WriteString("Token: "+m.tokIn.View()+"\n")
         b.WriteString("Model: "+m.modelIn.View()+"\n")
+        b.WriteString(m.renderPicker("       "))
+        if agentSupportsHeaders(g.id) { b.WriteString("Headers: "+m.hdrIn.View()+"\n") }
+        b.WriteString("Tags:  "+m.tagsIn.View()+"\n")
+        b.WriteString("Notes: "+m.notesIn.View()+"\n")
+        b.WriteString("Expires: "+m.expIn.View()+"\n")
         if m.formErr != "" {
             b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(m.formErr)+"\n")
         }
+    }
+    if m.pending {
+        b.WriteString(styleMuted.Render("saving…") + "\n")
+    }
+    if m.m == modeConfirmApply {
+        b.WriteString(renderPlan(m.plan, m.showFiles))
+    } else if m.m == modeCopy {
+        b.WriteString(m.renderCopy())
+    } else if m.m == modeRotate {
+        b.WriteString(m.renderRotate())
+    } else if m.m == modePartial {
+        b.WriteString(m.renderPartial())
     } else if m.m == modeConfirmDel {
         b.WriteString("\nConfirm Delete:\n")
         b.WriteString(fmt.Sprintf("Agent: %s\n", agentTitle(g.id)))
@@ -888,6 +1309,11 @@ func (m model) renderDetailBottom() string {
         b.WriteString("URL:       "+m.urlIn.View()+"\n")
         b.WriteString("Token:     "+m.tokIn.View()+"\n")
         b.WriteString("Model:     "+m.modelIn.View()+"\n")
+        b.WriteString(m.renderPicker("           "))
+        if agentSupportsHeaders(g.id) { b.WriteString("Headers:   "+m.hdrIn.View()+"\n") }
+        b.WriteString("Tags:      "+m.tagsIn.View()+"\n")
+        b.WriteString("Notes:     "+m.notesIn.View()+"\n")
+        b.WriteString("Expires:   "+m.expIn.View()+"\n")
         if m.formErr != "" {
             b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(m.formErr)+"\n")
         }
@@ -895,6 +1321,115 @@ func (m model) renderDetailBottom() string {
     return b.String()
 }
 
+// fmtTime renders a metadata timestamp in local time, "-" when unset.
+func fmtTime(s string) string {
+    if t, ok := core.ParseTime(s); ok { return t.Local().Format("2006-01-02 15:04") }
+    if s == "" { return "-" }
+    return s
+}
+
+// renderMeta shows a preset's tags, expiry, usage and notes on two lines.
+func renderMeta(p core.Preset) string {
+    tags := strings.Join(p.Tags, ", ")
+    if tags == "" { tags = styleMuted.Render("(none)") }
+    st, exp := p.Expiry(time.Now())
+    switch st {
+    case core.ExpiryNone:
+        exp = styleMuted.Render("(never)")
+    case core.ExpirySoon:
+        exp = styleNotice.Render(exp)
+    case core.ExpiryPast:
+        exp = styleStatusErr.Render(exp)
+    }
+    used := fmt.Sprintf("%d times", p.UseCount)
+    if p.LastUsedAt != "" { used += ", last " + fmtTime(p.LastUsedAt) }
+    notes := p.Notes
+    if notes == "" { notes = styleMuted.Render("(none)") }
+    gw := ""
+    if p.Gateway != "" { gw = "  Gateway: " + p.Gateway }
+    return fmt.Sprintf("Tags: %s  Key: %s%s\nUsed: %s  Notes: %s\n", tags, exp, gw, used, notes)
+}
+
+// renderAuth describes a Gemini auth mode, with project and location for Vertex AI.
+func renderAuth(f core.Fields) string {
+    mode := core.GeminiAuth(f.Auth)
+    if mode != core.GeminiAuthVertex { return mode }
+    var where []string
+    for _, v := range []string{f.Project, f.Location} {
+        if v != "" { where = append(where, v) }
+    }
+    if len(where) == 0 { return mode }
+    return mode + " (" + strings.Join(where, ", ") + ")"
+}
+
+// renderTiers shows a Claude tier mapping, e.g. "opus → gw-large  sonnet → gw-medium".
+func renderTiers(t core.ModelTiers) string {
+    var parts []string
+    for _, kv := range [][2]string{{"opus", t.Opus}, {"sonnet", t.Sonnet}, {"haiku", t.Haiku}, {"main", t.Main}} {
+        if kv[1] != "" { parts = append(parts, kv[0]+" → "+kv[1]) }
+    }
+    if len(parts) == 0 { return "(none)" }
+    return strings.Join(parts, "  ")
+}
+
+// renderPlan shows what applying a preset changes: managed fields (tokens masked)
+// and, when files is set, a unified diff per config file.
+func renderPlan(p ops.Plan, files bool) string {
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("\nApply '%s' to %s:\n", p.Alias, agentTitle(p.Agent)))
+    if len(p.Fields) == 0 {
+        b.WriteString(styleMuted.Render("(URL, Token and Model unchanged)") + "\n")
+    }
+    show := func(c core.FieldChange, v string) string {
+        if c.Secret { v = util.Mask(v) }
+        if v == "" { v = "(unset)" }
+        return v
+    }
+    for _, c := range p.Fields {
+        b.WriteString(fmt.Sprintf("%s: %s -> %s\n", c.Field, styleStatusErr.Render(show(c, c.Old)), styleStatusOK.Render(show(c, c.New))))
+    }
+    if !p.Changed() {
+        b.WriteString(styleMuted.Render("files already match; applying only records history") + "\n")
+    }
+    if !files {
+        return b.String()
+    }
+    for _, f := range p.Files {
+        d := util.MaskAll(core.UnifiedDiff(f), p.Secrets)
+        if d == "" { continue }
+        b.WriteString("\n")
+        for _, ln := range strings.Split(strings.TrimSuffix(d, "\n"), "\n") {
+            switch {
+            case strings.HasPrefix(ln, "+++"), strings.HasPrefix(ln, "---"), strings.HasPrefix(ln, "@@"):
+                ln = styleMuted.Render(ln)
+            case strings.HasPrefix(ln, "+"):
+                ln = styleStatusOK.Render(ln)
+            case strings.HasPrefix(ln, "-"):
+                ln = styleStatusErr.Render(ln)
+            }
+            b.WriteString(ln + "\n")
+        }
+    }
+    return b.String()
+}
+
+// renderShadowed warns about file values the agent will not actually use.
+func renderShadowed(eff core.Effective) string {
+    var b strings.Builder
+    warn := func(name string, r core.Resolved, secret bool) {
+        if !r.Shadowed() { return }
+        v := r.Value
+        if secret { v = util.Mask(v) }
+        src := r.Source
+        if src == "" { v, src = "(unset)", "file not loaded" }
+        b.WriteString(styleStatusErr.Render(fmt.Sprintf("! %s shadowed: agent uses %s (%s)", name, v, src)) + "\n")
+    }
+    warn("URL", eff.URL, false)
+    warn("Token", eff.Token, true)
+    warn("Model", eff.Model, false)
+    return b.String()
+}
+
 // async version loading
 type verMsg struct {
     id        core.AgentID
@@ -905,16 +1440,16 @@ type verMsg struct {
 
 func versionCmd(id core.AgentID) tea.Cmd {
     return func() tea.Msg {
-        text, inst := detectVersion(id)
+        text, inst := detectVersion(id.Base())
         return verMsg{id: id, text: text, installed: inst, at: time.Now()}
     }
 }
 
 func (m model) scheduleVersionCmds() tea.Cmd {
     var cmds []tea.Cmd
-    ids := []core.AgentID{core.AgentClaude, core.AgentGemini, core.AgentCodex}
     now := time.Now()
-    for _, id := range ids {
+    for _, g := range m.groups {
+        id := g.id
         st, ok := m.verCache[id]
         if !ok || now.Sub(st.at) >= verTTL {
             cmds = append(cmds, versionCmd(id))
@@ -932,7 +1467,14 @@ func truncate(s string, n int) string {
 
 // Run starts the TUI program (built with -tags tui)
 func Run() error {
-    p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+    m := initialModel()
+    // live reload is best effort: without a watcher, 'r' still reloads
+    if w, err := newWatcher(); err == nil {
+        m.watch = w
+        m.watch.sync()
+        defer m.watch.close()
+    }
+    p := tea.NewProgram(m, tea.WithAltScreen())
     _, err := p.Run()
     return err
 }
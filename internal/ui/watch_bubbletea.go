@@ -0,0 +1,196 @@
+package ui
+
+import (
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/fsnotify/fsnotify"
+
+    core "tks/internal/core"
+    "tks/internal/fsx"
+    "tks/internal/providers"
+    "tks/internal/store"
+)
+
+// watchDebounce groups bursts of events (temp file + rename + backup) into one reload.
+const watchDebounce = 250 * time.Millisecond
+
+// fsChangedMsg reports watched files that changed on disk since the last message.
+// external lists those that no longer hold what agtok itself last wrote.
+type fsChangedMsg struct{ paths, external []string }
+
+// watcher observes agent config files and the presets dir. Directories are
+// watched rather than files because atomic writes replace the inode.
+type watcher struct {
+    w      *fsnotify.Watcher
+    out    chan []string
+    mu     sync.Mutex
+    files  map[string]core.AgentID // exact files of interest (links and their targets) -> owning agent; "" affects all
+    dirs   map[string]bool // directories where any *.json entry matters
+    active map[string]bool // directories currently registered with fsnotify
+}
+
+func newWatcher() (*watcher, error) {
+    fw, err := fsnotify.NewWatcher()
+    if err != nil {
+        return nil, err
+    }
+    w := &watcher{w: fw, out: make(chan []string, 1), files: map[string]core.AgentID{}, dirs: map[string]bool{}, active: map[string]bool{}}
+    go w.loop()
+    return w, nil
+}
+
+// sync registers every current Provider.Paths() file plus the preset and instance files.
+// It is cheap to call again after a reload so newly created directories get watched.
+func (w *watcher) sync() {
+    if w == nil {
+        return
+    }
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    add := func(id core.AgentID, p string) {
+        w.files[p] = id
+        w.watchDir(filepath.Dir(p))
+        if target, ok := fsx.LinkTarget(p); ok {
+            w.files[target] = id
+            w.watchDir(filepath.Dir(target))
+        }
+    }
+    for _, id := range providers.Agents() {
+        if prov := providers.NewProvider(id); prov != nil {
+            for _, p := range prov.Paths() {
+                add(id, p)
+            }
+        }
+    }
+    add("", filepath.Join(store.BaseDir(), "instances.json"))
+    w.dirs[store.PresetsDir()] = true
+    w.watchDir(store.PresetsDir())
+}
+
+// syncCmd runs sync off the UI loop (it reads the instance registry and resolves links).
+func (w *watcher) syncCmd() tea.Cmd {
+    if w == nil {
+        return nil
+    }
+    return func() tea.Msg { w.sync(); return nil }
+}
+
+// affected maps changed paths to the agents whose groups must be reloaded.
+// all is true when the instance registry changed and the agent list itself may differ.
+func (w *watcher) affected(paths []string) (ids []core.AgentID, all bool) {
+    if w == nil {
+        return nil, false
+    }
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    seen := map[core.AgentID]bool{}
+    for _, p := range paths {
+        id, ok := w.files[p]
+        if !ok && w.dirs[filepath.Dir(p)] {
+            // presets dir: one <agent>.json file per agent or instance
+            id, ok = core.AgentID(strings.TrimSuffix(filepath.Base(p), ".json")), true
+        }
+        if !ok {
+            continue
+        }
+        if id == "" {
+            all = true
+            continue
+        }
+        if !seen[id] {
+            seen[id] = true
+            ids = append(ids, id)
+        }
+    }
+    return ids, all
+}
+
+func (w *watcher) watchDir(dir string) {
+    if w.active[dir] {
+        return
+    }
+    if err := w.w.Add(dir); err == nil {
+        w.active[dir] = true
+    }
+}
+
+func (w *watcher) relevant(name string) bool {
+    base := filepath.Base(name)
+    if strings.HasSuffix(base, ".tmp") || strings.HasSuffix(base, ".bak") {
+        return false
+    }
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    _, ok := w.files[name]
+    return ok || (w.dirs[filepath.Dir(name)] && strings.HasSuffix(base, ".json"))
+}
+
+func (w *watcher) loop() {
+    pending := map[string]bool{}
+    var timer <-chan time.Time
+    for {
+        select {
+        case ev, ok := <-w.w.Events:
+            if !ok {
+                close(w.out)
+                return
+            }
+            if ev.Op == fsnotify.Chmod || !w.relevant(ev.Name) {
+                continue
+            }
+            pending[ev.Name] = true
+            timer = time.After(watchDebounce)
+        case _, ok := <-w.w.Errors:
+            if !ok {
+                close(w.out)
+                return
+            }
+        case <-timer:
+            timer = nil
+            paths := make([]string, 0, len(pending))
+            for p := range pending {
+                paths = append(paths, p)
+            }
+            sort.Strings(paths)
+            select {
+            case w.out <- paths:
+                pending = map[string]bool{}
+            default:
+                // the UI has not consumed the previous batch yet; keep collecting
+                timer = time.After(watchDebounce)
+            }
+        }
+    }
+}
+
+// next waits for the next debounced batch; re-issue it after every fsChangedMsg.
+func (w *watcher) next() tea.Cmd {
+    if w == nil {
+        return nil
+    }
+    return func() tea.Msg {
+        paths, ok := <-w.out
+        if !ok {
+            return nil
+        }
+        // compare content, not timing: a change right after our own write still counts
+        var external []string
+        for _, p := range paths {
+            if !fsx.OwnContent(p) {
+                external = append(external, p)
+            }
+        }
+        return fsChangedMsg{paths: paths, external: external}
+    }
+}
+
+func (w *watcher) close() {
+    if w != nil {
+        _ = w.w.Close()
+    }
+}
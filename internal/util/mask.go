@@ -1,9 +1,57 @@
 package util
 
-// Mask masks a secret by keeping last 4 chars.
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "slices"
+    "strings"
+)
+
+// Mask masks a secret by keeping last 4 chars. Secret references are not
+// secrets and are shown as is.
 func Mask(s string) string {
     if s == "" { return "" }
+    if IsSecretRef(s) { return s }
     if len(s) <= 4 { return "****" }
     return "****" + s[len(s)-4:]
 }
 
+// MaskAll masks every occurrence of each secret in s, longest first so a secret
+// that contains another is masked whole.
+func MaskAll(s string, secrets []string) string {
+    sorted := slices.Clone(secrets)
+    slices.SortFunc(sorted, func(a, b string) int { return len(b) - len(a) })
+    for _, sec := range sorted {
+        if sec != "" { s = strings.ReplaceAll(s, sec, Mask(sec)) }
+    }
+    return s
+}
+
+// Fingerprint returns a short, stable identifier for a secret: the first 12
+// hex chars of its SHA-256. Safe to log; empty for an empty secret.
+func Fingerprint(s string) string {
+    if s == "" { return "" }
+    sum := sha256.Sum256([]byte(s))
+    return hex.EncodeToString(sum[:])[:12]
+}
+
+// MatchesFingerprint reports whether secret is identified by fp: either its last
+// four characters (as shown by Mask, "****" optional) or a prefix of at least six
+// hex chars of its SHA-256 ("sha:" optional), as printed by Fingerprint.
+func MatchesFingerprint(secret, fp string) bool {
+    fp = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(fp), "****"), "sha:")
+    if secret == "" || fp == "" || IsSecretRef(secret) { return false }
+    if len(fp) == 4 { return strings.HasSuffix(secret, fp) }
+    if len(fp) < 6 { return false }
+    sum := sha256.Sum256([]byte(secret))
+    return strings.HasPrefix(hex.EncodeToString(sum[:]), strings.ToLower(fp))
+}
+
+// IsSecretRef reports whether a token value is a reference to the real secret
+// (env:VAR, file:/path or cmd:command) rather than the secret itself.
+func IsSecretRef(s string) bool {
+    for _, p := range []string{"env:", "file:", "cmd:"} {
+        if strings.HasPrefix(s, p) { return true }
+    }
+    return false
+}
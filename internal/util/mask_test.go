@@ -0,0 +1,27 @@
+package util
+
+import "testing"
+
+func TestMatchesFingerprint(t *testing.T) {
+    secret := "sk-live-abcd1234"
+    sha := Fingerprint(secret)
+    for fp, want := range map[string]bool{
+        "1234":           true,
+        "****1234":       true,
+        " 1234 ":         true,
+        sha:              true,
+        "sha:" + sha[:6]: true,
+        sha[:5]:          false, // too short to be a hash prefix
+        "9999":           false,
+        "":               false,
+    } {
+        if got := MatchesFingerprint(secret, fp); got != want { t.Errorf("MatchesFingerprint(%q) = %v, want %v", fp, got, want) }
+    }
+    if MatchesFingerprint("env:KEY_1234", "1234") { t.Error("a reference matched a fingerprint") }
+    if MatchesFingerprint("", "1234") { t.Error("an empty secret matched") }
+}
+
+func TestMaskAll(t *testing.T) {
+    got := MaskAll("token sk-abc and Bearer sk-abc-long", []string{"sk-abc", "Bearer sk-abc-long", ""})
+    if want := "token ****-abc and ****long"; got != want { t.Fatalf("MaskAll = %q, want %q", got, want) }
+}